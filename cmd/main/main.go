@@ -5,14 +5,20 @@ import (
 	"L0_project/internal/cache"
 	"L0_project/internal/config"
 	"L0_project/internal/database"
+	"L0_project/internal/ingest"
 	"L0_project/internal/kafka"
 	"L0_project/internal/metrics"
+	"L0_project/internal/model"
 	"L0_project/internal/tracing"
+	"L0_project/internal/validator"
 	"context"
 	"log"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
+
+	"github.com/redis/go-redis/v9"
 )
 
 func main() {
@@ -23,6 +29,16 @@ func main() {
 	// Инициализация метрик (Prometheus)
 	metrics.Init()
 
+	// Язык сообщений об ошибках валидации в логах и DLQ
+	lang, ok := validator.ParseLang(cfg.Validation.Lang)
+	if !ok {
+		log.Fatalf("Неподдерживаемый VALIDATION_LANG: %q (ожидается ru или en)", cfg.Validation.Lang)
+	}
+	validator.SetDefaultLang(lang)
+	if err := validator.DisableRules(cfg.Validation.DisabledRules...); err != nil {
+		log.Fatalf("Ошибка настройки VALIDATION_DISABLED_RULES: %v", err)
+	}
+
 	// Инициализация хранилища
 	// Путь изменен на папку с миграциями
 	storage, err := database.New(cfg.Postgres.URL, "./internal/database/migrations")
@@ -36,19 +52,139 @@ func main() {
 	}()
 
 	// Инициализация кэша
-	orderCache := cache.NewLRUCache(cfg.Cache.Size)
-	// Используем Background-контекст для прогрева, т.к. он должен завершиться до старта
-	if err := cache.WarmUp(context.Background(), storage, orderCache); err != nil {
-		log.Printf("Ошибка при прогреве кэша: %v", err)
+	localCache, err := cache.New[string, *model.Order](cfg.Cache.Policy, cfg.Cache.Size,
+		cache.WithTTL(cfg.Cache.TTL),
+		cache.WithCleanupInterval(cfg.Cache.CleanupInterval),
+		cache.WithMaxBytes(cfg.Cache.MaxBytes, cache.OrderSizer),
+		cache.WithShards(cfg.Cache.Shards),
+	)
+	if err != nil {
+		log.Fatalf("Ошибка инициализации кэша: %v", err)
 	}
 
-	// Запуск Kafka Consumer
+	// Общий для реплик кэш (Redis) подключается вторым уровнем за локальным.
+	// Снимок и прогрев заполняют только локальный уровень.
+	orderCache := localCache
+	if cfg.Cache.RedisAddr != "" {
+		redisClient := redis.NewClient(&redis.Options{
+			Addr:     cfg.Cache.RedisAddr,
+			Password: cfg.Cache.RedisPassword,
+			DB:       cfg.Cache.RedisDB,
+		})
+		defer func() {
+			if err := redisClient.Close(); err != nil {
+				log.Printf("Ошибка закрытия клиента Redis: %v", err)
+			}
+		}()
+
+		orderCache = cache.NewTieredCache(localCache, redisClient,
+			cache.WithRemoteTTL(cfg.Cache.RedisTTL),
+			cache.WithRemoteTimeout(cfg.Cache.RedisTimeout),
+			cache.WithRemoteKeyPrefix(cfg.Cache.RedisKeyPrefix),
+		)
+		log.Printf("Общий кэш Redis: %s", cfg.Cache.RedisAddr)
+	}
+	defer orderCache.Close()
+
 	ctx, cancel := context.WithCancel(context.Background())
-	consumer := kafka.NewConsumer(cfg.Kafka, storage, orderCache)
-	go consumer.Run(ctx)
+
+	// Снимок кэша с прошлого запуска; если его нет или он непригоден - прогрев из БД
+	restored := 0
+	if cfg.Cache.SnapshotEnabled {
+		restored, err = cache.LoadSnapshot(ctx, cfg.Cache.SnapshotPath, localCache, cfg.Cache.SnapshotMaxAge)
+		if err != nil {
+			log.Printf("Снимок кэша проигнорирован: %v", err)
+		} else if restored > 0 {
+			log.Printf("Кэш восстановлен из снимка %s: %d заказов.", cfg.Cache.SnapshotPath, restored)
+		}
+	}
+
+	// Прогрев кэша: синхронно (до старта сервера) или в фоне
+	warmUpLimit := cfg.Cache.Size
+	if cfg.Cache.WarmUpLimit > 0 && cfg.Cache.WarmUpLimit < warmUpLimit {
+		warmUpLimit = cfg.Cache.WarmUpLimit
+	}
+	warmUp := func() {
+		if err := cache.WarmUp(ctx, storage, localCache, warmUpLimit); err != nil {
+			log.Printf("Ошибка при прогреве кэша: %v", err)
+		}
+	}
+	switch {
+	case restored > 0:
+		// Кэш уже заполнен из снимка
+	case cfg.Cache.WarmUpAsync:
+		go warmUp()
+	default:
+		warmUp()
+	}
+
+	if cfg.Cache.SoftTTL > 0 && cfg.Cache.TTL > 0 && cfg.Cache.SoftTTL >= cfg.Cache.TTL {
+		log.Printf("CACHE_SOFT_TTL (%s) не меньше CACHE_TTL (%s): устаревшие значения отдаваться не будут.", cfg.Cache.SoftTTL, cfg.Cache.TTL)
+	}
+	orderLoader := cache.NewLoader(orderCache,
+		cache.WithNegativeCache[string, *model.Order](cfg.Cache.NegativeSize, cfg.Cache.NegativeTTL),
+		cache.WithStaleWhileRevalidate[string, *model.Order](cfg.Cache.SoftTTL),
+	)
+
+	// Подписка на изменения заказов, сделанные другими экземплярами. Запись
+	// удаляется из обоих уровней: экземпляр, прочитавший заказ до фиксации
+	// изменения, мог записать в общий уровень прежнее значение.
+	if cfg.Cache.InvalidationEnabled {
+		listener := database.NewOrderChangeListener(cfg.Postgres.URL,
+			func(ctx context.Context, orderUID string) {
+				orderLoader.Invalidate(ctx, orderUID)
+			},
+			func(ctx context.Context) {
+				// Изменения за время разрыва неизвестны, поэтому сбрасываем локальный кэш
+				// целиком вместе с отрицательными записями
+				orderLoader.Purge(ctx)
+				localCache.Purge(ctx)
+			},
+		)
+		go listener.Run(ctx)
+
+		// Изменения, сделанные другими экземплярами, пока сервис был остановлен,
+		// в снимок не попали: после подписки сверяем восстановленные заказы с БД
+		if restored > 0 {
+			go func() {
+				select {
+				case <-listener.Subscribed():
+				case <-ctx.Done():
+					return
+				}
+				if err := cache.RevalidateOrders(ctx, storage, orderLoader, localCache.Keys()); err != nil {
+					log.Printf("Ошибка сверки кэша из снимка с БД: %v", err)
+				}
+			}()
+		}
+	}
+
+	// Дополнительные правила валидации из файла, с перезагрузкой на лету
+	if cfg.Validation.RulesFile != "" {
+		count, err := validator.LoadRulesFile(cfg.Validation.RulesFile, model.Order{})
+		if err != nil {
+			log.Fatalf("Ошибка загрузки правил валидации: %v", err)
+		}
+		log.Printf("Правила валидации загружены из %s: %d полей.", cfg.Validation.RulesFile, count)
+		go validator.WatchRulesFile(ctx, cfg.Validation.RulesFile, model.Order{}, cfg.Validation.RulesPollInterval)
+	}
+
+	// Прием заказов, общий для Kafka и HTTP API
+	ingestService := ingest.NewService(storage, orderLoader, kafka.RetryPolicy(cfg.Kafka))
+
+	// Запуск Kafka Consumer
+	consumer := kafka.NewConsumer(cfg.Kafka, ingestService, storage, orderLoader)
+	consumerDone := make(chan struct{})
+	go func() {
+		defer close(consumerDone)
+		consumer.Run(ctx)
+	}()
 
 	// Запуск HTTP-сервера
-	server := api.NewServer(cfg.HTTP.Port, storage, orderCache)
+	server := api.NewServer(cfg.HTTP.Port, storage, orderCache, orderLoader, ingestService, api.IngestOptions{
+		MaxBatchSize:   cfg.HTTP.MaxBatchSize,
+		IdempotencyTTL: cfg.HTTP.IdempotencyTTL,
+	})
 	go func() {
 		if err := server.Run(); err != nil {
 			log.Fatalf("Ошибка запуска HTTP-сервера: %v", err)
@@ -62,5 +198,20 @@ func main() {
 
 	log.Println("Сервис останавливается...")
 	cancel() // Отправляем сигнал отмены во все компоненты (Kafka)
+
+	// Консюмер дообрабатывает принятые сообщения и коммитит их смещения
+	select {
+	case <-consumerDone:
+	case <-time.After(cfg.Kafka.ShutdownTimeout):
+		log.Printf("Kafka-консюмер не остановился за %s, смещения могут быть не закоммичены.", cfg.Kafka.ShutdownTimeout)
+	}
+
+	if cfg.Cache.SnapshotEnabled {
+		if saved, err := cache.SaveSnapshot(cfg.Cache.SnapshotPath, localCache); err != nil {
+			log.Printf("Ошибка сохранения снимка кэша: %v", err)
+		} else {
+			log.Printf("Снимок кэша сохранен в %s: %d заказов.", cfg.Cache.SnapshotPath, saved)
+		}
+	}
 	log.Println("Сервис успешно остановлен.")
 }
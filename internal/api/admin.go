@@ -0,0 +1,83 @@
+package api
+
+import (
+	"L0_project/internal/cache"
+	"L0_project/internal/metrics"
+	"L0_project/internal/model"
+	"L0_project/internal/validator"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// AdminHandler обрабатывает служебные запросы дежурных инженеров.
+type AdminHandler struct {
+	cache  cache.Cache[string, *model.Order]
+	loader *cache.Loader[string, *model.Order] // Чтение через cache; хранит отрицательные записи
+}
+
+// NewAdminHandler создает новый экземпляр AdminHandler.
+// loader должен читать через тот же cache.
+func NewAdminHandler(cache cache.Cache[string, *model.Order], loader *cache.Loader[string, *model.Order]) *AdminHandler {
+	return &AdminHandler{cache: cache, loader: loader}
+}
+
+// cacheDeleteResponse - тело ответа DELETE /api/admin/cache/{orderUID}.
+type cacheDeleteResponse struct {
+	OrderUID string `json:"order_uid"`
+	Deleted  bool   `json:"deleted"`
+}
+
+// cachePurgeResponse - тело ответа POST /api/admin/cache/purge.
+type cachePurgeResponse struct {
+	Purged int `json:"purged"`
+}
+
+// DeleteCacheEntry удаляет заказ из кэша вместе с отрицательной записью.
+// Следующий запрос заказа пойдет в БД.
+func (h *AdminHandler) DeleteCacheEntry(w http.ResponseWriter, r *http.Request) {
+	const handlerName = "AdminDeleteCacheEntry"
+	timer := prometheus.NewTimer(metrics.HttpRequestDuration.WithLabelValues(handlerName))
+	defer timer.ObserveDuration()
+
+	orderUID := chi.URLParam(r, "orderUID")
+	if orderUID == "" {
+		respondWithError(w, http.StatusBadRequest, "UID заказа не указан", handlerName)
+		return
+	}
+
+	h.loader.Forget(r.Context(), orderUID)
+	deleted := h.cache.Delete(r.Context(), orderUID)
+	log.Printf("Админ: удаление %s из кэша (был в кэше: %t)", orderUID, deleted)
+
+	metrics.HttpRequestsTotal.WithLabelValues(handlerName, "200").Inc()
+	respondWithJSON(w, http.StatusOK, cacheDeleteResponse{OrderUID: orderUID, Deleted: deleted})
+}
+
+// PurgeCache полностью очищает кэш вместе с отрицательными записями.
+func (h *AdminHandler) PurgeCache(w http.ResponseWriter, r *http.Request) {
+	const handlerName = "AdminPurgeCache"
+	timer := prometheus.NewTimer(metrics.HttpRequestDuration.WithLabelValues(handlerName))
+	defer timer.ObserveDuration()
+
+	purged := h.cache.Len()
+	h.loader.Purge(r.Context())
+	h.cache.Purge(r.Context())
+	log.Printf("Админ: кэш очищен (записей: %d)", purged)
+
+	metrics.HttpRequestsTotal.WithLabelValues(handlerName, "200").Inc()
+	respondWithJSON(w, http.StatusOK, cachePurgeResponse{Purged: purged})
+}
+
+// ValidationRules возвращает действующие правила валидации заказов: теги
+// полей, объединенные с правилами из файла VALIDATION_RULES_FILE, и бизнес-правила.
+func (h *AdminHandler) ValidationRules(w http.ResponseWriter, r *http.Request) {
+	const handlerName = "AdminValidationRules"
+	timer := prometheus.NewTimer(metrics.HttpRequestDuration.WithLabelValues(handlerName))
+	defer timer.ObserveDuration()
+
+	metrics.HttpRequestsTotal.WithLabelValues(handlerName, "200").Inc()
+	respondWithJSON(w, http.StatusOK, validator.EffectiveRules(model.Order{}))
+}
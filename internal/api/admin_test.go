@@ -0,0 +1,159 @@
+package api
+
+import (
+	"L0_project/internal/cache"
+	"L0_project/internal/cache/mocks"
+	"L0_project/internal/model"
+	"L0_project/internal/validator"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestAdminHandler_DeleteCacheEntry(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockCache := mocks.NewMockCache[string, *model.Order](ctrl)
+	handler := NewAdminHandler(mockCache, cache.NewLoader(mockCache))
+
+	uid := "test-uid-123"
+	req := httptest.NewRequest(http.MethodDelete, "/api/admin/cache/"+uid, nil)
+	chiCtx := chi.NewRouteContext()
+	chiCtx.URLParams.Add("orderUID", uid)
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, chiCtx))
+	rr := httptest.NewRecorder()
+
+	mockCache.EXPECT().Delete(gomock.Any(), uid).Return(true)
+
+	handler.DeleteCacheEntry(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var resp cacheDeleteResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, cacheDeleteResponse{OrderUID: uid, Deleted: true}, resp)
+}
+
+func TestAdminHandler_DeleteCacheEntry_ForgetsNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockCache := mocks.NewMockCache[string, *model.Order](ctrl)
+	loader := cache.NewLoader(mockCache, cache.WithNegativeCache[string, *model.Order](10, time.Hour))
+	handler := NewAdminHandler(mockCache, loader)
+
+	uid := "test-uid-123"
+	mockCache.EXPECT().Get(gomock.Any(), uid).Return(nil, false).AnyTimes()
+	mockCache.EXPECT().Delete(gomock.Any(), uid).Return(false)
+
+	loads := 0
+	load := func(context.Context) (*model.Order, error) {
+		loads++
+		return nil, cache.ErrNotFound
+	}
+	_, _, err := loader.GetOrLoad(context.Background(), uid, load)
+	assert.ErrorIs(t, err, cache.ErrNotFound)
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/admin/cache/"+uid, nil)
+	chiCtx := chi.NewRouteContext()
+	chiCtx.URLParams.Add("orderUID", uid)
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, chiCtx))
+	handler.DeleteCacheEntry(httptest.NewRecorder(), req)
+
+	// Отрицательная запись удалена: следующий запрос снова идет в источник
+	_, status, err := loader.GetOrLoad(context.Background(), uid, load)
+	assert.ErrorIs(t, err, cache.ErrNotFound)
+	assert.Equal(t, cache.StatusMiss, status)
+	assert.Equal(t, 2, loads)
+}
+
+func TestAdminHandler_DeleteCacheEntry_NoUID(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockCache := mocks.NewMockCache[string, *model.Order](ctrl)
+	handler := NewAdminHandler(mockCache, cache.NewLoader(mockCache))
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/admin/cache/", nil)
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, chi.NewRouteContext()))
+	rr := httptest.NewRecorder()
+
+	mockCache.EXPECT().Delete(gomock.Any(), gomock.Any()).Times(0)
+
+	handler.DeleteCacheEntry(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestAdminHandler_PurgeCache(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockCache := mocks.NewMockCache[string, *model.Order](ctrl)
+	handler := NewAdminHandler(mockCache, cache.NewLoader(mockCache))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/admin/cache/purge", nil)
+	rr := httptest.NewRecorder()
+
+	gomock.InOrder(
+		mockCache.EXPECT().Len().Return(3),
+		mockCache.EXPECT().Purge(gomock.Any()),
+	)
+
+	handler.PurgeCache(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var resp cachePurgeResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, 3, resp.Purged)
+}
+
+func TestAdminHandler_PurgeCache_ForgetsNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockCache := mocks.NewMockCache[string, *model.Order](ctrl)
+	loader := cache.NewLoader(mockCache, cache.WithNegativeCache[string, *model.Order](10, time.Hour))
+	handler := NewAdminHandler(mockCache, loader)
+
+	uid := "test-uid-123"
+	mockCache.EXPECT().Get(gomock.Any(), uid).Return(nil, false).AnyTimes()
+	mockCache.EXPECT().Len().Return(0)
+	mockCache.EXPECT().Purge(gomock.Any())
+
+	loads := 0
+	load := func(context.Context) (*model.Order, error) {
+		loads++
+		return nil, cache.ErrNotFound
+	}
+	_, _, err := loader.GetOrLoad(context.Background(), uid, load)
+	assert.ErrorIs(t, err, cache.ErrNotFound)
+
+	handler.PurgeCache(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/admin/cache/purge", nil))
+
+	// Отрицательная запись удалена вместе с кэшем: следующий запрос снова идет в источник
+	_, status, err := loader.GetOrLoad(context.Background(), uid, load)
+	assert.ErrorIs(t, err, cache.ErrNotFound)
+	assert.Equal(t, cache.StatusMiss, status)
+	assert.Equal(t, 2, loads)
+}
+
+func TestAdminHandler_ValidationRules(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockCache := mocks.NewMockCache[string, *model.Order](ctrl)
+	handler := NewAdminHandler(mockCache, cache.NewLoader(mockCache))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/admin/validation/rules", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ValidationRules(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var resp validator.RuleSet
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Contains(t, resp.Fields, validator.EffectiveField{Path: "items[].price", Tag: "gt=0", Effective: "gt=0"})
+	assert.NotEmpty(t, resp.BusinessRules)
+}
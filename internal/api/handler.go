@@ -4,10 +4,16 @@ import (
 	"L0_project/internal/cache"
 	"L0_project/internal/database"
 	"L0_project/internal/metrics"
+	"L0_project/internal/model"
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/prometheus/client_golang/prometheus"
@@ -15,16 +21,21 @@ import (
 
 // OrderHandler обрабатывает HTTP-запросы, связанные с заказами.
 type OrderHandler struct {
-	storage database.Storage // Используем интерфейс
-	cache   cache.Cache      // Используем интерфейс
+	storage database.Storage                    // Используем интерфейс
+	loader  *cache.Loader[string, *model.Order] // Чтение через кэш
 }
 
 // NewOrderHandler создает новый экземпляр OrderHandler.
-func NewOrderHandler(storage database.Storage, cache cache.Cache) *OrderHandler {
-	return &OrderHandler{storage: storage, cache: cache}
+func NewOrderHandler(storage database.Storage, loader *cache.Loader[string, *model.Order]) *OrderHandler {
+	return &OrderHandler{storage: storage, loader: loader}
 }
 
+// cacheStatusHeader - заголовок ответа с состоянием кэша: HIT, STALE или MISS.
+const cacheStatusHeader = "X-Cache"
+
 // GetByUID ищет заказ по UID сначала в кэше, затем в БД.
+// Отсутствующий заказ - 404, временная недоступность БД - 503, прочие ошибки - 500.
+// Состояние кэша передается в заголовке X-Cache.
 func (h *OrderHandler) GetByUID(w http.ResponseWriter, r *http.Request) {
 	// Метрики и трассировка
 	const handlerName = "GetByUID"
@@ -37,34 +48,132 @@ func (h *OrderHandler) GetByUID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 1. Поиск в кэше. Передаем контекст (r.Context()) для трейсинга.
-	if order, found := h.cache.Get(r.Context(), orderUID); found {
-		log.Printf("КЭШ ХИТ: %s", orderUID)
-		metrics.CacheHits.Inc()
-		metrics.HttpRequestsTotal.WithLabelValues(handlerName, "200").Inc()
-		respondWithJSON(w, http.StatusOK, order)
+	// Поиск в кэше, при промахе - в БД. Одновременные промахи по одному UID
+	// объединяются в один запрос к БД. Передаем контекст (r.Context()) для трейсинга.
+	order, status, err := h.loader.GetOrLoad(r.Context(), orderUID, func(ctx context.Context) (*model.Order, error) {
+		log.Printf("КЭШ ПРОМАХ: %s. Запрос к БД.", orderUID)
+		order, err := h.storage.GetOrderByUID(ctx, orderUID)
+		if errors.Is(err, sql.ErrNoRows) {
+			// Запоминается в отрицательном кэше
+			return nil, cache.ErrNotFound
+		}
+		return order, err
+	})
+	w.Header().Set(cacheStatusHeader, status.String())
+	switch {
+	case errors.Is(err, cache.ErrNotFound):
+		log.Printf("Заказ %s не найден (кэш: %s)", orderUID, status)
+		respondWithError(w, http.StatusNotFound, "Заказ не найден", handlerName)
 		return
+	case err != nil && database.IsTransient(err):
+		log.Printf("БД временно недоступна при получении заказа %s: %v", orderUID, err)
+		respondWithError(w, http.StatusServiceUnavailable, "Сервис временно недоступен, повторите запрос позже", handlerName)
+		return
+	case err != nil:
+		log.Printf("Ошибка получения заказа из БД: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Внутренняя ошибка сервера", handlerName)
+		return
+	}
+
+	switch status {
+	case cache.StatusHit:
+		log.Printf("КЭШ ХИТ: %s", orderUID)
+	case cache.StatusStale:
+		log.Printf("КЭШ ХИТ (устаревший, обновляется в фоне): %s", orderUID)
 	}
 
-	// 2. Поиск в БД
-	log.Printf("КЭШ ПРОМАХ: %s. Запрос к БД.", orderUID)
-	metrics.CacheMisses.Inc()
+	metrics.HttpRequestsTotal.WithLabelValues(handlerName, "200").Inc()
+	respondWithJSON(w, http.StatusOK, order)
+}
+
+// orderListResponse - тело ответа GET /api/orders.
+type orderListResponse struct {
+	Orders     []model.Order `json:"orders"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
 
-	// Передаем контекст (r.Context()) для трейсинга.
-	order, err := h.storage.GetOrderByUID(r.Context(), orderUID)
+// List возвращает постраничный список заказов с фильтрами.
+// Следующая страница запрашивается с параметром cursor из поля next_cursor.
+func (h *OrderHandler) List(w http.ResponseWriter, r *http.Request) {
+	// Метрики и трассировка
+	const handlerName = "List"
+	timer := prometheus.NewTimer(metrics.HttpRequestDuration.WithLabelValues(handlerName))
+	defer timer.ObserveDuration()
+
+	filter, cursor, err := parseOrderListQuery(r)
 	if err != nil {
-		log.Printf("Ошибка получения заказа из БД: %v", err)
-		metrics.DBErrors.WithLabelValues("get_order").Inc()
-		respondWithError(w, http.StatusNotFound, "Заказ не найден", handlerName)
+		respondWithError(w, http.StatusBadRequest, err.Error(), handlerName)
 		return
 	}
 
-	// 3. Сохранение в кэш. Передаем контекст.
-	h.cache.Set(r.Context(), orderUID, order)
-	log.Printf("Заказ %s добавлен в кэш.", orderUID)
+	page, err := h.storage.ListOrders(r.Context(), filter, cursor)
+	switch {
+	case err != nil && database.IsTransient(err):
+		log.Printf("БД временно недоступна при получении списка заказов: %v", err)
+		respondWithError(w, http.StatusServiceUnavailable, "Сервис временно недоступен, повторите запрос позже", handlerName)
+		return
+	case err != nil:
+		log.Printf("Ошибка получения списка заказов из БД: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Не удалось получить список заказов", handlerName)
+		return
+	}
+
+	response := orderListResponse{Orders: page.Orders}
+	if page.NextCursor != nil {
+		response.NextCursor = page.NextCursor.Encode()
+	}
 
 	metrics.HttpRequestsTotal.WithLabelValues(handlerName, "200").Inc()
-	respondWithJSON(w, http.StatusOK, order)
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// parseOrderListQuery разбирает query-параметры GET /api/orders.
+func parseOrderListQuery(r *http.Request) (database.OrderFilter, *database.OrderCursor, error) {
+	query := r.URL.Query()
+
+	filter := database.OrderFilter{
+		CustomerID:      query.Get("customer_id"),
+		TrackNumber:     query.Get("track_number"),
+		DeliveryService: query.Get("delivery_service"),
+		Locale:          query.Get("locale"),
+		Provider:        query.Get("provider"),
+		Bank:            query.Get("bank"),
+		Currency:        query.Get("currency"),
+	}
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			return filter, nil, fmt.Errorf("некорректный параметр limit: %q", v)
+		}
+		filter.Limit = limit
+	}
+
+	for param, dst := range map[string]*time.Time{
+		"date_from": &filter.CreatedFrom,
+		"date_to":   &filter.CreatedTo,
+	} {
+		v := query.Get(param)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return filter, nil, fmt.Errorf("некорректный параметр %s (ожидается RFC3339): %q", param, v)
+		}
+		*dst = t
+	}
+
+	var cursor *database.OrderCursor
+	if v := query.Get("cursor"); v != "" {
+		c, err := database.DecodeOrderCursor(v)
+		if err != nil {
+			return filter, nil, err
+		}
+		cursor = c
+	}
+
+	return filter, cursor, nil
 }
 
 // respondWithJSON вспомогательная функция для отправки JSON-ответов.
@@ -1,17 +1,23 @@
 package api
 
 import (
+	"L0_project/internal/cache"
 	"L0_project/internal/cache/mocks"
+	"L0_project/internal/database"
 	db_mocks "L0_project/internal/database/mocks"
 	"L0_project/internal/model"
 	"context"
+	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/mock/gomock"
 )
@@ -29,11 +35,11 @@ var helperTestOrder = &model.Order{
 }
 
 // setupHandlerAndMocks - хелпер для инициализации хендлера и моков
-func setupHandlerAndMocks(t *testing.T) (*gomock.Controller, *OrderHandler, *mocks.MockCache, *db_mocks.MockStorage) {
+func setupHandlerAndMocks(t *testing.T) (*gomock.Controller, *OrderHandler, *mocks.MockCache[string, *model.Order], *db_mocks.MockStorage) {
 	ctrl := gomock.NewController(t)
-	mockCache := mocks.NewMockCache(ctrl)
+	mockCache := mocks.NewMockCache[string, *model.Order](ctrl)
 	mockStorage := db_mocks.NewMockStorage(ctrl)
-	handler := NewOrderHandler(mockStorage, mockCache)
+	handler := NewOrderHandler(mockStorage, cache.NewLoader(mockCache))
 	return ctrl, handler, mockCache, mockStorage
 }
 
@@ -65,6 +71,7 @@ func TestOrderHandler_GetByUID_CacheHit(t *testing.T) {
 
 	// Проверка ответа
 	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "HIT", rr.Header().Get("X-Cache"))
 
 	var order model.Order
 	err := json.Unmarshal(rr.Body.Bytes(), &order)
@@ -91,6 +98,7 @@ func TestOrderHandler_GetByUID_CacheMiss_DBHit(t *testing.T) {
 
 	// Проверка ответа
 	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "MISS", rr.Header().Get("X-Cache"))
 
 	var order model.Order
 	err := json.Unmarshal(rr.Body.Bytes(), &order)
@@ -98,6 +106,38 @@ func TestOrderHandler_GetByUID_CacheMiss_DBHit(t *testing.T) {
 	assert.Equal(t, helperTestOrder.OrderUID, order.OrderUID)
 }
 
+func TestOrderHandler_GetByUID_StaleRevalidatesInBackground(t *testing.T) {
+	ctrl, _, mockCache, mockStorage := setupHandlerAndMocks(t)
+	defer ctrl.Finish()
+	handler := NewOrderHandler(mockStorage, cache.NewLoader(mockCache, cache.WithStaleWhileRevalidate[string, *model.Order](time.Minute)))
+
+	uid := "test-uid-123"
+	updated := *helperTestOrder
+	updated.TrackNumber = "track-updated"
+	refreshed := make(chan struct{})
+
+	// Значение старше мягкого срока отдается из кэша, а обновляется в фоне
+	mockCache.EXPECT().Get(gomock.Any(), uid).Return(helperTestOrder, true)
+	mockCache.EXPECT().Peek(uid).Return(cache.Entry[*model.Order]{Value: helperTestOrder, StoredAt: time.Now().Add(-2 * time.Minute)}, true)
+	mockStorage.EXPECT().GetOrderByUID(gomock.Any(), uid).Return(&updated, nil)
+	mockCache.EXPECT().Set(gomock.Any(), uid, &updated).Do(func(context.Context, string, *model.Order) { close(refreshed) })
+
+	rr := httptest.NewRecorder()
+	handler.GetByUID(rr, createTestRequest(t, uid))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "STALE", rr.Header().Get("X-Cache"))
+	var order model.Order
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &order))
+	assert.Equal(t, helperTestOrder.TrackNumber, order.TrackNumber)
+
+	select {
+	case <-refreshed:
+	case <-time.After(time.Second):
+		t.Fatal("устаревший заказ не обновлен в фоне")
+	}
+}
+
 func TestOrderHandler_GetByUID_NotFound(t *testing.T) {
 	ctrl, handler, mockCache, mockStorage := setupHandlerAndMocks(t)
 	defer ctrl.Finish()
@@ -108,8 +148,8 @@ func TestOrderHandler_GetByUID_NotFound(t *testing.T) {
 
 	// 1. Ожидаем промах кэша
 	mockCache.EXPECT().Get(gomock.Any(), uid).Return(nil, false)
-	// 2. Ожидаем запрос к БД, который вернет ошибку
-	mockStorage.EXPECT().GetOrderByUID(gomock.Any(), uid).Return(nil, errors.New("not found"))
+	// 2. Ожидаем запрос к БД, который вернет "не найдено"
+	mockStorage.EXPECT().GetOrderByUID(gomock.Any(), uid).Return(nil, fmt.Errorf("не удалось получить заказ: %w", sql.ErrNoRows))
 	// 3. Не ожидаем вызова Set в кэш
 	mockCache.EXPECT().Set(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
 
@@ -119,6 +159,72 @@ func TestOrderHandler_GetByUID_NotFound(t *testing.T) {
 	assert.Equal(t, http.StatusNotFound, rr.Code)
 }
 
+func TestOrderHandler_GetByUID_NegativeCache(t *testing.T) {
+	ctrl, _, mockCache, mockStorage := setupHandlerAndMocks(t)
+	defer ctrl.Finish()
+	handler := NewOrderHandler(mockStorage, cache.NewLoader(mockCache, cache.WithNegativeCache[string, *model.Order](10, time.Minute)))
+
+	uid := "not-found-uid"
+
+	// Оба запроса промахиваются мимо основного кэша, но в БД идет только первый
+	mockCache.EXPECT().Get(gomock.Any(), uid).Return(nil, false).Times(2)
+	mockStorage.EXPECT().GetOrderByUID(gomock.Any(), uid).Return(nil, sql.ErrNoRows).Times(1)
+
+	for i := 0; i < 2; i++ {
+		rr := httptest.NewRecorder()
+		handler.GetByUID(rr, createTestRequest(t, uid))
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	}
+
+	// Заказ сохранен консьюмером в основной кэш - отрицательная запись больше не мешает
+	mockCache.EXPECT().Get(gomock.Any(), uid).Return(helperTestOrder, true)
+	rr := httptest.NewRecorder()
+	handler.GetByUID(rr, createTestRequest(t, uid))
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
+
+func TestOrderHandler_GetByUID_DBErrors(t *testing.T) {
+	testCases := []struct {
+		name       string
+		dbErr      error
+		wantStatus int
+	}{
+		{
+			name:       "временная ошибка",
+			dbErr:      fmt.Errorf("не удалось получить заказ: %w", &pq.Error{Code: "57P01"}),
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:       "таймаут запроса",
+			dbErr:      fmt.Errorf("не удалось получить заказ: %w", context.DeadlineExceeded),
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:       "прочая ошибка",
+			dbErr:      errors.New("unexpected"),
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl, handler, mockCache, mockStorage := setupHandlerAndMocks(t)
+			defer ctrl.Finish()
+
+			uid := "test-uid-123"
+			rr := httptest.NewRecorder()
+
+			mockCache.EXPECT().Get(gomock.Any(), uid).Return(nil, false)
+			mockStorage.EXPECT().GetOrderByUID(gomock.Any(), uid).Return(nil, tc.dbErr)
+			mockCache.EXPECT().Set(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+			handler.GetByUID(rr, createTestRequest(t, uid))
+
+			assert.Equal(t, tc.wantStatus, rr.Code)
+		})
+	}
+}
+
 func TestOrderHandler_GetByUID_NoUID(t *testing.T) {
 	ctrl, handler, mockCache, mockStorage := setupHandlerAndMocks(t)
 	defer ctrl.Finish()
@@ -140,3 +246,70 @@ func TestOrderHandler_GetByUID_NoUID(t *testing.T) {
 	// Проверка ответа
 	assert.Equal(t, http.StatusBadRequest, rr.Code)
 }
+
+func TestOrderHandler_List_WithFiltersAndCursor(t *testing.T) {
+	ctrl, handler, _, mockStorage := setupHandlerAndMocks(t)
+	defer ctrl.Finish()
+
+	cursor := database.OrderCursor{DateCreated: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), OrderUID: "cursor-uid"}
+	next := database.OrderCursor{DateCreated: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), OrderUID: helperTestOrder.OrderUID}
+
+	expectedFilter := database.OrderFilter{
+		CustomerID:  "cust",
+		Provider:    "wbpay",
+		CreatedFrom: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Limit:       10,
+	}
+	mockStorage.EXPECT().ListOrders(gomock.Any(), expectedFilter, &cursor).
+		Return(&database.OrderPage{Orders: []model.Order{*helperTestOrder}, NextCursor: &next}, nil)
+
+	req := httptest.NewRequest("GET", "/api/orders?customer_id=cust&provider=wbpay&date_from=2024-01-01T00:00:00Z&limit=10&cursor="+cursor.Encode(), nil)
+	rr := httptest.NewRecorder()
+
+	handler.List(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var resp orderListResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Len(t, resp.Orders, 1)
+	assert.Equal(t, next.Encode(), resp.NextCursor)
+}
+
+func TestOrderHandler_List_BadParams(t *testing.T) {
+	ctrl, handler, _, mockStorage := setupHandlerAndMocks(t)
+	defer ctrl.Finish()
+
+	mockStorage.EXPECT().ListOrders(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	for _, query := range []string{"limit=abc", "date_to=yesterday", "cursor=not-a-cursor!"} {
+		rr := httptest.NewRecorder()
+		handler.List(rr, httptest.NewRequest("GET", "/api/orders?"+query, nil))
+		assert.Equal(t, http.StatusBadRequest, rr.Code, query)
+	}
+}
+
+func TestOrderHandler_List_DBError(t *testing.T) {
+	ctrl, handler, _, mockStorage := setupHandlerAndMocks(t)
+	defer ctrl.Finish()
+
+	testCases := []struct {
+		name       string
+		err        error
+		wantStatus int
+	}{
+		{name: "временная ошибка", err: &pq.Error{Code: "08006"}, wantStatus: http.StatusServiceUnavailable},
+		{name: "прочая ошибка", err: errors.New("db down"), wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockStorage.EXPECT().ListOrders(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, tc.err)
+
+			rr := httptest.NewRecorder()
+			handler.List(rr, httptest.NewRequest("GET", "/api/orders", nil))
+
+			assert.Equal(t, tc.wantStatus, rr.Code)
+		})
+	}
+}
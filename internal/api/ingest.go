@@ -0,0 +1,366 @@
+package api
+
+import (
+	"L0_project/internal/database"
+	"L0_project/internal/ingest"
+	"L0_project/internal/metrics"
+	"L0_project/internal/model"
+	"L0_project/internal/validator"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// idempotencyKeyHeader - заголовок с ключом идемпотентности запроса.
+	idempotencyKeyHeader = "Idempotency-Key"
+	// idempotentReplayHeader выставляется в ответе, повторенном по ключу идемпотентности.
+	idempotentReplayHeader = "Idempotent-Replayed"
+	// maxIdempotencyKeyLength - максимальная длина ключа (размер колонки в БД).
+	maxIdempotencyKeyLength = 255
+	// maxIngestBodyBytes ограничивает тело запроса приема заказов (как размер сообщения Kafka).
+	maxIngestBodyBytes = 10 << 20
+	// ingestTimeout ограничивает ожидание БД: временные ошибки сервис приема
+	// повторяет до отмены контекста, а HTTP-клиент не должен ждать бесконечно.
+	ingestTimeout = 30 * time.Second
+)
+
+// Статусы приема одного заказа в ответах API.
+const (
+	ingestStatusSaved       = "saved"       // Заказ сохранен
+	ingestStatusUnchanged   = "unchanged"   // Заказ уже сохранен с теми же данными
+	ingestStatusInvalid     = "invalid"     // Некорректный JSON или заказ не прошел валидацию
+	ingestStatusConflict    = "conflict"    // Данные противоречат уже сохраненному заказу
+	ingestStatusFailed      = "failed"      // Ошибка БД, повтор не поможет
+	ingestStatusUnavailable = "unavailable" // БД временно недоступна, запрос можно повторить
+)
+
+// IngestOptions - параметры приема заказов через HTTP API.
+type IngestOptions struct {
+	MaxBatchSize   int           // Максимум заказов в одном пакетном запросе
+	IdempotencyTTL time.Duration // Сколько хранится ответ для ключа идемпотентности
+}
+
+// IngestHandler принимает заказы через HTTP так же, как Kafka-консюмер.
+type IngestHandler struct {
+	service *ingest.Service
+	storage database.Storage // Хранение ключей идемпотентности
+	opts    IngestOptions
+}
+
+// NewIngestHandler создает новый экземпляр IngestHandler.
+func NewIngestHandler(service *ingest.Service, storage database.Storage, opts IngestOptions) *IngestHandler {
+	return &IngestHandler{service: service, storage: storage, opts: opts}
+}
+
+// ingestResult - результат приема одного заказа.
+type ingestResult struct {
+	Index    int    `json:"index"` // Позиция заказа в пакете (0 для одиночного запроса)
+	OrderUID string `json:"order_uid,omitempty"`
+	Status   string `json:"status"`
+	Reason   string `json:"reason,omitempty"` // Для invalid: json_unmarshal_error или validation_error
+	Error    string `json:"error,omitempty"`  // Описание ошибки, не относящейся к отдельным полям
+	// Ошибки полей для validation_error (формат - validator.ErrorSchema)
+	Errors []validator.FieldError `json:"errors,omitempty"`
+	// Нарушения бизнес-правил, из-за которых заказ отклонен (business_rule_violation)
+	Violations []validator.Violation `json:"violations,omitempty"`
+	// Нарушения бизнес-правил с уровнем warn: заказ принят, но данные стоит проверить
+	Warnings []validator.Violation `json:"warnings,omitempty"`
+}
+
+// retryable сообщает, что заказ не принят из-за временной проблемы.
+func (r ingestResult) retryable() bool {
+	return r.Status == ingestStatusUnavailable
+}
+
+// batchResponse - тело ответа POST /api/orders:batch.
+type batchResponse struct {
+	Saved     int            `json:"saved"`
+	Unchanged int            `json:"unchanged"`
+	Rejected  int            `json:"rejected"`
+	Results   []ingestResult `json:"results"`
+}
+
+// Create принимает один заказ. Новый или измененный заказ - 201, повторный
+// прием тех же данных - 200, некорректный JSON - 400, ошибки валидации - 422
+// (с перечнем ошибок полей на языке из Accept-Language), конфликт с сохраненным заказом - 409, временная
+// недоступность БД - 503. Поддерживает заголовок Idempotency-Key.
+func (h *IngestHandler) Create(w http.ResponseWriter, r *http.Request) {
+	// Метрики и трассировка
+	const handlerName = "CreateOrder"
+	timer := prometheus.NewTimer(metrics.HttpRequestDuration.WithLabelValues(handlerName))
+	defer timer.ObserveDuration()
+
+	lang := validator.FromAcceptLanguage(r.Header.Get("Accept-Language"))
+	h.serveIdempotent(w, r, handlerName, func(ctx context.Context, body []byte) (int, any, bool) {
+		result := h.ingestOne(ctx, body, lang)
+		return createStatusCode(result), result, result.retryable()
+	})
+}
+
+// CreateBatch принимает JSON-массив заказов. Некорректные заказы не мешают
+// сохранению остальных: результат каждого заказа возвращается в results
+// в порядке массива, а сам ответ - 200. Не-массив - 400, слишком большой
+// пакет - 413. Поддерживает заголовок Idempotency-Key.
+func (h *IngestHandler) CreateBatch(w http.ResponseWriter, r *http.Request) {
+	// Метрики и трассировка
+	const handlerName = "CreateOrders"
+	timer := prometheus.NewTimer(metrics.HttpRequestDuration.WithLabelValues(handlerName))
+	defer timer.ObserveDuration()
+
+	lang := validator.FromAcceptLanguage(r.Header.Get("Accept-Language"))
+	h.serveIdempotent(w, r, handlerName, func(ctx context.Context, body []byte) (int, any, bool) {
+		var raw []json.RawMessage
+		if err := json.Unmarshal(body, &raw); err != nil {
+			return http.StatusBadRequest, errorResponse("Тело запроса должно быть JSON-массивом заказов"), false
+		}
+		if len(raw) == 0 {
+			return http.StatusBadRequest, errorResponse("Пакет не содержит заказов"), false
+		}
+		if h.opts.MaxBatchSize > 0 && len(raw) > h.opts.MaxBatchSize {
+			return http.StatusRequestEntityTooLarge, errorResponse(fmt.Sprintf("В пакете %d заказов, максимум %d", len(raw), h.opts.MaxBatchSize)), false
+		}
+
+		response, retryable := h.ingestBatch(ctx, raw, lang)
+		return http.StatusOK, response, retryable
+	})
+}
+
+// ErrorSchema отдает JSON Schema списка ошибок валидации из поля errors.
+func (h *IngestHandler) ErrorSchema(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/schema+json")
+	if _, err := w.Write(validator.ErrorSchema); err != nil {
+		log.Printf("Ошибка записи HTTP-ответа: %v", err)
+	}
+}
+
+// ingestOne десериализует, валидирует и сохраняет один заказ.
+func (h *IngestHandler) ingestOne(ctx context.Context, body []byte, lang validator.Lang) ingestResult {
+	order, warnings, err := h.service.Decode(body)
+	if err != nil {
+		return withWarnings(invalidResult(0, err, lang), warnings, lang)
+	}
+	result, err := h.service.Save(ctx, order)
+	return withWarnings(saveResult(0, order.OrderUID, result, err), warnings, lang)
+}
+
+// ingestBatch сохраняет валидные заказы пакета одним вызовом сервиса приема.
+func (h *IngestHandler) ingestBatch(ctx context.Context, raw []json.RawMessage, lang validator.Lang) (batchResponse, bool) {
+	response := batchResponse{Results: make([]ingestResult, len(raw))}
+	var (
+		orders   []*model.Order
+		indexes  []int
+		warnings = make([][]validator.Violation, len(raw))
+	)
+	for i, data := range raw {
+		order, orderWarnings, err := h.service.Decode(data)
+		warnings[i] = orderWarnings
+		if err != nil {
+			response.Results[i] = withWarnings(invalidResult(i, err, lang), orderWarnings, lang)
+			continue
+		}
+		orders = append(orders, order)
+		indexes = append(indexes, i)
+	}
+	for j, outcome := range h.service.SaveBatch(ctx, orders) {
+		i := indexes[j]
+		response.Results[i] = withWarnings(saveResult(i, orders[j].OrderUID, outcome.Result, outcome.Err), warnings[i], lang)
+	}
+
+	retryable := false
+	for _, result := range response.Results {
+		switch result.Status {
+		case ingestStatusSaved:
+			response.Saved++
+		case ingestStatusUnchanged:
+			response.Unchanged++
+		default:
+			response.Rejected++
+		}
+		retryable = retryable || result.retryable()
+	}
+	return response, retryable
+}
+
+// invalidResult описывает заказ, не прошедший десериализацию или валидацию.
+// Ошибки полей и нарушения бизнес-правил переводятся на язык lang.
+func invalidResult(index int, err error, lang validator.Lang) ingestResult {
+	result := ingestResult{Index: index, Status: ingestStatusInvalid}
+	var invalid *ingest.InvalidOrderError
+	if errors.As(err, &invalid) {
+		result.OrderUID = invalid.OrderUID
+		result.Reason = invalid.Reason
+	}
+
+	var (
+		validationErr *validator.ValidationError
+		rulesErr      *validator.RuleViolationError
+	)
+	switch {
+	case errors.As(err, &validationErr):
+		result.Errors = validationErr.Localize(lang).Fields
+	case errors.As(err, &rulesErr):
+		result.Violations = rulesErr.Localize(lang).Violations
+	default:
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// withWarnings добавляет к результату предупреждения бизнес-правил на языке lang.
+func withWarnings(result ingestResult, warnings []validator.Violation, lang validator.Lang) ingestResult {
+	result.Warnings = validator.LocalizeViolations(warnings, lang)
+	return result
+}
+
+// saveResult описывает итог сохранения валидного заказа.
+func saveResult(index int, orderUID string, result ingest.Result, err error) ingestResult {
+	var conflict *database.OrderConflictError
+	switch {
+	case err == nil:
+		return ingestResult{Index: index, OrderUID: orderUID, Status: result.String()} // saved или unchanged
+	case errors.As(err, &conflict):
+		return ingestResult{Index: index, OrderUID: orderUID, Status: ingestStatusConflict, Error: err.Error()}
+	case database.IsPermanent(err):
+		log.Printf("Постоянная ошибка сохранения заказа %s: %v", orderUID, err)
+		return ingestResult{Index: index, OrderUID: orderUID, Status: ingestStatusFailed, Error: "Заказ не может быть сохранен"}
+	case database.IsTransient(err) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded):
+		log.Printf("БД временно недоступна при сохранении заказа %s: %v", orderUID, err)
+		return ingestResult{Index: index, OrderUID: orderUID, Status: ingestStatusUnavailable, Error: "Сервис временно недоступен, повторите запрос позже"}
+	default:
+		log.Printf("Ошибка сохранения заказа %s: %v", orderUID, err)
+		return ingestResult{Index: index, OrderUID: orderUID, Status: ingestStatusFailed, Error: "Внутренняя ошибка сервера"}
+	}
+}
+
+// createStatusCode возвращает HTTP-код ответа на прием одного заказа.
+func createStatusCode(result ingestResult) int {
+	switch result.Status {
+	case ingestStatusSaved:
+		return http.StatusCreated
+	case ingestStatusUnchanged:
+		return http.StatusOK
+	case ingestStatusInvalid:
+		if result.Reason == ingest.ReasonJSON {
+			return http.StatusBadRequest
+		}
+		return http.StatusUnprocessableEntity
+	case ingestStatusConflict:
+		return http.StatusConflict
+	case ingestStatusUnavailable:
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// errorResponse - тело ответа с ошибкой запроса целиком.
+func errorResponse(message string) map[string]string {
+	return map[string]string{"error": message}
+}
+
+// serveIdempotent читает тело запроса и выполняет process. Если задан заголовок
+// Idempotency-Key, ответ сохраняется, и повторный запрос с тем же ключом и телом
+// получает его без повторной обработки. Тот же ключ с другим запросом - 422,
+// пока первый запрос с ключом выполняется - 409. Ответ, который можно получить
+// иначе при повторе (retryable), не сохраняется.
+func (h *IngestHandler) serveIdempotent(w http.ResponseWriter, r *http.Request, handlerName string,
+	process func(ctx context.Context, body []byte) (code int, payload any, retryable bool),
+) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIngestBodyBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			respondWithError(w, http.StatusRequestEntityTooLarge, "Тело запроса слишком большое", handlerName)
+			return
+		}
+		respondWithError(w, http.StatusBadRequest, "Не удалось прочитать тело запроса", handlerName)
+		return
+	}
+
+	key := r.Header.Get(idempotencyKeyHeader)
+	if len(key) > maxIdempotencyKeyLength {
+		respondWithError(w, http.StatusBadRequest, "Слишком длинный Idempotency-Key", handlerName)
+		return
+	}
+	if key != "" {
+		hash := requestHash(r, body)
+		record, err := h.storage.ReserveIdempotencyKey(r.Context(), key, hash, h.opts.IdempotencyTTL)
+		switch {
+		case err != nil && database.IsTransient(err):
+			log.Printf("БД временно недоступна при проверке Idempotency-Key: %v", err)
+			respondWithError(w, http.StatusServiceUnavailable, "Сервис временно недоступен, повторите запрос позже", handlerName)
+			return
+		case err != nil:
+			log.Printf("Ошибка проверки Idempotency-Key: %v", err)
+			respondWithError(w, http.StatusInternalServerError, "Внутренняя ошибка сервера", handlerName)
+			return
+		case record != nil && record.RequestHash != hash:
+			respondWithError(w, http.StatusUnprocessableEntity, "Idempotency-Key уже использован с другим запросом", handlerName)
+			return
+		case record != nil && !record.Completed():
+			respondWithError(w, http.StatusConflict, "Запрос с этим Idempotency-Key еще выполняется", handlerName)
+			return
+		case record != nil:
+			code := int(record.StatusCode.Int32)
+			w.Header().Set(idempotentReplayHeader, "true")
+			metrics.HttpRequestsTotal.WithLabelValues(handlerName, strconv.Itoa(code)).Inc()
+			respondWithRawJSON(w, code, record.Response)
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), ingestTimeout)
+	code, payload, retryable := process(ctx, body)
+	cancel()
+	response, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Ошибка сериализации JSON: %v", err)
+		code, retryable = http.StatusInternalServerError, true
+		response, _ = json.Marshal(errorResponse("Внутренняя ошибка сервера"))
+	}
+
+	if key != "" {
+		// Ключ сохраняется, даже если клиент уже отключился
+		ctx = context.WithoutCancel(r.Context())
+		if retryable || code >= http.StatusInternalServerError {
+			err = h.storage.ReleaseIdempotencyKey(ctx, key)
+		} else {
+			err = h.storage.CompleteIdempotencyKey(ctx, key, code, response)
+		}
+		if err != nil {
+			log.Printf("Ошибка сохранения состояния Idempotency-Key %s: %v", key, err)
+		}
+	}
+
+	metrics.HttpRequestsTotal.WithLabelValues(handlerName, strconv.Itoa(code)).Inc()
+	respondWithRawJSON(w, code, response)
+}
+
+// requestHash - хэш метода, пути и тела запроса. Ключ идемпотентности,
+// повторно использованный с другим запросом, отклоняется.
+func requestHash(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	hash.Write(bytes.TrimSpace(body))
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// respondWithRawJSON отправляет уже сериализованный JSON-ответ.
+func respondWithRawJSON(w http.ResponseWriter, code int, response []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if _, err := w.Write(response); err != nil {
+		log.Printf("Ошибка записи HTTP-ответа: %v", err)
+	}
+}
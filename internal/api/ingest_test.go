@@ -0,0 +1,368 @@
+package api
+
+import (
+	"L0_project/internal/cache"
+	"L0_project/internal/cache/mocks"
+	"L0_project/internal/database"
+	db_mocks "L0_project/internal/database/mocks"
+	"L0_project/internal/ingest"
+	"L0_project/internal/model"
+	"L0_project/internal/retry"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+// testIngestOrder - валидный заказ для тестов приема
+const testIngestOrder = `{
+	"order_uid": "b563feb7-b2b8-4b6f-807c-9b63a11e81b9", "track_number": "WBILMTESTTRACK", "entry": "WBIL",
+	"delivery": {"name": "Test Testov", "phone": "+9720000000", "zip": "2639809", "city": "Kiryat Mozkin",
+		"address": "Ploshad Mira 15", "region": "Kraiot", "email": "test@gmail.com"},
+	"payment": {"transaction": "b563feb7-b2b8-4b6f-807c-9b63a11e81b9", "currency": "USD", "provider": "wbpay", "amount": 1817,
+		"payment_dt": 1637907727, "bank": "alpha", "delivery_cost": 1500, "goods_total": 317},
+	"items": [{"chrt_id": 9934930, "track_number": "WBILMTESTTRACK", "price": 453, "rid": "ab4219087a764ae0btest",
+		"name": "Mascaras", "sale": 30, "size": "0", "total_price": 317, "nm_id": 2389212, "brand": "Vivienne Sabo", "status": 202}],
+	"locale": "en", "customer_id": "test", "delivery_service": "meest", "shardkey": "9", "sm_id": 99,
+	"date_created": "2021-11-26T06:22:19Z", "oof_shard": "1"
+}`
+
+// setupIngestHandler - хелпер для создания обработчика приема с моками
+func setupIngestHandler(t *testing.T) (*IngestHandler, *mocks.MockCache[string, *model.Order], *db_mocks.MockStorage) {
+	ctrl := gomock.NewController(t)
+	mockCache := mocks.NewMockCache[string, *model.Order](ctrl)
+	mockStorage := db_mocks.NewMockStorage(ctrl)
+	service := ingest.NewService(mockStorage, cache.NewLoader(mockCache), retry.Policy{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond, IsTransient: database.IsTransient, IsPermanent: database.IsPermanent})
+	handler := NewIngestHandler(service, mockStorage, IngestOptions{MaxBatchSize: 3, IdempotencyTTL: time.Hour})
+	return handler, mockCache, mockStorage
+}
+
+func TestIngestHandler_Create(t *testing.T) {
+	testCases := []struct {
+		name       string
+		body       string
+		saveErr    error
+		saves      int
+		wantStatus int
+		wantResult string
+	}{
+		{name: "новый заказ", body: testIngestOrder, saves: 1, wantStatus: http.StatusCreated, wantResult: "saved"},
+		{name: "повторный прием", body: testIngestOrder, saves: 1, saveErr: database.ErrOrderUnchanged, wantStatus: http.StatusOK, wantResult: "unchanged"},
+		{name: "конфликт", body: testIngestOrder, saves: 1, saveErr: &database.OrderConflictError{OrderUID: "b563feb7-b2b8-4b6f-807c-9b63a11e81b9", Err: errors.New("duplicate key")}, wantStatus: http.StatusConflict, wantResult: "conflict"},
+		{name: "битый JSON", body: `{`, wantStatus: http.StatusBadRequest, wantResult: "invalid"},
+		{name: "ошибка валидации", body: `{"order_uid": "x"}`, wantStatus: http.StatusUnprocessableEntity, wantResult: "invalid"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler, mockCache, mockStorage := setupIngestHandler(t)
+			mockStorage.EXPECT().SaveOrder(gomock.Any(), gomock.Any()).Return(tc.saveErr).Times(tc.saves)
+			if tc.saveErr == nil {
+				mockStorage.EXPECT().GetOrderByUID(gomock.Any(), "b563feb7-b2b8-4b6f-807c-9b63a11e81b9").Return(&model.Order{}, nil).Times(tc.saves)
+				mockCache.EXPECT().Set(gomock.Any(), "b563feb7-b2b8-4b6f-807c-9b63a11e81b9", gomock.Any()).Times(tc.saves)
+			}
+
+			rr := httptest.NewRecorder()
+			handler.Create(rr, httptest.NewRequest("POST", "/api/orders", strings.NewReader(tc.body)))
+
+			assert.Equal(t, tc.wantStatus, rr.Code)
+			var result ingestResult
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &result))
+			assert.Equal(t, tc.wantResult, result.Status)
+			if tc.wantResult == "invalid" {
+				assert.True(t, result.Error != "" || len(result.Errors) > 0)
+			}
+		})
+	}
+}
+
+func TestIngestHandler_Create_FieldErrors(t *testing.T) {
+	handler, _, _ := setupIngestHandler(t)
+	body := strings.Replace(testIngestOrder, `"price": 453`, `"price": 0`, 1)
+
+	req := httptest.NewRequest("POST", "/api/orders", strings.NewReader(body))
+	req.Header.Set("Accept-Language", "en-US,en;q=0.9,ru;q=0.8")
+	rr := httptest.NewRecorder()
+	handler.Create(rr, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	var result ingestResult
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &result))
+	assert.Equal(t, "validation_error", result.Reason)
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, "items[0].price", result.Errors[0].Path)
+	assert.Equal(t, "gt", result.Errors[0].Rule)
+	assert.Equal(t, "0", result.Errors[0].Param)
+	assert.EqualValues(t, 0, result.Errors[0].Value)
+	assert.Equal(t, "must be greater than 0", result.Errors[0].Message)
+}
+
+func TestIngestHandler_Create_BusinessRules(t *testing.T) {
+	testCases := []struct {
+		name         string
+		body         string
+		saves        int
+		wantStatus   int
+		wantReject   string
+		wantWarnings int
+	}{
+		{
+			name:       "нарушение с отклонением",
+			body:       strings.Replace(testIngestOrder, `"goods_total": 317`, `"goods_total": 300`, 1),
+			wantStatus: http.StatusUnprocessableEntity,
+			wantReject: "goods_total_mismatch",
+		},
+		{
+			name:         "предупреждение не мешает приему",
+			body:         strings.Replace(testIngestOrder, `"track_number": "WBILMTESTTRACK", "price"`, `"track_number": "OTHER", "price"`, 1),
+			saves:        1,
+			wantStatus:   http.StatusCreated,
+			wantWarnings: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler, mockCache, mockStorage := setupIngestHandler(t)
+			mockStorage.EXPECT().SaveOrder(gomock.Any(), gomock.Any()).Return(nil).Times(tc.saves)
+			mockStorage.EXPECT().GetOrderByUID(gomock.Any(), gomock.Any()).Return(&model.Order{}, nil).Times(tc.saves)
+			mockCache.EXPECT().Set(gomock.Any(), gomock.Any(), gomock.Any()).Times(tc.saves)
+
+			rr := httptest.NewRecorder()
+			handler.Create(rr, httptest.NewRequest("POST", "/api/orders", strings.NewReader(tc.body)))
+
+			assert.Equal(t, tc.wantStatus, rr.Code)
+			var result ingestResult
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &result))
+			if tc.wantReject != "" {
+				assert.Equal(t, "business_rule_violation", result.Reason)
+				require.NotEmpty(t, result.Violations)
+				assert.Equal(t, tc.wantReject, result.Violations[0].Code)
+			}
+			assert.Len(t, result.Warnings, tc.wantWarnings)
+		})
+	}
+}
+
+func TestIngestHandler_CreateBatch_PerOrderResults(t *testing.T) {
+	handler, mockCache, mockStorage := setupIngestHandler(t)
+
+	second := strings.ReplaceAll(testIngestOrder, "b563feb7-b2b8-4b6f-807c-9b63a11e81b9", "5f0b5e3a-7b8e-4a4f-9d63-1c2b3a4d5e6f")
+	body := "[" + testIngestOrder + `, {"order_uid": "broken"}, ` + second + "]"
+
+	conflictErr := &database.OrderConflictError{OrderUID: "5f0b5e3a-7b8e-4a4f-9d63-1c2b3a4d5e6f", Err: errors.New("duplicate key")}
+	mockStorage.EXPECT().SaveOrders(gomock.Any(), gomock.Len(2)).Return([]error{nil, conflictErr}, nil)
+	mockStorage.EXPECT().GetOrderByUID(gomock.Any(), "b563feb7-b2b8-4b6f-807c-9b63a11e81b9").Return(&model.Order{}, nil)
+	mockCache.EXPECT().Set(gomock.Any(), "b563feb7-b2b8-4b6f-807c-9b63a11e81b9", gomock.Any())
+
+	rr := httptest.NewRecorder()
+	handler.CreateBatch(rr, httptest.NewRequest("POST", "/api/orders:batch", strings.NewReader(body)))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var response batchResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal(t, 1, response.Saved)
+	assert.Equal(t, 2, response.Rejected)
+	require.Len(t, response.Results, 3)
+	assert.Equal(t, "saved", response.Results[0].Status)
+	assert.Equal(t, "invalid", response.Results[1].Status)
+	assert.Equal(t, "broken", response.Results[1].OrderUID)
+	assert.Equal(t, 1, response.Results[1].Index)
+	assert.Equal(t, "conflict", response.Results[2].Status)
+}
+
+func TestIngestHandler_CachesSavedOrders(t *testing.T) {
+	const (
+		firstUID  = "b563feb7-b2b8-4b6f-807c-9b63a11e81b9"
+		secondUID = "5f0b5e3a-7b8e-4a4f-9d63-1c2b3a4d5e6f"
+	)
+	second := strings.ReplaceAll(testIngestOrder, firstUID, secondUID)
+
+	testCases := []struct {
+		name       string
+		send       func(h *IngestHandler, rr *httptest.ResponseRecorder)
+		wantStatus int
+		wantCached []string
+	}{
+		{
+			name: "один заказ",
+			send: func(h *IngestHandler, rr *httptest.ResponseRecorder) {
+				h.Create(rr, httptest.NewRequest("POST", "/api/orders", strings.NewReader(testIngestOrder)))
+			},
+			wantStatus: http.StatusCreated,
+			wantCached: []string{firstUID},
+		},
+		{
+			name: "пакет",
+			send: func(h *IngestHandler, rr *httptest.ResponseRecorder) {
+				body := "[" + testIngestOrder + ", " + second + "]"
+				h.CreateBatch(rr, httptest.NewRequest("POST", "/api/orders:batch", strings.NewReader(body)))
+			},
+			wantStatus: http.StatusOK,
+			wantCached: []string{firstUID, secondUID},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			mockStorage := db_mocks.NewMockStorage(ctrl)
+			orderCache := cache.NewLRUCache[string, *model.Order](10)
+			service := ingest.NewService(mockStorage, cache.NewLoader(orderCache), retry.Policy{})
+			handler := NewIngestHandler(service, mockStorage, IngestOptions{MaxBatchSize: 3})
+
+			mockStorage.EXPECT().SaveOrder(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+			mockStorage.EXPECT().SaveOrders(gomock.Any(), gomock.Any()).Return([]error{nil, nil}, nil).AnyTimes()
+			mockStorage.EXPECT().GetOrderByUID(gomock.Any(), gomock.Any()).DoAndReturn(func(_ any, uid string) (*model.Order, error) {
+				return &model.Order{OrderUID: uid, Status: model.StatusCreated, StatusVersion: 1}, nil
+			}).Times(len(tc.wantCached))
+
+			rr := httptest.NewRecorder()
+			tc.send(handler, rr)
+			require.Equal(t, tc.wantStatus, rr.Code)
+
+			// Заказ доступен из кэша без обращения к БД, со статусом из БД
+			for _, uid := range tc.wantCached {
+				entry, found := orderCache.Peek(uid)
+				require.True(t, found, uid)
+				assert.Equal(t, model.StatusCreated, entry.Value.Status)
+			}
+		})
+	}
+}
+
+func TestIngestHandler_CreateBatch_RejectedRequests(t *testing.T) {
+	testCases := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{name: "не массив", body: testIngestOrder, wantStatus: http.StatusBadRequest},
+		{name: "пустой пакет", body: `[]`, wantStatus: http.StatusBadRequest},
+		{name: "слишком большой пакет", body: `[{}, {}, {}, {}]`, wantStatus: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler, _, _ := setupIngestHandler(t)
+
+			rr := httptest.NewRecorder()
+			handler.CreateBatch(rr, httptest.NewRequest("POST", "/api/orders:batch", strings.NewReader(tc.body)))
+
+			assert.Equal(t, tc.wantStatus, rr.Code)
+		})
+	}
+}
+
+// idempotentRequest - запрос приема заказа с ключом идемпотентности
+func idempotentRequest(key string) *http.Request {
+	req := httptest.NewRequest("POST", "/api/orders", strings.NewReader(testIngestOrder))
+	req.Header.Set(idempotencyKeyHeader, key)
+	return req
+}
+
+func TestIngestHandler_Idempotency_FirstRequestStored(t *testing.T) {
+	handler, mockCache, mockStorage := setupIngestHandler(t)
+
+	mockStorage.EXPECT().ReserveIdempotencyKey(gomock.Any(), "key-1", gomock.Any(), time.Hour).Return(nil, nil)
+	mockStorage.EXPECT().SaveOrder(gomock.Any(), gomock.Any()).Return(nil)
+	mockStorage.EXPECT().GetOrderByUID(gomock.Any(), gomock.Any()).Return(&model.Order{}, nil)
+	mockCache.EXPECT().Set(gomock.Any(), gomock.Any(), gomock.Any())
+	mockStorage.EXPECT().CompleteIdempotencyKey(gomock.Any(), "key-1", http.StatusCreated, gomock.Any()).
+		DoAndReturn(func(_ any, _ string, _ int, response []byte) error {
+			assert.Contains(t, string(response), `"status":"saved"`)
+			return nil
+		})
+
+	rr := httptest.NewRecorder()
+	handler.Create(rr, idempotentRequest("key-1"))
+	assert.Equal(t, http.StatusCreated, rr.Code)
+}
+
+func TestIngestHandler_Idempotency_Replay(t *testing.T) {
+	handler, _, mockStorage := setupIngestHandler(t)
+
+	stored := []byte(`{"index":0,"order_uid":"b563feb7-b2b8-4b6f-807c-9b63a11e81b9","status":"saved"}`)
+	mockStorage.EXPECT().ReserveIdempotencyKey(gomock.Any(), "key-1", gomock.Any(), time.Hour).DoAndReturn(
+		func(_ any, key, hash string, _ time.Duration) (*database.IdempotencyRecord, error) {
+			return &database.IdempotencyRecord{Key: key, RequestHash: hash, StatusCode: sql.NullInt32{Int32: http.StatusCreated, Valid: true}, Response: stored}, nil
+		})
+	// Заказ повторно не сохраняется
+	mockStorage.EXPECT().SaveOrder(gomock.Any(), gomock.Any()).Times(0)
+
+	rr := httptest.NewRecorder()
+	handler.Create(rr, idempotentRequest("key-1"))
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Equal(t, "true", rr.Header().Get(idempotentReplayHeader))
+	assert.JSONEq(t, string(stored), rr.Body.String())
+}
+
+func TestIngestHandler_Idempotency_KeyConflicts(t *testing.T) {
+	testCases := []struct {
+		name       string
+		record     func(hash string) *database.IdempotencyRecord
+		wantStatus int
+	}{
+		{
+			name: "ключ использован с другим телом",
+			record: func(string) *database.IdempotencyRecord {
+				return &database.IdempotencyRecord{RequestHash: "other", StatusCode: sql.NullInt32{Int32: 201, Valid: true}}
+			},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "запрос с ключом еще выполняется",
+			record:     func(hash string) *database.IdempotencyRecord { return &database.IdempotencyRecord{RequestHash: hash} },
+			wantStatus: http.StatusConflict,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler, _, mockStorage := setupIngestHandler(t)
+			mockStorage.EXPECT().ReserveIdempotencyKey(gomock.Any(), "key-1", gomock.Any(), time.Hour).DoAndReturn(
+				func(_ any, _, hash string, _ time.Duration) (*database.IdempotencyRecord, error) {
+					return tc.record(hash), nil
+				})
+
+			rr := httptest.NewRecorder()
+			handler.Create(rr, idempotentRequest("key-1"))
+			assert.Equal(t, tc.wantStatus, rr.Code)
+		})
+	}
+}
+
+func TestIngestHandler_Idempotency_ReleasedOnRetryableFailure(t *testing.T) {
+	handler, _, mockStorage := setupIngestHandler(t)
+
+	mockStorage.EXPECT().ReserveIdempotencyKey(gomock.Any(), "key-1", gomock.Any(), gomock.Any()).Return(nil, nil)
+	mockStorage.EXPECT().SaveOrder(gomock.Any(), gomock.Any()).Return(errors.New("boom")).Times(3)
+	// Ответ с ошибкой сервера не запоминается: клиент может повторить запрос с тем же ключом
+	mockStorage.EXPECT().ReleaseIdempotencyKey(gomock.Any(), "key-1").Return(nil)
+
+	rr := httptest.NewRecorder()
+	handler.Create(rr, idempotentRequest("key-1"))
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+}
+
+func TestServer_IngestRoutes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockStorage := db_mocks.NewMockStorage(ctrl)
+	service := ingest.NewService(mockStorage, cache.NewLoader(mocks.NewMockCache[string, *model.Order](ctrl)), retry.Policy{})
+	server := NewServer("0", mockStorage, nil, nil, service, IngestOptions{})
+
+	// Маршрут с двоеточием не должен совпадать с /api/orders
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, httptest.NewRequest("POST", "/api/orders:batch", strings.NewReader(`{}`)))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "JSON-массивом")
+}
@@ -3,6 +3,8 @@ package api
 import (
 	"L0_project/internal/cache"
 	"L0_project/internal/database"
+	"L0_project/internal/ingest"
+	"L0_project/internal/model"
 	"fmt"
 	"net/http"
 
@@ -17,15 +19,24 @@ type Server struct {
 	port    string
 	router  *chi.Mux
 	storage database.Storage
-	cache   cache.Cache
+	cache   cache.Cache[string, *model.Order]
+	loader  *cache.Loader[string, *model.Order] // Чтение заказов через кэш
+	ingest  *ingest.Service                     // Прием заказов, общий с Kafka-консюмером
+	opts    IngestOptions
 }
 
 // NewServer создает и настраивает новый экземпляр сервера.
-func NewServer(port string, storage database.Storage, cache cache.Cache) *Server {
+// loader должен читать через тот же cache, которым управляют служебные эндпоинты.
+func NewServer(port string, storage database.Storage, cache cache.Cache[string, *model.Order], loader *cache.Loader[string, *model.Order],
+	service *ingest.Service, opts IngestOptions,
+) *Server {
 	server := &Server{
 		port:    port,
 		storage: storage,
 		cache:   cache,
+		loader:  loader,
+		ingest:  service,
+		opts:    opts,
 	}
 	server.router = server.setupRouter()
 	return server
@@ -48,8 +59,23 @@ func (s *Server) setupRouter() *chi.Mux {
 	router.Use(otelhttp.NewMiddleware("l0-http-server"))
 
 	// Обработчик API
-	orderHandler := NewOrderHandler(s.storage, s.cache)
+	orderHandler := NewOrderHandler(s.storage, s.loader)
 	router.Get("/api/order/{orderUID}", orderHandler.GetByUID)
+	router.Get("/api/orders", orderHandler.List)
+	router.Get("/api/order/{orderUID}/history", orderHandler.History)
+	router.Post("/api/order/{orderUID}/status", orderHandler.UpdateStatus)
+
+	// Прием заказов для партнеров, которые не публикуют их в Kafka
+	ingestHandler := NewIngestHandler(s.ingest, s.storage, s.opts)
+	router.Post("/api/orders", ingestHandler.Create)
+	router.Post("/api/orders:batch", ingestHandler.CreateBatch)
+	router.Get("/api/schema/validation-errors", ingestHandler.ErrorSchema)
+
+	// Служебные эндпоинты для управления кэшем
+	adminHandler := NewAdminHandler(s.cache, s.loader)
+	router.Delete("/api/admin/cache/{orderUID}", adminHandler.DeleteCacheEntry)
+	router.Post("/api/admin/cache/purge", adminHandler.PurgeCache)
+	router.Get("/api/admin/validation/rules", adminHandler.ValidationRules)
 
 	// Эндпоинт для сбора метрик Prometheus
 	router.Handle("/metrics", promhttp.Handler())
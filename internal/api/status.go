@@ -0,0 +1,116 @@
+package api
+
+import (
+	"L0_project/internal/database"
+	"L0_project/internal/metrics"
+	"L0_project/internal/model"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// statusRequest - тело запроса POST /api/order/{orderUID}/status.
+type statusRequest struct {
+	Status model.OrderStatus `json:"status"`
+	// ExpectedVersion - версия статуса, которую видел клиент (поле status_version
+	// заказа или version истории). Если не указана, версия не проверяется.
+	ExpectedVersion int    `json:"expected_version,omitempty"`
+	Reason          string `json:"reason,omitempty"`
+}
+
+// History возвращает текущий статус заказа и историю его переходов.
+func (h *OrderHandler) History(w http.ResponseWriter, r *http.Request) {
+	// Метрики и трассировка
+	const handlerName = "History"
+	timer := prometheus.NewTimer(metrics.HttpRequestDuration.WithLabelValues(handlerName))
+	defer timer.ObserveDuration()
+
+	orderUID := chi.URLParam(r, "orderUID")
+	if orderUID == "" {
+		respondWithError(w, http.StatusBadRequest, "UID заказа не указан", handlerName)
+		return
+	}
+
+	history, err := h.storage.GetStatusHistory(r.Context(), orderUID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		respondWithError(w, http.StatusNotFound, "Заказ не найден", handlerName)
+		return
+	case err != nil && database.IsTransient(err):
+		log.Printf("БД временно недоступна при получении истории статусов %s: %v", orderUID, err)
+		respondWithError(w, http.StatusServiceUnavailable, "Сервис временно недоступен, повторите запрос позже", handlerName)
+		return
+	case err != nil:
+		log.Printf("Ошибка получения истории статусов из БД: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Внутренняя ошибка сервера", handlerName)
+		return
+	}
+
+	metrics.HttpRequestsTotal.WithLabelValues(handlerName, "200").Inc()
+	respondWithJSON(w, http.StatusOK, history)
+}
+
+// UpdateStatus переводит заказ в новый статус и удаляет его из кэша.
+// Неизвестный статус - 400, отсутствующий заказ - 404, устаревшая версия - 409,
+// переход, запрещенный жизненным циклом заказа, - 422.
+func (h *OrderHandler) UpdateStatus(w http.ResponseWriter, r *http.Request) {
+	// Метрики и трассировка
+	const handlerName = "UpdateStatus"
+	timer := prometheus.NewTimer(metrics.HttpRequestDuration.WithLabelValues(handlerName))
+	defer timer.ObserveDuration()
+
+	orderUID := chi.URLParam(r, "orderUID")
+	if orderUID == "" {
+		respondWithError(w, http.StatusBadRequest, "UID заказа не указан", handlerName)
+		return
+	}
+
+	var request statusRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Некорректное тело запроса", handlerName)
+		return
+	}
+	if !request.Status.Valid() {
+		respondWithError(w, http.StatusBadRequest, "Неизвестный статус заказа: "+string(request.Status), handlerName)
+		return
+	}
+
+	change, err := h.storage.TransitionStatus(r.Context(), database.StatusTransition{
+		OrderUID:        orderUID,
+		To:              request.Status,
+		ExpectedVersion: request.ExpectedVersion,
+		Reason:          request.Reason,
+	})
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		respondWithError(w, http.StatusNotFound, "Заказ не найден", handlerName)
+		return
+	case errors.Is(err, database.ErrStatusVersionConflict):
+		respondWithError(w, http.StatusConflict, err.Error(), handlerName)
+		return
+	case errors.Is(err, model.ErrIllegalTransition):
+		respondWithError(w, http.StatusUnprocessableEntity, err.Error(), handlerName)
+		return
+	case err != nil && database.IsTransient(err):
+		log.Printf("БД временно недоступна при смене статуса заказа %s: %v", orderUID, err)
+		respondWithError(w, http.StatusServiceUnavailable, "Сервис временно недоступен, повторите запрос позже", handlerName)
+		return
+	case err != nil:
+		log.Printf("Ошибка смены статуса заказа %s: %v", orderUID, err)
+		respondWithError(w, http.StatusInternalServerError, "Внутренняя ошибка сервера", handlerName)
+		return
+	}
+
+	// Закэшированная копия заказа содержит прежний статус
+	h.loader.Invalidate(r.Context(), orderUID)
+	metrics.OrderStatusTransitions.WithLabelValues(string(change.From), string(change.To)).Inc()
+	log.Printf("Статус заказа %s: %s -> %s (версия %d)", orderUID, change.From, change.To, change.Version)
+
+	metrics.HttpRequestsTotal.WithLabelValues(handlerName, "200").Inc()
+	respondWithJSON(w, http.StatusOK, change)
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"L0_project/internal/database"
+	"L0_project/internal/model"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+// createStatusRequest - хелпер для создания запроса смены статуса
+func createStatusRequest(uid, body string) *http.Request {
+	req := httptest.NewRequest("POST", "/api/order/"+uid+"/status", strings.NewReader(body))
+	chiCtx := chi.NewRouteContext()
+	chiCtx.URLParams.Add("orderUID", uid)
+	return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, chiCtx))
+}
+
+func TestOrderHandler_UpdateStatus_Success(t *testing.T) {
+	ctrl, handler, mockCache, mockStorage := setupHandlerAndMocks(t)
+	defer ctrl.Finish()
+
+	uid := "test-uid-123"
+	change := &model.StatusChange{OrderUID: uid, From: model.StatusCreated, To: model.StatusPaid, Version: 2, ChangedAt: time.Now()}
+
+	mockStorage.EXPECT().TransitionStatus(gomock.Any(), database.StatusTransition{
+		OrderUID: uid, To: model.StatusPaid, ExpectedVersion: 1, Reason: "оплачен",
+	}).Return(change, nil)
+	// Закэшированная копия с прежним статусом удаляется
+	mockCache.EXPECT().Delete(gomock.Any(), uid).Return(true)
+
+	rr := httptest.NewRecorder()
+	handler.UpdateStatus(rr, createStatusRequest(uid, `{"status":"paid","expected_version":1,"reason":"оплачен"}`))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var got model.StatusChange
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+	assert.Equal(t, model.StatusPaid, got.To)
+	assert.Equal(t, 2, got.Version)
+}
+
+func TestOrderHandler_UpdateStatus_Errors(t *testing.T) {
+	testCases := []struct {
+		name       string
+		body       string
+		storageErr error
+		wantStatus int
+	}{
+		{name: "некорректный JSON", body: `{`, wantStatus: http.StatusBadRequest},
+		{name: "неизвестный статус", body: `{"status":"lost"}`, wantStatus: http.StatusBadRequest},
+		{name: "заказ не найден", body: `{"status":"paid"}`, storageErr: fmt.Errorf("не удалось получить статус заказа: %w", sql.ErrNoRows), wantStatus: http.StatusNotFound},
+		{name: "устаревшая версия", body: `{"status":"paid","expected_version":1}`, storageErr: fmt.Errorf("%w: ожидалась версия 1, текущая 2", database.ErrStatusVersionConflict), wantStatus: http.StatusConflict},
+		{name: "запрещенный переход", body: `{"status":"delivered"}`, storageErr: fmt.Errorf("%w: created -> delivered", model.ErrIllegalTransition), wantStatus: http.StatusUnprocessableEntity},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl, handler, mockCache, mockStorage := setupHandlerAndMocks(t)
+			defer ctrl.Finish()
+
+			if tc.storageErr != nil {
+				mockStorage.EXPECT().TransitionStatus(gomock.Any(), gomock.Any()).Return(nil, tc.storageErr)
+			}
+			// Кэш не трогаем, если статус не изменился
+			mockCache.EXPECT().Delete(gomock.Any(), gomock.Any()).Times(0)
+
+			rr := httptest.NewRecorder()
+			handler.UpdateStatus(rr, createStatusRequest("test-uid-123", tc.body))
+
+			assert.Equal(t, tc.wantStatus, rr.Code)
+		})
+	}
+}
+
+func TestOrderHandler_History(t *testing.T) {
+	ctrl, handler, _, mockStorage := setupHandlerAndMocks(t)
+	defer ctrl.Finish()
+
+	uid := "test-uid-123"
+	mockStorage.EXPECT().GetStatusHistory(gomock.Any(), uid).Return(&model.StatusHistory{
+		OrderUID: uid,
+		Status:   model.StatusPaid,
+		Version:  2,
+		Changes:  []model.StatusChange{{OrderUID: uid, From: model.StatusCreated, To: model.StatusPaid, Version: 2}},
+	}, nil)
+
+	rr := httptest.NewRecorder()
+	handler.History(rr, createTestRequest(t, uid))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var got model.StatusHistory
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+	assert.Equal(t, model.StatusPaid, got.Status)
+	assert.Len(t, got.Changes, 1)
+}
+
+func TestOrderHandler_History_NotFound(t *testing.T) {
+	ctrl, handler, _, mockStorage := setupHandlerAndMocks(t)
+	defer ctrl.Finish()
+
+	mockStorage.EXPECT().GetStatusHistory(gomock.Any(), "absent").Return(nil, fmt.Errorf("не удалось получить статус заказа: %w", sql.ErrNoRows))
+
+	rr := httptest.NewRecorder()
+	handler.History(rr, createTestRequest(t, "absent"))
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
@@ -0,0 +1,117 @@
+package cache
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// LegacyCache - прежний нетипизированный интерфейс кэша (ключ string, значение
+// interface{}). Оставлен на время миграции; новый код использует Cache[K, V]
+// с конкретным типом значения.
+type LegacyCache = Cache[string, interface{}]
+
+// Typed представляет нетипизированный кэш как типизированный. Значение другого
+// типа, записанное в кэш в обход адаптера, считается промахом.
+func Typed[V any](c LegacyCache) Cache[string, V] {
+	return &typedAdapter[V]{legacy: c}
+}
+
+// Untyped представляет типизированный кэш как LegacyCache для кода, который еще
+// не переведен на Cache[K, V]. Запись значения другого типа игнорируется с
+// предупреждением в логе.
+func Untyped[V any](c Cache[string, V]) LegacyCache {
+	return &untypedAdapter[V]{typed: c}
+}
+
+// typedAdapter реализует Cache[string, V] поверх LegacyCache.
+type typedAdapter[V any] struct {
+	legacy LegacyCache
+}
+
+func (a *typedAdapter[V]) Set(ctx context.Context, key string, value V) {
+	a.legacy.Set(ctx, key, value)
+}
+
+func (a *typedAdapter[V]) SetWithTTL(ctx context.Context, key string, value V, ttl time.Duration) {
+	a.legacy.SetWithTTL(ctx, key, value, ttl)
+}
+
+func (a *typedAdapter[V]) Get(ctx context.Context, key string) (V, bool) {
+	value, found := a.legacy.Get(ctx, key)
+	typed, ok := value.(V)
+	if !found || !ok {
+		var zero V
+		return zero, false
+	}
+	return typed, true
+}
+
+func (a *typedAdapter[V]) Peek(key string) (Entry[V], bool) {
+	entry, found := a.legacy.Peek(key)
+	typed, ok := entry.Value.(V)
+	if !found || !ok {
+		return Entry[V]{}, false
+	}
+	return Entry[V]{Value: typed, ExpiresAt: entry.ExpiresAt, StoredAt: entry.StoredAt}, true
+}
+
+func (a *typedAdapter[V]) Delete(ctx context.Context, key string) bool {
+	return a.legacy.Delete(ctx, key)
+}
+
+func (a *typedAdapter[V]) Purge(ctx context.Context) { a.legacy.Purge(ctx) }
+func (a *typedAdapter[V]) Len() int                  { return a.legacy.Len() }
+func (a *typedAdapter[V]) Keys() []string            { return a.legacy.Keys() }
+func (a *typedAdapter[V]) Close()                    { a.legacy.Close() }
+
+// untypedAdapter реализует LegacyCache поверх Cache[string, V].
+type untypedAdapter[V any] struct {
+	typed Cache[string, V]
+}
+
+func (a *untypedAdapter[V]) Set(ctx context.Context, key string, value interface{}) {
+	if typed, ok := a.assert(key, value); ok {
+		a.typed.Set(ctx, key, typed)
+	}
+}
+
+func (a *untypedAdapter[V]) SetWithTTL(ctx context.Context, key string, value interface{}, ttl time.Duration) {
+	if typed, ok := a.assert(key, value); ok {
+		a.typed.SetWithTTL(ctx, key, typed, ttl)
+	}
+}
+
+// assert приводит значение к типу кэша; значение другого типа логируется.
+func (a *untypedAdapter[V]) assert(key string, value interface{}) (V, bool) {
+	typed, ok := value.(V)
+	if !ok {
+		log.Printf("Кэш: значение ключа %s имеет тип %T, ожидался %T; запись пропущена", key, value, typed)
+	}
+	return typed, ok
+}
+
+func (a *untypedAdapter[V]) Get(ctx context.Context, key string) (interface{}, bool) {
+	value, found := a.typed.Get(ctx, key)
+	if !found {
+		return nil, false
+	}
+	return value, true
+}
+
+func (a *untypedAdapter[V]) Peek(key string) (Entry[interface{}], bool) {
+	entry, found := a.typed.Peek(key)
+	if !found {
+		return Entry[interface{}]{}, false
+	}
+	return Entry[interface{}]{Value: entry.Value, ExpiresAt: entry.ExpiresAt, StoredAt: entry.StoredAt}, true
+}
+
+func (a *untypedAdapter[V]) Delete(ctx context.Context, key string) bool {
+	return a.typed.Delete(ctx, key)
+}
+
+func (a *untypedAdapter[V]) Purge(ctx context.Context) { a.typed.Purge(ctx) }
+func (a *untypedAdapter[V]) Len() int                  { return a.typed.Len() }
+func (a *untypedAdapter[V]) Keys() []string            { return a.typed.Keys() }
+func (a *untypedAdapter[V]) Close()                    { a.typed.Close() }
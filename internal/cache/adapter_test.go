@@ -0,0 +1,66 @@
+package cache
+
+import (
+	"L0_project/internal/model"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTyped_WrongTypeIsMiss(t *testing.T) {
+	legacy := NewLRUCache[string, interface{}](10)
+	typed := Typed[*model.Order](legacy)
+	ctx := context.Background()
+
+	typed.Set(ctx, "order", &model.Order{OrderUID: "order"})
+	legacy.Set(ctx, "broken", "не заказ")
+
+	order, found := typed.Get(ctx, "order")
+	assert.True(t, found)
+	assert.Equal(t, "order", order.OrderUID)
+
+	order, found = typed.Get(ctx, "broken")
+	assert.False(t, found, "значение другого типа должно считаться промахом")
+	assert.Nil(t, order)
+	assert.Equal(t, 2, typed.Len())
+}
+
+func TestUntyped_SkipsWrongType(t *testing.T) {
+	typed := NewLRUCache[string, *model.Order](10)
+	legacy := Untyped(typed)
+	ctx := context.Background()
+
+	legacy.Set(ctx, "order", &model.Order{OrderUID: "order"})
+	legacy.Set(ctx, "broken", "не заказ")
+
+	value, found := legacy.Get(ctx, "order")
+	assert.True(t, found)
+	assert.IsType(t, &model.Order{}, value)
+
+	_, found = legacy.Get(ctx, "broken")
+	assert.False(t, found)
+	assert.Equal(t, []string{"order"}, typed.Keys())
+
+	assert.True(t, legacy.Delete(ctx, "order"))
+	assert.Equal(t, 0, typed.Len())
+}
+
+func TestUntyped_TTL(t *testing.T) {
+	typed := NewLRUCache[string, *model.Order](10, WithTTL(time.Hour))
+	legacy := Untyped(typed)
+	ctx := context.Background()
+
+	// Set сохраняет срок жизни по умолчанию, SetWithTTL с ttl <= 0 - бессрочно, как в Cache
+	legacy.Set(ctx, "default", &model.Order{OrderUID: "default"})
+	legacy.SetWithTTL(ctx, "forever", &model.Order{OrderUID: "forever"}, 0)
+
+	entry, found := legacy.Peek("default")
+	assert.True(t, found)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), entry.ExpiresAt, time.Minute)
+
+	entry, found = legacy.Peek("forever")
+	assert.True(t, found)
+	assert.True(t, entry.ExpiresAt.IsZero(), "ttl 0 означает запись без срока жизни")
+}
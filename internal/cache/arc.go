@@ -0,0 +1,191 @@
+package cache
+
+import "container/list"
+
+// NewARCCache создает ARC-кэш (Adaptive Replacement Cache) с заданной емкостью.
+func NewARCCache[K comparable, V any](capacity int, opts ...Option) Cache[K, V] {
+	return newCache(capacity, newARCPolicy[K, V], opts...)
+}
+
+// Сегменты ARC для резидентных записей.
+const (
+	arcRecent   uint8 = iota + 1 // T1: записи, к которым обращались один раз
+	arcFrequent                  // T2: записи, к которым обращались повторно
+)
+
+// arcPolicy реализует ARC (Megiddo, Modha). Резидентные записи делятся на
+// недавние (T1) и частые (T2); для вытесненных хранятся только ключи (B1, B2).
+// Повторное обращение к ключу из B1 увеличивает целевой размер T1, из B2 -
+// уменьшает, так что кэш сам подстраивается под соотношение свежести и частоты.
+// Поток однократных записей проходит через T1 и не вытесняет частые записи из T2.
+type arcPolicy[K comparable, V any] struct {
+	capacity int
+	target   int // Целевой размер T1 (p)
+
+	recent, frequent *list.List // T1, T2: *cacheItem[K, V], от свежих к старым
+	recentGhosts     *list.List // B1: *arcGhost, от свежих к старым
+	frequentGhosts   *list.List // B2: *arcGhost, от свежих к старым
+	ghosts           map[K]*list.Element
+}
+
+// arcGhost - ключ вытесненной записи.
+type arcGhost[K comparable] struct {
+	key      K
+	frequent bool // Из B2 (иначе из B1)
+}
+
+func newARCPolicy[K comparable, V any](capacity int) policy[K, V] {
+	return &arcPolicy[K, V]{
+		capacity:       capacity,
+		recent:         list.New(),
+		frequent:       list.New(),
+		recentGhosts:   list.New(),
+		frequentGhosts: list.New(),
+		ghosts:         make(map[K]*list.Element),
+	}
+}
+
+func (p *arcPolicy[K, V]) name() string { return PolicyARC }
+
+func (p *arcPolicy[K, V]) add(item *cacheItem[K, V]) []*cacheItem[K, V] {
+	var evicted []*cacheItem[K, V]
+
+	if element, ok := p.ghosts[item.key]; ok {
+		// Ключ недавно вытеснен: адаптируем целевой размер T1 и сразу считаем запись частой
+		ghost := element.Value.(*arcGhost[K])
+		if ghost.frequent {
+			p.target = max(0, p.target-max(1, p.recentGhosts.Len()/p.frequentGhosts.Len()))
+		} else {
+			p.target = min(p.capacity, p.target+max(1, p.frequentGhosts.Len()/p.recentGhosts.Len()))
+		}
+		p.dropGhost(element)
+
+		if p.resident() >= p.capacity {
+			evicted = append(evicted, p.replace(ghost.frequent))
+		}
+		p.push(item, arcFrequent)
+		return evicted
+	}
+
+	if p.recent.Len()+p.recentGhosts.Len() >= p.capacity {
+		if p.recent.Len() < p.capacity {
+			p.dropGhost(p.recentGhosts.Back())
+			if p.resident() >= p.capacity {
+				evicted = append(evicted, p.replace(false))
+			}
+		} else {
+			// T1 занимает весь кэш: вытесняем без сохранения ключа
+			evicted = append(evicted, p.recent.Remove(p.recent.Back()).(*cacheItem[K, V]))
+		}
+	} else if total := p.resident() + p.recentGhosts.Len() + p.frequentGhosts.Len(); total >= p.capacity {
+		if total >= 2*p.capacity {
+			p.dropGhost(p.frequentGhosts.Back())
+		}
+		if p.resident() >= p.capacity {
+			evicted = append(evicted, p.replace(false))
+		}
+	}
+
+	p.push(item, arcRecent)
+	return evicted
+}
+
+// access переносит запись в начало T2.
+func (p *arcPolicy[K, V]) access(item *cacheItem[K, V]) {
+	if item.segment == arcFrequent {
+		p.frequent.MoveToFront(item.element)
+		return
+	}
+	p.recent.Remove(item.element)
+	p.push(item, arcFrequent)
+}
+
+func (p *arcPolicy[K, V]) miss(K) {}
+
+func (p *arcPolicy[K, V]) remove(item *cacheItem[K, V]) {
+	p.list(item.segment).Remove(item.element)
+}
+
+func (p *arcPolicy[K, V]) evict() *cacheItem[K, V] {
+	if p.resident() == 0 {
+		return nil
+	}
+	return p.replace(false)
+}
+
+// keys возвращает сначала частые записи (T2), затем недавние (T1).
+func (p *arcPolicy[K, V]) keys() []K {
+	keys := make([]K, 0, p.resident())
+	keys = listKeys[K, V](keys, p.frequent)
+	return listKeys[K, V](keys, p.recent)
+}
+
+func (p *arcPolicy[K, V]) reset() {
+	p.target = 0
+	p.recent.Init()
+	p.frequent.Init()
+	p.recentGhosts.Init()
+	p.frequentGhosts.Init()
+	p.ghosts = make(map[K]*list.Element)
+}
+
+// replace вытесняет самую старую запись из T1 или T2 в зависимости от целевого
+// размера T1 и запоминает ее ключ в соответствующем списке B.
+func (p *arcPolicy[K, V]) replace(frequentGhostHit bool) *cacheItem[K, V] {
+	fromRecent := p.recent.Len() > 0 &&
+		(p.recent.Len() > p.target || (frequentGhostHit && p.recent.Len() == p.target) || p.frequent.Len() == 0)
+
+	source, ghosts := p.frequent, p.frequentGhosts
+	if fromRecent {
+		source, ghosts = p.recent, p.recentGhosts
+	}
+
+	item := source.Remove(source.Back()).(*cacheItem[K, V])
+	p.ghosts[item.key] = ghosts.PushFront(&arcGhost[K]{key: item.key, frequent: !fromRecent})
+	p.trimGhosts()
+	return item
+}
+
+// trimGhosts ограничивает число ключей в B1 и B2 емкостью кэша.
+// При обычной работе ARC это выполняется само; проверка страхует от
+// явных удалений и истечений, которые ARC не учитывает.
+func (p *arcPolicy[K, V]) trimGhosts() {
+	for p.recentGhosts.Len()+p.frequentGhosts.Len() > p.capacity {
+		if p.recentGhosts.Len() >= p.frequentGhosts.Len() {
+			p.dropGhost(p.recentGhosts.Back())
+		} else {
+			p.dropGhost(p.frequentGhosts.Back())
+		}
+	}
+}
+
+// dropGhost забывает ключ вытесненной записи.
+func (p *arcPolicy[K, V]) dropGhost(element *list.Element) {
+	if element == nil {
+		return
+	}
+	ghost := element.Value.(*arcGhost[K])
+	if ghost.frequent {
+		p.frequentGhosts.Remove(element)
+	} else {
+		p.recentGhosts.Remove(element)
+	}
+	delete(p.ghosts, ghost.key)
+}
+
+// push добавляет запись в начало сегмента.
+func (p *arcPolicy[K, V]) push(item *cacheItem[K, V], segment uint8) {
+	item.segment = segment
+	item.element = p.list(segment).PushFront(item)
+}
+
+func (p *arcPolicy[K, V]) list(segment uint8) *list.List {
+	if segment == arcFrequent {
+		return p.frequent
+	}
+	return p.recent
+}
+
+func (p *arcPolicy[K, V]) resident() int {
+	return p.recent.Len() + p.frequent.Len()
+}
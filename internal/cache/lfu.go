@@ -0,0 +1,105 @@
+package cache
+
+import "container/list"
+
+// NewLFUCache создает LFU-кэш (Least Frequently Used) с заданной емкостью.
+func NewLFUCache[K comparable, V any](capacity int, opts ...Option) Cache[K, V] {
+	return newCache(capacity, newLFUPolicy[K, V], opts...)
+}
+
+// lfuPolicy реализует LFU со списком частот: вытесняется запись с наименьшим
+// числом обращений, среди равных - давно не использованная. Все операции O(1).
+type lfuPolicy[K comparable, V any] struct {
+	capacity int
+	freqs    *list.List // Узлы *freqNode по возрастанию частоты
+	size     int
+}
+
+// freqNode - группа записей с одинаковым числом обращений.
+type freqNode struct {
+	count int
+	items *list.List // От самых свежих к самым старым
+}
+
+func newLFUPolicy[K comparable, V any](capacity int) policy[K, V] {
+	return &lfuPolicy[K, V]{capacity: capacity, freqs: list.New()}
+}
+
+func (p *lfuPolicy[K, V]) name() string { return PolicyLFU }
+
+func (p *lfuPolicy[K, V]) add(item *cacheItem[K, V]) []*cacheItem[K, V] {
+	var evicted []*cacheItem[K, V]
+	if p.size >= p.capacity {
+		evicted = append(evicted, p.evict())
+	}
+
+	first := p.freqs.Front()
+	if first == nil || first.Value.(*freqNode).count != 1 {
+		first = p.freqs.PushFront(&freqNode{count: 1, items: list.New()})
+	}
+	p.attach(item, first)
+	p.size++
+	return evicted
+}
+
+// access переносит запись в группу со следующей частотой.
+func (p *lfuPolicy[K, V]) access(item *cacheItem[K, V]) {
+	current := item.freq
+	count := current.Value.(*freqNode).count
+
+	next := current.Next()
+	if next == nil || next.Value.(*freqNode).count != count+1 {
+		next = p.freqs.InsertAfter(&freqNode{count: count + 1, items: list.New()}, current)
+	}
+
+	p.detach(item)
+	p.attach(item, next)
+}
+
+func (p *lfuPolicy[K, V]) miss(K) {}
+
+func (p *lfuPolicy[K, V]) remove(item *cacheItem[K, V]) {
+	p.detach(item)
+	p.size--
+}
+
+// evict удаляет самую старую запись из группы с наименьшей частотой.
+func (p *lfuPolicy[K, V]) evict() *cacheItem[K, V] {
+	first := p.freqs.Front()
+	if first == nil {
+		return nil
+	}
+	item := first.Value.(*freqNode).items.Back().Value.(*cacheItem[K, V])
+	p.remove(item)
+	return item
+}
+
+// keys возвращает ключи от самых частых к самым редким.
+func (p *lfuPolicy[K, V]) keys() []K {
+	keys := make([]K, 0, p.size)
+	for node := p.freqs.Back(); node != nil; node = node.Prev() {
+		keys = listKeys[K, V](keys, node.Value.(*freqNode).items)
+	}
+	return keys
+}
+
+func (p *lfuPolicy[K, V]) reset() {
+	p.freqs.Init()
+	p.size = 0
+}
+
+// attach добавляет запись в начало группы частоты.
+func (p *lfuPolicy[K, V]) attach(item *cacheItem[K, V], node *list.Element) {
+	item.freq = node
+	item.element = node.Value.(*freqNode).items.PushFront(item)
+}
+
+// detach убирает запись из ее группы; пустая группа удаляется.
+func (p *lfuPolicy[K, V]) detach(item *cacheItem[K, V]) {
+	node := item.freq.Value.(*freqNode)
+	node.items.Remove(item.element)
+	if node.items.Len() == 0 {
+		p.freqs.Remove(item.freq)
+	}
+	item.freq, item.element = nil, nil
+}
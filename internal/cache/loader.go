@@ -0,0 +1,337 @@
+package cache
+
+import (
+	"L0_project/internal/metrics"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrNotFound возвращается LoadFunc, если значения нет в источнике.
+// Loader возвращает ее же, в том числе для ключей из отрицательного кэша.
+var ErrNotFound = errors.New("значение не найдено в источнике")
+
+// ErrLoadPanic возвращается GetOrLoad, если LoadFunc завершилась паникой.
+var ErrLoadPanic = errors.New("паника при загрузке значения")
+
+// LoadFunc загружает значение из источника при промахе кэша.
+type LoadFunc[V any] func(ctx context.Context) (V, error)
+
+// Status сообщает, откуда получен ответ GetOrLoad.
+type Status int
+
+const (
+	// StatusMiss - значение загружено из источника.
+	StatusMiss Status = iota
+	// StatusHit - ответ из кэша (в том числе ErrNotFound из отрицательного кэша).
+	StatusHit
+	// StatusStale - значение из кэша старше мягкого срока жизни; запущено его
+	// фоновое обновление.
+	StatusStale
+)
+
+// String возвращает название статуса для заголовков и логов: HIT, STALE или MISS.
+func (s Status) String() string {
+	switch s {
+	case StatusHit:
+		return "HIT"
+	case StatusStale:
+		return "STALE"
+	default:
+		return "MISS"
+	}
+}
+
+// LoaderOption настраивает Loader при создании.
+type LoaderOption[K comparable, V any] func(*Loader[K, V])
+
+// WithNegativeCache включает отрицательное кэширование: ключи, для которых
+// LoadFunc вернула ErrNotFound, запоминаются на ttl (не более capacity ключей),
+// и повторные запросы не доходят до источника.
+//
+// Отрицательные записи хранятся отдельно от основного кэша и проверяются после
+// него, поэтому значение, сохраненное в основной кэш (например, консьюмером),
+// сразу перекрывает отрицательную запись и удаляет ее при первом обращении.
+func WithNegativeCache[K comparable, V any](capacity int, ttl time.Duration) LoaderOption[K, V] {
+	return func(l *Loader[K, V]) {
+		if capacity > 0 && ttl > 0 {
+			l.negative = newLRUCache[K, struct{}](capacity, WithTTL(ttl), withoutMetrics())
+		}
+	}
+}
+
+// WithStaleWhileRevalidate задает мягкий срок жизни значений (stale-while-revalidate).
+// Значение старше softTTL по-прежнему отдается из кэша, но запрос запускает его
+// фоновую перезагрузку (одну на ключ, как и при промахе). Жесткий срок жизни -
+// это срок жизни записей самого кэша: после него значение не отдается, и запрос
+// ждет загрузки из источника.
+//
+// Возраст значения отсчитывается от записи в кэш, поэтому softTTL имеет смысл
+// только меньше срока жизни записей кэша. Если перезагрузка не удалась, значение
+// остается в кэше и отдается как устаревшее до истечения жесткого срока.
+func WithStaleWhileRevalidate[K comparable, V any](softTTL time.Duration) LoaderOption[K, V] {
+	return func(l *Loader[K, V]) {
+		l.softTTL = softTTL
+	}
+}
+
+// Loader реализует чтение через кэш (read-through): при промахе значение
+// загружается через LoadFunc и сохраняется в кэш. Одновременные промахи по
+// одному ключу объединяются: источник запрашивается один раз, результат
+// получают все ожидающие.
+//
+// Если ключ инвалидирован (Invalidate, Forget, Purge или Refresh) во время загрузки, ее
+// результат отдается уже ожидающим, но в кэш не сохраняется: он мог быть
+// прочитан до изменения. Следующий промах запускает новую загрузку.
+type Loader[K comparable, V any] struct {
+	cache    Cache[K, V]
+	negative *localCache[K, struct{}] // Отрицательный кэш (nil - выключен)
+	softTTL  time.Duration            // Мягкий срок жизни значений (0 - выключен)
+	tracer   trace.Tracer
+	now      func() time.Time
+
+	mu      sync.Mutex
+	flights map[K]*flight[V] // Загрузки в процессе
+}
+
+// flight - одна загрузка значения, результат которой ждут все промахи по ключу.
+type flight[V any] struct {
+	done  chan struct{} // Закрывается по завершении загрузки
+	value V
+	err   error
+
+	mu          sync.Mutex // Упорядочивает сохранение результата и инвалидацию ключа
+	invalidated bool       // Ключ инвалидирован во время загрузки: результат не сохраняется
+}
+
+// NewLoader создает Loader поверх кэша.
+func NewLoader[K comparable, V any](cache Cache[K, V], opts ...LoaderOption[K, V]) *Loader[K, V] {
+	l := &Loader[K, V]{
+		cache:   cache,
+		tracer:  otel.Tracer("cache-loader"),
+		now:     time.Now,
+		flights: make(map[K]*flight[V]),
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// GetOrLoad возвращает значение по ключу из кэша, а при промахе - загружает его
+// через load. Второе значение сообщает, откуда получен ответ (см. Status).
+//
+// Загрузка выполняется с контекстом первого запроса, но без его отмены: если
+// первый клиент отключится, остальные все равно получат результат. Каждый
+// вызов при этом перестает ждать при отмене собственного контекста.
+func (l *Loader[K, V]) GetOrLoad(ctx context.Context, key K, load LoadFunc[V]) (V, Status, error) {
+	// Создаем span для трассировки
+	ctx, span := l.tracer.Start(ctx, "Cache.GetOrLoad")
+	defer span.End()
+
+	var zero V
+	if value, found := l.cache.Get(ctx, key); found {
+		metrics.CacheHits.Inc()
+		if l.negative != nil {
+			// Значение появилось в основном кэше - отрицательная запись устарела
+			l.negative.Delete(ctx, key)
+		}
+		if l.stale(key) {
+			metrics.CacheStaleHits.Inc()
+			l.revalidate(ctx, key, load)
+			return value, StatusStale, nil
+		}
+		return value, StatusHit, nil
+	}
+
+	if l.negative != nil {
+		if _, found := l.negative.Get(ctx, key); found {
+			metrics.CacheNegativeHits.Inc()
+			return zero, StatusHit, ErrNotFound
+		}
+	}
+	metrics.CacheMisses.Inc()
+
+	f, leader := l.join(key)
+	if leader {
+		go l.run(context.WithoutCancel(ctx), key, f, load)
+	} else {
+		metrics.CacheCoalescedRequests.Inc()
+	}
+
+	select {
+	case <-f.done:
+		return f.value, StatusMiss, f.err
+	case <-ctx.Done():
+		return zero, StatusMiss, ctx.Err()
+	}
+}
+
+// stale сообщает, что значение в кэше старше мягкого срока жизни.
+func (l *Loader[K, V]) stale(key K) bool {
+	if l.softTTL <= 0 {
+		return false
+	}
+	entry, found := l.cache.Peek(key)
+	return found && !entry.StoredAt.IsZero() && l.now().Sub(entry.StoredAt) >= l.softTTL
+}
+
+// revalidate запускает фоновую перезагрузку устаревшего значения, если она еще
+// не идет. Ошибка перезагрузки только логируется: значение остается в кэше.
+func (l *Loader[K, V]) revalidate(ctx context.Context, key K, load LoadFunc[V]) {
+	f, leader := l.join(key)
+	if !leader {
+		return
+	}
+	go func() {
+		l.run(context.WithoutCancel(ctx), key, f, load)
+		if f.err != nil {
+			log.Printf("Фоновое обновление устаревшего значения %v не удалось: %v", key, f.err)
+		}
+	}()
+}
+
+// Forget удаляет отрицательную запись ключа, например когда значение появилось
+// в источнике, а записать его в кэш некому (изменение сделал другой экземпляр).
+// Результат идущей загрузки ключа в кэш не сохраняется. Если значение удаляется
+// из кэша отдельно, Forget вызывается до удаления.
+func (l *Loader[K, V]) Forget(ctx context.Context, key K) {
+	l.invalidateFlight(key)
+	if l.negative != nil {
+		l.negative.Delete(ctx, key)
+	}
+}
+
+// Purge удаляет все отрицательные записи и инвалидирует все идущие загрузки,
+// как Forget для каждого ключа. Если кэш очищается отдельно, Purge вызывается
+// до его очистки.
+func (l *Loader[K, V]) Purge(ctx context.Context) {
+	l.mu.Lock()
+	flights := l.flights
+	l.flights = make(map[K]*flight[V])
+	l.mu.Unlock()
+
+	for _, f := range flights {
+		f.invalidate()
+	}
+	if l.negative != nil {
+		l.negative.Purge(ctx)
+	}
+}
+
+// Invalidate удаляет значение ключа из кэша вместе с отрицательной записью,
+// чтобы следующий запрос загрузил его из источника.
+func (l *Loader[K, V]) Invalidate(ctx context.Context, key K) {
+	l.Forget(ctx, key)
+	l.cache.Delete(ctx, key)
+}
+
+// Refresh загружает значение ключа из источника и сохраняет его в кэш, например
+// после изменения источника этим экземпляром (write-through). Отрицательная
+// запись удаляется, идущая загрузка ключа инвалидируется, а промахи во время
+// Refresh ждут ее результата. Если загрузка не удалась, значение удаляется из
+// кэша, чтобы не отдавать прежнее; если ключ инвалидирован во время Refresh,
+// результат в кэш не сохраняется.
+func (l *Loader[K, V]) Refresh(ctx context.Context, key K, load LoadFunc[V]) error {
+	f := &flight[V]{done: make(chan struct{})}
+	l.mu.Lock()
+	prev, ok := l.flights[key]
+	l.flights[key] = f
+	l.mu.Unlock()
+	if ok {
+		prev.invalidate()
+	}
+	if l.negative != nil {
+		l.negative.Delete(ctx, key)
+	}
+
+	l.run(ctx, key, f, load)
+	if f.err != nil {
+		l.cache.Delete(ctx, key)
+		return f.err
+	}
+	return nil
+}
+
+// invalidateFlight отмечает идущую загрузку ключа инвалидированной и убирает
+// ее из списка, чтобы новые промахи не присоединялись к ней.
+func (l *Loader[K, V]) invalidateFlight(key K) {
+	l.mu.Lock()
+	f, ok := l.flights[key]
+	if ok {
+		delete(l.flights, key)
+	}
+	l.mu.Unlock()
+
+	if ok {
+		f.invalidate()
+	}
+}
+
+// invalidate отмечает загрузку инвалидированной: ее результат не сохраняется.
+func (f *flight[V]) invalidate() {
+	f.mu.Lock()
+	f.invalidated = true
+	f.mu.Unlock()
+}
+
+// join возвращает идущую загрузку ключа или регистрирует новую.
+// Второе значение равно true, если загрузку должен выполнить вызывающий.
+func (l *Loader[K, V]) join(key K) (*flight[V], bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if f, ok := l.flights[key]; ok {
+		return f, false
+	}
+	f := &flight[V]{done: make(chan struct{})}
+	l.flights[key] = f
+	return f, true
+}
+
+// run выполняет загрузку, сохраняет результат в кэш и будит ожидающих.
+// Паника в load возвращается ожидающим как ошибка ErrLoadPanic.
+func (l *Loader[K, V]) run(ctx context.Context, key K, f *flight[V], load LoadFunc[V]) {
+	defer func() {
+		l.mu.Lock()
+		if l.flights[key] == f {
+			delete(l.flights, key)
+		}
+		l.mu.Unlock()
+		close(f.done)
+	}()
+
+	f.value, f.err = safeLoad(ctx, load)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.invalidated {
+		return // Результат мог быть прочитан до изменения
+	}
+	if f.err != nil {
+		if l.negative != nil && errors.Is(f.err, ErrNotFound) {
+			l.negative.Set(ctx, key, struct{}{})
+		}
+		return
+	}
+	l.cache.Set(ctx, key, f.value)
+}
+
+// safeLoad вызывает load и превращает его панику в ошибку.
+func safeLoad[V any](ctx context.Context, load LoadFunc[V]) (value V, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			log.Printf("Паника при загрузке значения: %v\n%s", p, debug.Stack())
+			var zero V
+			value, err = zero, fmt.Errorf("%w: %v", ErrLoadPanic, p)
+		}
+	}()
+	return load(ctx)
+}
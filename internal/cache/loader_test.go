@@ -0,0 +1,389 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// countingCache подсчитывает обращения к кэшу для проверки объединения промахов.
+type countingCache struct {
+	Cache[string, any]
+	gets atomic.Int32
+}
+
+func (c *countingCache) Get(ctx context.Context, key string) (interface{}, bool) {
+	c.gets.Add(1)
+	return c.Cache.Get(ctx, key)
+}
+
+func TestLoader_GetOrLoad_HitAndMiss(t *testing.T) {
+	assertions := assert.New(t)
+	ctx := context.Background()
+	loader := NewLoader(NewLRUCache[string, any](2))
+
+	calls := 0
+	load := func(context.Context) (interface{}, error) {
+		calls++
+		return "value1", nil
+	}
+
+	// Промах: значение загружается и сохраняется в кэш
+	val, status, err := loader.GetOrLoad(ctx, "key1", load)
+	assertions.NoError(err)
+	assertions.Equal(StatusMiss, status)
+	assertions.Equal("value1", val)
+
+	// Попадание: источник не запрашивается
+	val, status, err = loader.GetOrLoad(ctx, "key1", load)
+	assertions.NoError(err)
+	assertions.Equal(StatusHit, status)
+	assertions.Equal("value1", val)
+	assertions.Equal(1, calls)
+}
+
+func TestLoader_GetOrLoad_ErrorNotCached(t *testing.T) {
+	assertions := assert.New(t)
+	ctx := context.Background()
+	lru := NewLRUCache[string, any](2)
+	loader := NewLoader(lru)
+
+	loadErr := errors.New("db down")
+	_, _, err := loader.GetOrLoad(ctx, "key1", func(context.Context) (interface{}, error) {
+		return nil, loadErr
+	})
+	assertions.ErrorIs(err, loadErr)
+	assertions.Equal(0, lru.Len())
+}
+
+func TestLoader_GetOrLoad_CoalescesConcurrentMisses(t *testing.T) {
+	const callers = 10
+	ctx := context.Background()
+	cache := &countingCache{Cache: NewLRUCache[string, any](2)}
+	loader := NewLoader(cache)
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	load := func(context.Context) (interface{}, error) {
+		calls.Add(1)
+		<-release
+		return "value1", nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([]interface{}, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			val, _, err := loader.GetOrLoad(ctx, "key1", load)
+			assert.NoError(t, err)
+			results[i] = val
+		}(i)
+	}
+
+	// Дожидаемся, пока все вызовы получат промах и присоединятся к загрузке
+	assert.Eventually(t, func() bool { return cache.gets.Load() == callers }, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls.Load(), "источник должен быть запрошен один раз")
+	for _, val := range results {
+		assert.Equal(t, "value1", val)
+	}
+}
+
+func TestLoader_GetOrLoad_WaiterCancellation(t *testing.T) {
+	loader := NewLoader(NewLRUCache[string, any](2))
+	release := make(chan struct{})
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Отмена контекста ожидающего не блокирует его до конца загрузки
+	_, _, err := loader.GetOrLoad(ctx, "key1", func(context.Context) (interface{}, error) {
+		<-release
+		return "value1", nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestLoader_GetOrLoad_NegativeCache(t *testing.T) {
+	assertions := assert.New(t)
+	ctx := context.Background()
+	main := NewLRUCache[string, any](2)
+	loader := NewLoader(main, WithNegativeCache[string, any](10, time.Minute))
+
+	calls := 0
+	notFound := func(context.Context) (interface{}, error) {
+		calls++
+		return nil, ErrNotFound
+	}
+
+	_, status, err := loader.GetOrLoad(ctx, "key1", notFound)
+	assertions.ErrorIs(err, ErrNotFound)
+	assertions.Equal(StatusMiss, status)
+
+	// Повторный запрос обслуживается отрицательным кэшем
+	_, status, err = loader.GetOrLoad(ctx, "key1", notFound)
+	assertions.ErrorIs(err, ErrNotFound)
+	assertions.Equal(StatusHit, status)
+	assertions.Equal(1, calls)
+	assertions.Equal(0, main.Len(), "отрицательные записи не попадают в основной кэш")
+
+	// Значение появилось в основном кэше - оно важнее отрицательной записи
+	main.Set(ctx, "key1", "value1")
+	val, status, err := loader.GetOrLoad(ctx, "key1", notFound)
+	assertions.NoError(err)
+	assertions.Equal(StatusHit, status)
+	assertions.Equal("value1", val)
+	assertions.Equal(0, loader.negative.Len())
+}
+
+func TestLoader_Forget(t *testing.T) {
+	ctx := context.Background()
+	loader := NewLoader(NewLRUCache[string, any](2), WithNegativeCache[string, any](10, time.Minute))
+
+	calls := 0
+	load := func(context.Context) (interface{}, error) {
+		calls++
+		if calls == 1 {
+			return nil, ErrNotFound
+		}
+		return "value1", nil
+	}
+
+	_, _, _ = loader.GetOrLoad(ctx, "key1", load)
+	loader.Forget(ctx, "key1")
+
+	// Отрицательная запись забыта - значение загружается из источника
+	val, status, err := loader.GetOrLoad(ctx, "key1", load)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusMiss, status)
+	assert.Equal(t, "value1", val)
+	assert.Equal(t, 2, calls)
+}
+
+func TestLoader_GetOrLoad_NegativeCacheExpires(t *testing.T) {
+	clock := newFakeClock()
+	loader := NewLoader(NewLRUCache[string, any](2), WithNegativeCache[string, any](10, time.Second))
+	loader.negative.now = clock.Now
+	ctx := context.Background()
+
+	calls := 0
+	notFound := func(context.Context) (interface{}, error) {
+		calls++
+		return nil, ErrNotFound
+	}
+
+	_, _, _ = loader.GetOrLoad(ctx, "key1", notFound)
+	clock.Advance(time.Second)
+	_, status, err := loader.GetOrLoad(ctx, "key1", notFound)
+
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Equal(t, StatusMiss, status)
+	assert.Equal(t, 2, calls)
+}
+
+func TestLoader_GetOrLoad_StaleWhileRevalidate(t *testing.T) {
+	clock := newFakeClock()
+	ctx := context.Background()
+	main := NewLRUCache[string, any](2, WithTTL(10*time.Second), withClock(clock))
+	loader := NewLoader(main, WithStaleWhileRevalidate[string, any](5*time.Second))
+	loader.now = clock.Now
+	main.Set(ctx, "key1", "value1")
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	load := func(context.Context) (interface{}, error) {
+		calls.Add(1)
+		<-release
+		return "value2", nil
+	}
+
+	// До мягкого срока - обычное попадание
+	val, status, err := loader.GetOrLoad(ctx, "key1", load)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusHit, status)
+	assert.Equal(t, "value1", val)
+
+	// После мягкого срока значение отдается сразу, обновление идет в фоне и только одно
+	clock.Advance(6 * time.Second)
+	for i := 0; i < 3; i++ {
+		val, status, err = loader.GetOrLoad(ctx, "key1", load)
+		assert.NoError(t, err)
+		assert.Equal(t, StatusStale, status)
+		assert.Equal(t, "value1", val)
+	}
+	close(release)
+	assert.Eventually(t, func() bool {
+		entry, _ := main.Peek("key1")
+		return entry.Value == "value2"
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, int32(1), calls.Load())
+
+	val, status, _ = loader.GetOrLoad(ctx, "key1", load)
+	assert.Equal(t, StatusHit, status)
+	assert.Equal(t, "value2", val)
+
+	// После жесткого срока запрос ждет загрузки
+	clock.Advance(10 * time.Second)
+	_, status, err = loader.GetOrLoad(ctx, "key1", load)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusMiss, status)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestLoader_GetOrLoad_StaleValueSurvivesFailedRevalidation(t *testing.T) {
+	clock := newFakeClock()
+	ctx := context.Background()
+	main := NewLRUCache[string, any](2, WithTTL(10*time.Second), withClock(clock))
+	loader := NewLoader(main, WithStaleWhileRevalidate[string, any](5*time.Second))
+	loader.now = clock.Now
+	main.Set(ctx, "key1", "value1")
+	clock.Advance(6 * time.Second)
+
+	done := make(chan struct{})
+	_, status, _ := loader.GetOrLoad(ctx, "key1", func(context.Context) (interface{}, error) {
+		defer close(done)
+		return nil, errors.New("db error")
+	})
+	<-done
+	assert.Equal(t, StatusStale, status)
+
+	val, status, err := loader.GetOrLoad(ctx, "key1", func(context.Context) (interface{}, error) {
+		return "value2", nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, StatusStale, status)
+	assert.Equal(t, "value1", val)
+}
+
+func TestLoader_InvalidateDuringLoad(t *testing.T) {
+	assertions := assert.New(t)
+	ctx := context.Background()
+	lru := NewLRUCache[string, any](2)
+	loader := NewLoader(lru)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		// Значение прочитано из источника до изменения
+		val, _, err := loader.GetOrLoad(ctx, "key1", func(context.Context) (interface{}, error) {
+			close(started)
+			<-release
+			return "old", nil
+		})
+		assertions.NoError(err)
+		assertions.Equal("old", val, "ожидающие получают результат своей загрузки")
+	}()
+
+	<-started
+	loader.Invalidate(ctx, "key1")
+	close(release)
+	<-done
+
+	// Результат загрузки, начатой до инвалидации, не попал в кэш
+	assertions.Equal(0, lru.Len())
+	val, status, err := loader.GetOrLoad(ctx, "key1", func(context.Context) (interface{}, error) {
+		return "new", nil
+	})
+	assertions.NoError(err)
+	assertions.Equal(StatusMiss, status)
+	assertions.Equal("new", val)
+}
+
+func TestLoader_GetOrLoad_LoadPanic(t *testing.T) {
+	assertions := assert.New(t)
+	ctx := context.Background()
+	lru := NewLRUCache[string, any](2)
+	loader := NewLoader(lru)
+
+	// Паника загрузки возвращается ошибкой, а не роняет процесс
+	_, _, err := loader.GetOrLoad(ctx, "key1", func(context.Context) (interface{}, error) {
+		panic("boom")
+	})
+	assertions.ErrorIs(err, ErrLoadPanic)
+	assertions.Equal(0, lru.Len())
+
+	// Ключ не остается занятым: следующая загрузка выполняется
+	val, _, err := loader.GetOrLoad(ctx, "key1", func(context.Context) (interface{}, error) {
+		return "value1", nil
+	})
+	assertions.NoError(err)
+	assertions.Equal("value1", val)
+}
+
+func TestLoader_Refresh(t *testing.T) {
+	assertions := assert.New(t)
+	ctx := context.Background()
+	lru := NewLRUCache[string, any](2)
+	loader := NewLoader(lru, WithNegativeCache[string, any](10, time.Minute))
+
+	// Отрицательная запись заменяется загруженным значением
+	_, _, err := loader.GetOrLoad(ctx, "key1", func(context.Context) (interface{}, error) {
+		return nil, ErrNotFound
+	})
+	assertions.ErrorIs(err, ErrNotFound)
+	assertions.NoError(loader.Refresh(ctx, "key1", func(context.Context) (interface{}, error) {
+		return "value1", nil
+	}))
+	val, status, err := loader.GetOrLoad(ctx, "key1", nil)
+	assertions.NoError(err)
+	assertions.Equal(StatusHit, status)
+	assertions.Equal("value1", val)
+
+	// Неудачная загрузка удаляет прежнее значение
+	loadErr := errors.New("db down")
+	assertions.ErrorIs(loader.Refresh(ctx, "key1", func(context.Context) (interface{}, error) {
+		return nil, loadErr
+	}), loadErr)
+	assertions.Equal(0, lru.Len())
+}
+
+func TestLoader_Purge(t *testing.T) {
+	assertions := assert.New(t)
+	ctx := context.Background()
+	lru := NewLRUCache[string, any](2)
+	loader := NewLoader(lru, WithNegativeCache[string, any](10, time.Minute))
+
+	_, _, err := loader.GetOrLoad(ctx, "missing", func(context.Context) (interface{}, error) {
+		return nil, ErrNotFound
+	})
+	assertions.ErrorIs(err, ErrNotFound)
+
+	// Загрузка, начатая до очистки, не сохраняет результат
+	started := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _, _ = loader.GetOrLoad(ctx, "key1", func(context.Context) (interface{}, error) {
+			close(started)
+			<-release
+			return "old", nil
+		})
+	}()
+	<-started
+	loader.Purge(ctx)
+	close(release)
+	<-done
+	assertions.Equal(0, lru.Len())
+
+	// Отрицательная запись удалена: ключ снова загружается из источника
+	val, status, err := loader.GetOrLoad(ctx, "missing", func(context.Context) (interface{}, error) {
+		return "found", nil
+	})
+	assertions.NoError(err)
+	assertions.Equal(StatusMiss, status)
+	assertions.Equal("found", val)
+}
@@ -0,0 +1,328 @@
+package cache
+
+import (
+	"L0_project/internal/metrics"
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// localCache - кэш в памяти процесса. Хранение, сроки жизни, бюджет памяти,
+// метрики и трассировка общие для всех реализаций; порядок вытеснения
+// определяет политика (LRU, LFU, ARC, W-TinyLFU).
+type localCache[K comparable, V any] struct {
+	mu       sync.Mutex // Эксклюзивный: даже чтение меняет состояние политики
+	capacity int
+	items    map[K]*cacheItem[K, V]
+	policy   policy[K, V]
+	tracer   trace.Tracer // Для трассировки
+	spanAttr trace.SpanStartEventOption
+
+	ttl          time.Duration
+	now          func() time.Time
+	instrumented bool // Обновлять ли метрики размера, вытеснений и истечений
+
+	// Последние учтенные в метриках размеры. Метрики размера обновляются
+	// приращениями, поэтому шарды одного кэша складываются в общий итог.
+	reportedLen  int
+	reportedCost int64
+
+	maxBytes int64 // Бюджет стоимости (0 - без ограничения)
+	sizer    Sizer
+	cost     int64 // Текущая суммарная стоимость записей
+
+	stop      chan struct{} // Закрывается в Close для остановки janitor
+	closeOnce sync.Once
+}
+
+// cacheItem - запись кэша. Поля element, segment и freq принадлежат политике.
+type cacheItem[K comparable, V any] struct {
+	key       K
+	value     V
+	expiresAt time.Time // Нулевое значение - бессрочно
+	storedAt  time.Time // Момент записи значения
+	cost      int64     // Оценка стоимости (только при ограничении по памяти)
+
+	element *list.Element // Узел записи в списке политики
+	segment uint8         // Сегмент политики, в котором находится запись
+	freq    *list.Element // Узел частоты (LFU)
+}
+
+// expired сообщает, истек ли срок жизни записи к моменту now.
+func (i *cacheItem[K, V]) expired(now time.Time) bool {
+	return !i.expiresAt.IsZero() && !now.Before(i.expiresAt)
+}
+
+// newLocalCache создает кэш с заданной политикой вытеснения.
+func newLocalCache[K comparable, V any](capacity int, policy policy[K, V], opts ...Option) *localCache[K, V] {
+	return newLocalCacheWithOptions(capacity, policy, buildOptions(opts))
+}
+
+// newLocalCacheWithOptions создает кэш с уже разобранными настройками.
+func newLocalCacheWithOptions[K comparable, V any](capacity int, policy policy[K, V], o options) *localCache[K, V] {
+	c := &localCache[K, V]{
+		capacity: capacity,
+		items:    make(map[K]*cacheItem[K, V]),
+		policy:   policy,
+		tracer:   otel.Tracer("local-cache"), // Инициализация трейсера
+		spanAttr: trace.WithAttributes(attribute.String("cache.policy", policy.name())),
+		ttl:      o.ttl,
+		now:      o.now,
+		stop:     make(chan struct{}),
+
+		instrumented: !o.noMetrics,
+	}
+
+	if o.maxBytes > 0 {
+		c.maxBytes = o.maxBytes
+		c.sizer = o.sizer
+		if c.sizer == nil {
+			c.sizer = JSONSizer
+		}
+	}
+
+	if o.cleanupInterval > 0 {
+		go c.janitor(o.cleanupInterval)
+	}
+	return c
+}
+
+func (c *localCache[K, V]) Set(ctx context.Context, key K, value V) {
+	c.SetWithTTL(ctx, key, value, c.ttl)
+}
+
+func (c *localCache[K, V]) SetWithTTL(ctx context.Context, key K, value V, ttl time.Duration) {
+	c.setStoredAt(ctx, key, value, ttl, time.Time{})
+}
+
+// setStoredAt сохраняет значение с моментом записи storedAt (нулевое значение -
+// текущий момент). Используется при загрузке снимка, чтобы возраст записей
+// отсчитывался от их исходной записи.
+func (c *localCache[K, V]) setStoredAt(ctx context.Context, key K, value V, ttl time.Duration, storedAt time.Time) {
+	// Создаем span для трассировки
+	_, span := c.tracer.Start(ctx, "Cache.Set", c.spanAttr)
+	defer span.End()
+
+	// Стоимость оцениваем до захвата мьютекса: сериализация может быть дорогой
+	var cost int64
+	if c.maxBytes > 0 {
+		cost = c.sizer(value)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.capacity <= 0 {
+		return
+	}
+
+	if c.maxBytes > 0 && cost > c.maxBytes {
+		// Запись не помещается даже в пустой кэш; старое значение по ключу тоже неактуально
+		if item, exists := c.items[key]; exists {
+			c.policy.remove(item)
+			c.forget(item)
+			c.reportSize()
+		}
+		return
+	}
+
+	now := c.now()
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = now.Add(ttl)
+	}
+	if storedAt.IsZero() {
+		storedAt = now
+	}
+
+	if item, exists := c.items[key]; exists {
+		c.policy.access(item)
+		item.value = value
+		item.expiresAt = expiresAt
+		item.storedAt = storedAt
+		c.cost += cost - item.cost
+		item.cost = cost
+		c.evictOverBudget()
+		c.reportSize()
+		return
+	}
+
+	item := &cacheItem[K, V]{key: key, value: value, expiresAt: expiresAt, storedAt: storedAt, cost: cost}
+	c.items[key] = item
+	c.cost += cost
+	for _, evicted := range c.policy.add(item) {
+		c.evicted(evicted)
+	}
+	c.evictOverBudget()
+
+	// Обновляем метрику размера кэша
+	c.reportSize()
+}
+
+func (c *localCache[K, V]) Get(ctx context.Context, key K) (V, bool) {
+	// Создаем span для трассировки
+	_, span := c.tracer.Start(ctx, "Cache.Get", c.spanAttr)
+	defer span.End()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var zero V
+	item, exists := c.items[key]
+	if !exists {
+		c.policy.miss(key)
+		return zero, false
+	}
+	if item.expired(c.now()) {
+		// Ленивое удаление просроченной записи
+		c.removeExpired(item)
+		return zero, false
+	}
+	c.policy.access(item)
+	return item.value, true
+}
+
+func (c *localCache[K, V]) Peek(key K) (Entry[V], bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	item, exists := c.items[key]
+	if !exists || item.expired(c.now()) {
+		return Entry[V]{}, false
+	}
+	return Entry[V]{Value: item.value, ExpiresAt: item.expiresAt, StoredAt: item.storedAt}, true
+}
+
+func (c *localCache[K, V]) Delete(ctx context.Context, key K) bool {
+	// Создаем span для трассировки
+	_, span := c.tracer.Start(ctx, "Cache.Delete", c.spanAttr)
+	defer span.End()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	item, exists := c.items[key]
+	if !exists {
+		return false
+	}
+	c.policy.remove(item)
+	c.forget(item)
+
+	c.reportSize()
+	return true
+}
+
+func (c *localCache[K, V]) Purge(ctx context.Context) {
+	// Создаем span для трассировки
+	_, span := c.tracer.Start(ctx, "Cache.Purge", c.spanAttr)
+	defer span.End()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.items = make(map[K]*cacheItem[K, V])
+	c.policy.reset()
+	c.cost = 0
+
+	c.reportSize()
+}
+
+func (c *localCache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.items)
+}
+
+func (c *localCache[K, V]) Keys() []K {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.policy.keys()
+}
+
+// Close останавливает фоновую очистку. Повторные вызовы безопасны.
+func (c *localCache[K, V]) Close() {
+	c.closeOnce.Do(func() {
+		close(c.stop)
+	})
+}
+
+// janitor периодически удаляет просроченные записи до вызова Close.
+func (c *localCache[K, V]) janitor(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			c.deleteExpired()
+		}
+	}
+}
+
+// deleteExpired удаляет все просроченные записи.
+func (c *localCache[K, V]) deleteExpired() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	for _, item := range c.items {
+		if item.expired(now) {
+			c.removeExpired(item)
+		}
+	}
+}
+
+// evictOverBudget вытесняет записи по политике, пока суммарная стоимость
+// превышает бюджет (мьютекс уже захвачен). Политика может выбрать и только что
+// записанное значение, если считает его наименее ценным (например, LFU).
+func (c *localCache[K, V]) evictOverBudget() {
+	for c.maxBytes > 0 && c.cost > c.maxBytes && len(c.items) > 0 {
+		c.evicted(c.policy.evict())
+	}
+}
+
+// evicted учитывает запись, вытесненную политикой (мьютекс уже захвачен).
+func (c *localCache[K, V]) evicted(item *cacheItem[K, V]) {
+	c.forget(item)
+
+	// Обновляем метрики
+	if c.instrumented {
+		metrics.CacheEvictions.Inc()
+	}
+}
+
+// removeExpired удаляет просроченную запись (мьютекс уже захвачен).
+func (c *localCache[K, V]) removeExpired(item *cacheItem[K, V]) {
+	c.policy.remove(item)
+	c.forget(item)
+
+	// Обновляем метрики
+	if c.instrumented {
+		metrics.CacheExpirations.Inc()
+	}
+	c.reportSize()
+}
+
+// forget удаляет запись из индекса; из структур политики она уже удалена
+// (мьютекс уже захвачен).
+func (c *localCache[K, V]) forget(item *cacheItem[K, V]) {
+	delete(c.items, item.key)
+	c.cost -= item.cost
+}
+
+// reportSize обновляет метрики размера кэша (мьютекс уже захвачен).
+func (c *localCache[K, V]) reportSize() {
+	if !c.instrumented {
+		return
+	}
+	metrics.CacheSize.Add(float64(len(c.items) - c.reportedLen))
+	metrics.CacheSizeBytes.Add(float64(c.cost - c.reportedCost))
+	c.reportedLen, c.reportedCost = len(c.items), c.cost
+}
@@ -1,123 +1,155 @@
 package cache
 
 import (
-	"L0_project/internal/database"
-	"L0_project/internal/metrics"
 	"container/list"
 	"context"
-	"log"
-	"sync"
-
-	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/trace"
+	"time"
 )
 
 //go:generate mockgen -source=lru.go -destination=./mocks/cache_mock.go -package=mocks Cache
 
-// Cache определяет интерфейс для кэширования.
+// Cache определяет типизированный интерфейс для кэширования.
 // Контекст добавлен для поддержки сквозной трассировки.
-type Cache interface {
-	Set(ctx context.Context, key string, value interface{})
-	Get(ctx context.Context, key string) (interface{}, bool)
+type Cache[K comparable, V any] interface {
+	// Set сохраняет значение со сроком жизни по умолчанию.
+	Set(ctx context.Context, key K, value V)
+	// SetWithTTL сохраняет значение с собственным сроком жизни (ttl <= 0 - бессрочно).
+	SetWithTTL(ctx context.Context, key K, value V, ttl time.Duration)
+	Get(ctx context.Context, key K) (V, bool)
+	// Peek возвращает запись со служебными сведениями, не считая это обращением
+	// для политики вытеснения. Просроченная запись не возвращается.
+	Peek(key K) (Entry[V], bool)
+	// Delete удаляет запись и сообщает, была ли она в кэше.
+	Delete(ctx context.Context, key K) bool
+	// Purge удаляет все записи.
+	Purge(ctx context.Context)
+	// Len возвращает текущее количество записей (включая просроченные, еще не удаленные очисткой).
+	Len() int
+	// Keys возвращает ключи от наиболее ценных для политики вытеснения к наименее
+	// ценным (для LRU - от самых свежих к самым старым).
+	Keys() []K
+	// Close останавливает фоновые процессы кэша.
+	Close()
 }
 
-// lruCache реализует LRU (Least Recently Used) кэш.
-type lruCache struct {
-	mu       sync.RWMutex
-	capacity int
-	items    map[string]*list.Element
-	queue    *list.List
-	tracer   trace.Tracer // Для трассировки
+// Entry - запись кэша со служебными сведениями.
+type Entry[V any] struct {
+	Value     V
+	ExpiresAt time.Time // Нулевое значение - бессрочно
+	StoredAt  time.Time // Момент записи значения (по нему считается возраст записи)
 }
 
-type cacheItem struct {
-	key   string
-	value interface{}
+// Option настраивает кэш при создании.
+type Option func(*options)
+
+type options struct {
+	ttl             time.Duration    // Срок жизни записей по умолчанию (0 - бессрочно)
+	cleanupInterval time.Duration    // Период фоновой очистки просроченных записей (0 - без очистки)
+	now             func() time.Time // Источник времени (подменяется в тестах)
+	noMetrics       bool             // Не обновлять метрики основного кэша (для служебных кэшей)
+	maxBytes        int64            // Бюджет суммарной стоимости записей (0 - без ограничения)
+	sizer           Sizer            // Оценка стоимости записи
+	shards          int              // Количество шардов (0 или 1 - без шардирования)
 }
 
-// NewLRUCache создает новый LRU-кэш с заданной емкостью.
-func NewLRUCache(capacity int) Cache {
-	return &lruCache{
-		capacity: capacity,
-		items:    make(map[string]*list.Element),
-		queue:    list.New(),
-		tracer:   otel.Tracer("lru-cache"), // Инициализация трейсера
+// buildOptions применяет опции к настройкам по умолчанию.
+func buildOptions(opts []Option) options {
+	o := options{now: time.Now}
+	for _, opt := range opts {
+		opt(&o)
 	}
+	return o
 }
 
-func (c *lruCache) Set(ctx context.Context, key string, value interface{}) {
-	// Создаем span для трассировки
-	_, span := c.tracer.Start(ctx, "Cache.Set")
-	defer span.End()
-
-	c.mu.Lock()
-	defer c.mu.Unlock()
+// WithTTL задает срок жизни записей по умолчанию.
+func WithTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.ttl = ttl
+	}
+}
 
-	if c.capacity <= 0 {
-		return
+// WithCleanupInterval включает фоновую очистку просроченных записей с заданным периодом.
+// Без нее просроченные записи удаляются лениво - при обращении или вытеснении.
+func WithCleanupInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.cleanupInterval = interval
 	}
+}
 
-	if element, exists := c.items[key]; exists {
-		c.queue.MoveToFront(element)
-		element.Value.(*cacheItem).value = value
-		return
+// WithMaxBytes ограничивает суммарную стоимость записей (в байтах, по оценке sizer).
+// При превышении бюджета записи вытесняются по политике кэша; запись дороже всего
+// бюджета не сохраняется. Ограничение по количеству записей продолжает действовать.
+// Если sizer не задан, используется JSONSizer.
+func WithMaxBytes(maxBytes int64, sizer Sizer) Option {
+	return func(o *options) {
+		o.maxBytes = maxBytes
+		o.sizer = sizer
 	}
+}
 
-	if c.queue.Len() >= c.capacity && c.capacity > 0 {
-		c.removeOldest()
+// withoutMetrics отключает метрики основного кэша. Используется для служебных
+// кэшей (например, отрицательного), чтобы они не искажали метрики заказов.
+func withoutMetrics() Option {
+	return func(o *options) {
+		o.noMetrics = true
 	}
+}
+
+// NewLRUCache создает новый LRU-кэш с заданной емкостью.
+func NewLRUCache[K comparable, V any](capacity int, opts ...Option) Cache[K, V] {
+	return newCache(capacity, newLRUPolicy[K, V], opts...)
+}
 
-	item := &cacheItem{key: key, value: value}
-	element := c.queue.PushFront(item)
-	c.items[key] = element
+// newLRUCache создает LRU-кэш и возвращает конкретный тип для внутреннего использования.
+func newLRUCache[K comparable, V any](capacity int, opts ...Option) *localCache[K, V] {
+	return newLocalCache(capacity, newLRUPolicy[K, V](capacity), opts...)
+}
 
-	// Обновляем метрику размера кэша
-	metrics.CacheSize.Set(float64(c.queue.Len()))
+// lruPolicy реализует LRU (Least Recently Used): вытесняется запись,
+// к которой дольше всего не обращались.
+type lruPolicy[K comparable, V any] struct {
+	capacity int
+	queue    *list.List // От самых свежих к самым старым
 }
 
-func (c *lruCache) Get(ctx context.Context, key string) (interface{}, bool) {
-	// Создаем span для трассировки
-	_, span := c.tracer.Start(ctx, "Cache.Get")
-	defer span.End()
+func newLRUPolicy[K comparable, V any](capacity int) policy[K, V] {
+	return &lruPolicy[K, V]{capacity: capacity, queue: list.New()}
+}
 
-	c.mu.Lock()
-	defer c.mu.Unlock()
+func (p *lruPolicy[K, V]) name() string { return PolicyLRU }
 
-	if element, exists := c.items[key]; exists {
-		c.queue.MoveToFront(element)
-		return element.Value.(*cacheItem).value, true
+func (p *lruPolicy[K, V]) add(item *cacheItem[K, V]) []*cacheItem[K, V] {
+	var evicted []*cacheItem[K, V]
+	if p.queue.Len() >= p.capacity {
+		evicted = append(evicted, p.evict())
 	}
+	item.element = p.queue.PushFront(item)
+	return evicted
+}
 
-	return nil, false
+func (p *lruPolicy[K, V]) access(item *cacheItem[K, V]) {
+	p.queue.MoveToFront(item.element)
 }
 
-// removeOldest удаляет самый старый элемент (внутренняя функция, мьютекс уже захвачен).
-func (c *lruCache) removeOldest() {
-	element := c.queue.Back()
-	if element != nil {
-		item := c.queue.Remove(element).(*cacheItem)
-		delete(c.items, item.key)
+func (p *lruPolicy[K, V]) miss(K) {}
 
-		// Обновляем метрики
-		metrics.CacheEvictions.Inc()
-		metrics.CacheSize.Set(float64(c.queue.Len()))
-	}
+func (p *lruPolicy[K, V]) remove(item *cacheItem[K, V]) {
+	p.queue.Remove(item.element)
 }
 
-// WarmUp загружает данные из БД в кэш.
-func WarmUp(ctx context.Context, storage database.Storage, cache Cache) error {
-	log.Println("Выполняется прогрев кэша...")
-	orders, err := storage.GetAllOrders(ctx)
-	if err != nil {
-		return err
+// evict удаляет самую старую запись.
+func (p *lruPolicy[K, V]) evict() *cacheItem[K, V] {
+	element := p.queue.Back()
+	if element == nil {
+		return nil
 	}
+	return p.queue.Remove(element).(*cacheItem[K, V])
+}
 
-	for _, order := range orders {
-		orderCopy := order
-		// Передаем контекст
-		cache.Set(ctx, order.OrderUID, &orderCopy)
-	}
+func (p *lruPolicy[K, V]) keys() []K {
+	return listKeys[K, V](make([]K, 0, p.queue.Len()), p.queue)
+}
 
-	log.Printf("Кэш прогрет. Загружено %d заказов.", len(orders))
-	return nil
+func (p *lruPolicy[K, V]) reset() {
+	p.queue.Init()
 }
@@ -1,14 +1,17 @@
 package cache
 
 import (
+	"L0_project/internal/model"
 	"context"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
 
 func TestLRUCache_SetAndGet(t *testing.T) {
-	cache := NewLRUCache(2)
+	cache := NewLRUCache[string, any](2)
 	assertions := assert.New(t)
 	ctx := context.Background()
 
@@ -31,7 +34,7 @@ func TestLRUCache_SetAndGet(t *testing.T) {
 }
 
 func TestLRUCache_Eviction(t *testing.T) {
-	cache := NewLRUCache(2)
+	cache := NewLRUCache[string, any](2)
 	assertions := assert.New(t)
 	ctx := context.Background()
 
@@ -56,7 +59,7 @@ func TestLRUCache_Eviction(t *testing.T) {
 }
 
 func TestLRUCache_UsageUpdatesOrder(t *testing.T) {
-	cache := NewLRUCache(2)
+	cache := NewLRUCache[string, any](2)
 	assertions := assert.New(t)
 	ctx := context.Background()
 
@@ -81,7 +84,7 @@ func TestLRUCache_UsageUpdatesOrder(t *testing.T) {
 }
 
 func TestLRUCache_UpdateValue(t *testing.T) {
-	cache := NewLRUCache(2)
+	cache := NewLRUCache[string, any](2)
 	assertions := assert.New(t)
 	ctx := context.Background()
 
@@ -99,7 +102,7 @@ func TestLRUCache_UpdateValue(t *testing.T) {
 
 func TestLRUCache_ZeroCapacity(t *testing.T) {
 	// Кэш с 0 емкостью не должен ничего хранить
-	cache := NewLRUCache(0)
+	cache := NewLRUCache[string, any](0)
 	assertions := assert.New(t)
 	ctx := context.Background()
 
@@ -107,3 +110,242 @@ func TestLRUCache_ZeroCapacity(t *testing.T) {
 	_, found := cache.Get(ctx, "key1")
 	assertions.False(found)
 }
+
+// fakeClock - управляемый источник времени для тестов TTL.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// withClock подменяет источник времени кэша.
+func withClock(clock *fakeClock) Option {
+	return func(o *options) {
+		o.now = clock.Now
+	}
+}
+
+func TestLRUCache_DefaultTTL(t *testing.T) {
+	clock := newFakeClock()
+	cache := NewLRUCache[string, any](2, WithTTL(time.Minute), withClock(clock))
+	assertions := assert.New(t)
+	ctx := context.Background()
+
+	cache.Set(ctx, "key1", "value1")
+
+	clock.Advance(59 * time.Second)
+	val, found := cache.Get(ctx, "key1")
+	assertions.True(found)
+	assertions.Equal("value1", val)
+
+	// Срок жизни истек - запись удаляется при обращении
+	clock.Advance(time.Second)
+	_, found = cache.Get(ctx, "key1")
+	assertions.False(found, "key1 should be expired")
+	assertions.Equal(0, cache.Len())
+}
+
+func TestLRUCache_SetWithTTL(t *testing.T) {
+	clock := newFakeClock()
+	cache := NewLRUCache[string, any](3, WithTTL(time.Minute), withClock(clock))
+	assertions := assert.New(t)
+	ctx := context.Background()
+
+	cache.SetWithTTL(ctx, "short", "value1", 10*time.Second)
+	cache.SetWithTTL(ctx, "forever", "value2", 0)
+	cache.Set(ctx, "default", "value3")
+
+	clock.Advance(10 * time.Second)
+	_, found := cache.Get(ctx, "short")
+	assertions.False(found, "short should be expired")
+	_, found = cache.Get(ctx, "default")
+	assertions.True(found)
+
+	clock.Advance(time.Hour)
+	_, found = cache.Get(ctx, "default")
+	assertions.False(found, "default should be expired")
+	_, found = cache.Get(ctx, "forever")
+	assertions.True(found, "forever should never expire")
+}
+
+func TestLRUCache_SetRefreshesTTL(t *testing.T) {
+	clock := newFakeClock()
+	cache := NewLRUCache[string, any](2, WithTTL(time.Minute), withClock(clock))
+	assertions := assert.New(t)
+	ctx := context.Background()
+
+	cache.Set(ctx, "key1", "value1")
+	clock.Advance(50 * time.Second)
+
+	// Повторная запись продлевает срок жизни
+	cache.Set(ctx, "key1", "value_new")
+	clock.Advance(50 * time.Second)
+
+	val, found := cache.Get(ctx, "key1")
+	assertions.True(found)
+	assertions.Equal("value_new", val)
+}
+
+func TestLRUCache_Janitor(t *testing.T) {
+	clock := newFakeClock()
+	cache := NewLRUCache[string, any](3, WithTTL(time.Minute), WithCleanupInterval(time.Millisecond), withClock(clock))
+	defer cache.Close()
+	ctx := context.Background()
+
+	cache.Set(ctx, "key1", "value1")
+	cache.Set(ctx, "key2", "value2")
+	cache.SetWithTTL(ctx, "forever", "value3", 0)
+
+	clock.Advance(time.Minute)
+
+	// Фоновая очистка удаляет просроченные записи без обращения к ним
+	assert.Eventually(t, func() bool {
+		return cache.Len() == 1
+	}, time.Second, time.Millisecond)
+
+	_, found := cache.Get(ctx, "forever")
+	assert.True(t, found)
+}
+
+func TestLRUCache_CloseIsIdempotent(t *testing.T) {
+	cache := NewLRUCache[string, any](1, WithCleanupInterval(time.Millisecond))
+	cache.Close()
+	cache.Close()
+}
+
+func TestLRUCache_Delete(t *testing.T) {
+	cache := NewLRUCache[string, any](2)
+	assertions := assert.New(t)
+	ctx := context.Background()
+
+	cache.Set(ctx, "key1", "value1")
+	cache.Set(ctx, "key2", "value2")
+
+	assertions.True(cache.Delete(ctx, "key1"))
+	assertions.False(cache.Delete(ctx, "key1"), "повторное удаление ничего не удаляет")
+
+	_, found := cache.Get(ctx, "key1")
+	assertions.False(found)
+	assertions.Equal(1, cache.Len())
+
+	// Освободившееся место используется без вытеснения
+	cache.Set(ctx, "key3", "value3")
+	_, found = cache.Get(ctx, "key2")
+	assertions.True(found)
+	assertions.Equal(2, cache.Len())
+}
+
+func TestLRUCache_PurgeAndKeys(t *testing.T) {
+	cache := NewLRUCache[string, any](3)
+	assertions := assert.New(t)
+	ctx := context.Background()
+
+	cache.Set(ctx, "key1", "value1")
+	cache.Set(ctx, "key2", "value2")
+	cache.Set(ctx, "key3", "value3")
+	cache.Get(ctx, "key1")
+
+	// От самых свежих к самым старым
+	assertions.Equal([]string{"key1", "key3", "key2"}, cache.Keys())
+
+	cache.Purge(ctx)
+	assertions.Equal(0, cache.Len())
+	assertions.Empty(cache.Keys())
+
+	// После очистки кэш продолжает работать
+	cache.Set(ctx, "key4", "value4")
+	assertions.Equal([]string{"key4"}, cache.Keys())
+}
+
+// lenSizer оценивает строку ее длиной.
+func lenSizer(value interface{}) int64 {
+	return int64(len(value.(string)))
+}
+
+func TestLRUCache_MaxBytes(t *testing.T) {
+	cache := newLRUCache[string, any](10, WithMaxBytes(10, lenSizer))
+	assertions := assert.New(t)
+	ctx := context.Background()
+
+	cache.Set(ctx, "key1", "aaaa")
+	cache.Set(ctx, "key2", "bbbb")
+	assertions.Equal(int64(8), cache.cost)
+
+	// Не помещается в бюджет - вытесняется самая старая запись
+	cache.Set(ctx, "key3", "cccc")
+	_, found := cache.Get(ctx, "key1")
+	assertions.False(found, "key1 should be evicted by byte budget")
+	assertions.Equal(2, cache.Len())
+	assertions.Equal(int64(8), cache.cost)
+
+	// Увеличение значения по ключу тоже учитывается
+	cache.Set(ctx, "key3", "cccccccc")
+	assertions.Equal([]string{"key3"}, cache.Keys())
+	assertions.Equal(int64(8), cache.cost)
+
+	cache.Delete(ctx, "key3")
+	assertions.Equal(int64(0), cache.cost)
+}
+
+func TestLRUCache_MaxBytes_OversizedEntry(t *testing.T) {
+	cache := newLRUCache[string, any](10, WithMaxBytes(4, lenSizer))
+	assertions := assert.New(t)
+	ctx := context.Background()
+
+	cache.Set(ctx, "key1", "aaa")
+	cache.Set(ctx, "key2", "too long")
+
+	// Слишком дорогая запись не сохраняется и не вытесняет остальные
+	_, found := cache.Get(ctx, "key2")
+	assertions.False(found)
+	_, found = cache.Get(ctx, "key1")
+	assertions.True(found)
+
+	// Обновление существующего ключа слишком дорогим значением удаляет старое
+	cache.Set(ctx, "key1", "too long")
+	_, found = cache.Get(ctx, "key1")
+	assertions.False(found)
+	assertions.Equal(int64(0), cache.cost)
+}
+
+func TestOrderSizer(t *testing.T) {
+	small := &model.Order{OrderUID: "uid", Items: []model.Item{{Name: "item"}}}
+	large := &model.Order{OrderUID: "uid", Items: make([]model.Item, 100)}
+
+	assert.Greater(t, OrderSizer(large), OrderSizer(small), "стоимость растет с числом товаров")
+	assert.Equal(t, OrderSizer(small), OrderSizer(*small))
+	assert.Equal(t, JSONSizer("abc"), OrderSizer("abc"))
+}
+
+func TestLRUCache_PeekDoesNotPromote(t *testing.T) {
+	cache := NewLRUCache[string, string](2)
+	ctx := context.Background()
+
+	cache.Set(ctx, "key1", "value1")
+	cache.Set(ctx, "key2", "value2")
+
+	entry, found := cache.Peek("key1")
+	assert.True(t, found)
+	assert.Equal(t, "value1", entry.Value)
+
+	// key1 остается самой старой записью и вытесняется первой
+	cache.Set(ctx, "key3", "value3")
+	_, found = cache.Peek("key1")
+	assert.False(t, found)
+	assert.Equal(t, []string{"key3", "key2"}, cache.Keys())
+}
@@ -10,59 +10,154 @@
 package mocks
 
 import (
+	cache "L0_project/internal/cache"
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	gomock "go.uber.org/mock/gomock"
 )
 
 // MockCache is a mock of Cache interface.
-type MockCache struct {
+type MockCache[K comparable, V any] struct {
 	ctrl     *gomock.Controller
-	recorder *MockCacheMockRecorder
+	recorder *MockCacheMockRecorder[K, V]
 	isgomock struct{}
 }
 
 // MockCacheMockRecorder is the mock recorder for MockCache.
-type MockCacheMockRecorder struct {
-	mock *MockCache
+type MockCacheMockRecorder[K comparable, V any] struct {
+	mock *MockCache[K, V]
 }
 
 // NewMockCache creates a new mock instance.
-func NewMockCache(ctrl *gomock.Controller) *MockCache {
-	mock := &MockCache{ctrl: ctrl}
-	mock.recorder = &MockCacheMockRecorder{mock}
+func NewMockCache[K comparable, V any](ctrl *gomock.Controller) *MockCache[K, V] {
+	mock := &MockCache[K, V]{ctrl: ctrl}
+	mock.recorder = &MockCacheMockRecorder[K, V]{mock}
 	return mock
 }
 
 // EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockCache) EXPECT() *MockCacheMockRecorder {
+func (m *MockCache[K, V]) EXPECT() *MockCacheMockRecorder[K, V] {
 	return m.recorder
 }
 
+// Close mocks base method.
+func (m *MockCache[K, V]) Close() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Close")
+}
+
+// Close indicates an expected call of Close.
+func (mr *MockCacheMockRecorder[K, V]) Close() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockCache[K, V])(nil).Close))
+}
+
+// Delete mocks base method.
+func (m *MockCache[K, V]) Delete(ctx context.Context, key K) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, key)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockCacheMockRecorder[K, V]) Delete(ctx, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockCache[K, V])(nil).Delete), ctx, key)
+}
+
 // Get mocks base method.
-func (m *MockCache) Get(ctx context.Context, key string) (any, bool) {
+func (m *MockCache[K, V]) Get(ctx context.Context, key K) (V, bool) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Get", ctx, key)
-	ret0, _ := ret[0].(any)
+	ret0, _ := ret[0].(V)
 	ret1, _ := ret[1].(bool)
 	return ret0, ret1
 }
 
 // Get indicates an expected call of Get.
-func (mr *MockCacheMockRecorder) Get(ctx, key any) *gomock.Call {
+func (mr *MockCacheMockRecorder[K, V]) Get(ctx, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockCache[K, V])(nil).Get), ctx, key)
+}
+
+// Keys mocks base method.
+func (m *MockCache[K, V]) Keys() []K {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Keys")
+	ret0, _ := ret[0].([]K)
+	return ret0
+}
+
+// Keys indicates an expected call of Keys.
+func (mr *MockCacheMockRecorder[K, V]) Keys() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Keys", reflect.TypeOf((*MockCache[K, V])(nil).Keys))
+}
+
+// Len mocks base method.
+func (m *MockCache[K, V]) Len() int {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Len")
+	ret0, _ := ret[0].(int)
+	return ret0
+}
+
+// Len indicates an expected call of Len.
+func (mr *MockCacheMockRecorder[K, V]) Len() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Len", reflect.TypeOf((*MockCache[K, V])(nil).Len))
+}
+
+// Peek mocks base method.
+func (m *MockCache[K, V]) Peek(key K) (cache.Entry[V], bool) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Peek", key)
+	ret0, _ := ret[0].(cache.Entry[V])
+	ret1, _ := ret[1].(bool)
+	return ret0, ret1
+}
+
+// Peek indicates an expected call of Peek.
+func (mr *MockCacheMockRecorder[K, V]) Peek(key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Peek", reflect.TypeOf((*MockCache[K, V])(nil).Peek), key)
+}
+
+// Purge mocks base method.
+func (m *MockCache[K, V]) Purge(ctx context.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Purge", ctx)
+}
+
+// Purge indicates an expected call of Purge.
+func (mr *MockCacheMockRecorder[K, V]) Purge(ctx any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockCache)(nil).Get), ctx, key)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Purge", reflect.TypeOf((*MockCache[K, V])(nil).Purge), ctx)
 }
 
 // Set mocks base method.
-func (m *MockCache) Set(ctx context.Context, key string, value any) {
+func (m *MockCache[K, V]) Set(ctx context.Context, key K, value V) {
 	m.ctrl.T.Helper()
 	m.ctrl.Call(m, "Set", ctx, key, value)
 }
 
 // Set indicates an expected call of Set.
-func (mr *MockCacheMockRecorder) Set(ctx, key, value any) *gomock.Call {
+func (mr *MockCacheMockRecorder[K, V]) Set(ctx, key, value any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Set", reflect.TypeOf((*MockCache[K, V])(nil).Set), ctx, key, value)
+}
+
+// SetWithTTL mocks base method.
+func (m *MockCache[K, V]) SetWithTTL(ctx context.Context, key K, value V, ttl time.Duration) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetWithTTL", ctx, key, value, ttl)
+}
+
+// SetWithTTL indicates an expected call of SetWithTTL.
+func (mr *MockCacheMockRecorder[K, V]) SetWithTTL(ctx, key, value, ttl any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Set", reflect.TypeOf((*MockCache)(nil).Set), ctx, key, value)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetWithTTL", reflect.TypeOf((*MockCache[K, V])(nil).SetWithTTL), ctx, key, value, ttl)
 }
@@ -0,0 +1,69 @@
+package cache
+
+import (
+	"container/list"
+	"fmt"
+)
+
+// Названия политик вытеснения для New и CACHE_POLICY.
+const (
+	PolicyLRU     = "lru"     // Вытесняется давно не использованная запись
+	PolicyLFU     = "lfu"     // Вытесняется редко используемая запись
+	PolicyARC     = "arc"     // Adaptive Replacement Cache: баланс между свежестью и частотой
+	PolicyTinyLFU = "tinylfu" // W-TinyLFU: LRU-окно и фильтр допуска по частоте
+)
+
+// policy определяет порядок вытеснения записей localCache.
+// Все методы вызываются под мьютексом кэша.
+type policy[K comparable, V any] interface {
+	// name возвращает название политики (для трассировки).
+	name() string
+	// add регистрирует новую запись и возвращает записи, вытесненные ради нее
+	// (в том числе саму запись, если политика ее не допустила).
+	add(item *cacheItem[K, V]) []*cacheItem[K, V]
+	// access отмечает обращение к записи (чтение или перезапись).
+	access(item *cacheItem[K, V])
+	// miss отмечает обращение к отсутствующему ключу.
+	miss(key K)
+	// remove удаляет запись по инициативе кэша (удаление, истечение срока).
+	remove(item *cacheItem[K, V])
+	// evict выбирает и удаляет запись для вытеснения сверх бюджета памяти.
+	evict() *cacheItem[K, V]
+	// keys возвращает ключи от наиболее ценных для политики к наименее ценным.
+	keys() []K
+	// reset очищает состояние политики.
+	reset()
+}
+
+// New создает кэш с политикой вытеснения по названию (см. Policy*).
+func New[K comparable, V any](policyName string, capacity int, opts ...Option) (Cache[K, V], error) {
+	newPolicy, err := policyConstructor[K, V](policyName)
+	if err != nil {
+		return nil, err
+	}
+	return newCache(capacity, newPolicy, opts...), nil
+}
+
+// policyConstructor возвращает конструктор политики вытеснения по названию.
+func policyConstructor[K comparable, V any](policyName string) (func(capacity int) policy[K, V], error) {
+	switch policyName {
+	case PolicyLRU, "":
+		return newLRUPolicy[K, V], nil
+	case PolicyLFU:
+		return newLFUPolicy[K, V], nil
+	case PolicyARC:
+		return newARCPolicy[K, V], nil
+	case PolicyTinyLFU:
+		return newTinyLFUPolicy[K, V], nil
+	default:
+		return nil, fmt.Errorf("неизвестная политика вытеснения кэша: %q", policyName)
+	}
+}
+
+// listKeys добавляет к keys ключи записей списка от начала к концу.
+func listKeys[K comparable, V any](keys []K, l *list.List) []K {
+	for element := l.Front(); element != nil; element = element.Next() {
+		keys = append(keys, element.Value.(*cacheItem[K, V]).key)
+	}
+	return keys
+}
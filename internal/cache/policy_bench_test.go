@@ -0,0 +1,106 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"testing"
+)
+
+// traceOp - одно обращение воспроизводимой трассы: чтение через кэш
+// (промах дозаписывает значение) или запись без последующего чтения.
+type traceOp struct {
+	key   string
+	write bool
+}
+
+const (
+	benchCapacity = 1000
+	benchTraceLen = 200_000
+)
+
+// benchTraces - трассы обращений для сравнения политик по доле попаданий.
+// Строятся лениво, чтобы не замедлять обычный go test пакета.
+var benchTraces = sync.OnceValue(func() map[string][]traceOp {
+	return map[string][]traceOp{
+		// Чтения с распределением Ципфа по 50 000 заказов
+		"zipf": zipfTrace(benchTraceLen, 50_000, 1.1),
+		// Поддержка читает горячие заказы, консьюмер пишет поток новых
+		"support+consumer": mixedTrace(benchTraceLen, 5_000, 1.2, 0.5),
+		// Циклический проход по набору чуть больше емкости - худший случай для LRU
+		"loop": loopTrace(benchTraceLen, benchCapacity+benchCapacity/5),
+	}
+})
+
+func zipfTrace(n int, keys uint64, skew float64) []traceOp {
+	r := rand.New(rand.NewPCG(1, 2))
+	zipf := rand.NewZipf(r, skew, 1, keys-1)
+	trace := make([]traceOp, n)
+	for i := range trace {
+		trace[i] = traceOp{key: fmt.Sprintf("order%d", zipf.Uint64())}
+	}
+	return trace
+}
+
+func mixedTrace(n int, hotKeys uint64, skew, writeShare float64) []traceOp {
+	r := rand.New(rand.NewPCG(3, 4))
+	zipf := rand.NewZipf(r, skew, 1, hotKeys-1)
+	trace := make([]traceOp, n)
+	for i := range trace {
+		if r.Float64() < writeShare {
+			trace[i] = traceOp{key: fmt.Sprintf("new%d", i), write: true}
+		} else {
+			trace[i] = traceOp{key: fmt.Sprintf("order%d", zipf.Uint64())}
+		}
+	}
+	return trace
+}
+
+func loopTrace(n, keys int) []traceOp {
+	trace := make([]traceOp, n)
+	for i := range trace {
+		trace[i] = traceOp{key: fmt.Sprintf("order%d", i%keys)}
+	}
+	return trace
+}
+
+// replay воспроизводит трассу и возвращает долю попаданий среди чтений.
+func replay(c Cache[string, any], trace []traceOp) float64 {
+	ctx := context.Background()
+	var reads, hits int
+	for _, op := range trace {
+		if op.write {
+			c.Set(ctx, op.key, op.key)
+			continue
+		}
+		reads++
+		if _, found := c.Get(ctx, op.key); found {
+			hits++
+		} else {
+			c.Set(ctx, op.key, op.key)
+		}
+	}
+	return float64(hits) / float64(reads)
+}
+
+// BenchmarkPolicies_HitRatio сравнивает политики по доле попаданий (метрика hit%).
+//
+//	go test ./internal/cache -run=^$ -bench=HitRatio
+func BenchmarkPolicies_HitRatio(b *testing.B) {
+	for traceName, trace := range benchTraces() {
+		for _, policyName := range allPolicies {
+			b.Run(traceName+"/"+policyName, func(b *testing.B) {
+				var ratio float64
+				for i := 0; i < b.N; i++ {
+					c, err := New[string, any](policyName, benchCapacity, withoutMetrics())
+					if err != nil {
+						b.Fatal(err)
+					}
+					ratio = replay(c, trace)
+				}
+				b.ReportMetric(ratio*100, "hit%")
+			})
+		}
+	}
+}
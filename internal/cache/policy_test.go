@@ -0,0 +1,162 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// allPolicies - политики, которые проверяются общими тестами.
+var allPolicies = []string{PolicyLRU, PolicyLFU, PolicyARC, PolicyTinyLFU}
+
+func newPolicyCache(t *testing.T, policyName string, capacity int, opts ...Option) Cache[string, any] {
+	t.Helper()
+	c, err := New[string, any](policyName, capacity, opts...)
+	assert.NoError(t, err)
+	return c
+}
+
+func TestNew_UnknownPolicy(t *testing.T) {
+	_, err := New[string, any]("fifo", 10)
+	assert.Error(t, err)
+}
+
+func TestPolicies_SetGetDelete(t *testing.T) {
+	for _, policyName := range allPolicies {
+		t.Run(policyName, func(t *testing.T) {
+			c := newPolicyCache(t, policyName, 2)
+			assertions := assert.New(t)
+			ctx := context.Background()
+
+			c.Set(ctx, "key1", "value1")
+			c.Set(ctx, "key1", "value_new")
+			val, found := c.Get(ctx, "key1")
+			assertions.True(found)
+			assertions.Equal("value_new", val)
+			assertions.Equal(1, c.Len())
+
+			assertions.True(c.Delete(ctx, "key1"))
+			_, found = c.Get(ctx, "key1")
+			assertions.False(found)
+
+			c.Set(ctx, "key2", "value2")
+			c.Purge(ctx)
+			assertions.Equal(0, c.Len())
+			assertions.Empty(c.Keys())
+		})
+	}
+}
+
+func TestPolicies_TTL(t *testing.T) {
+	for _, policyName := range allPolicies {
+		t.Run(policyName, func(t *testing.T) {
+			clock := newFakeClock()
+			c := newPolicyCache(t, policyName, 2, WithTTL(time.Minute), withClock(clock))
+			ctx := context.Background()
+
+			c.Set(ctx, "key1", "value1")
+			clock.Advance(time.Minute)
+
+			_, found := c.Get(ctx, "key1")
+			assert.False(t, found)
+			assert.Equal(t, 0, c.Len())
+		})
+	}
+}
+
+// TestPolicies_RandomOperations проверяет согласованность структур политик
+// на случайной последовательности операций.
+func TestPolicies_RandomOperations(t *testing.T) {
+	const capacity = 10
+
+	for _, policyName := range allPolicies {
+		t.Run(policyName, func(t *testing.T) {
+			c := newPolicyCache(t, policyName, capacity, WithMaxBytes(40, lenSizer))
+			local := c.(*localCache[string, any])
+			r := rand.New(rand.NewPCG(1, 2))
+			ctx := context.Background()
+
+			for i := 0; i < 5000; i++ {
+				key := fmt.Sprintf("key%d", r.IntN(40))
+				switch op := r.IntN(10); {
+				case op < 5:
+					c.Get(ctx, key)
+				case op < 9:
+					c.Set(ctx, key, string(make([]byte, 1+r.IntN(8))))
+				default:
+					c.Delete(ctx, key)
+				}
+
+				keys := c.Keys()
+				assert.LessOrEqual(t, c.Len(), capacity)
+				assert.Len(t, keys, c.Len())
+				assert.LessOrEqual(t, local.cost, int64(40))
+
+				seen := make(map[string]bool, len(keys))
+				for _, k := range keys {
+					assert.False(t, seen[k], "ключ %s встречается дважды", k)
+					seen[k] = true
+					_, ok := local.items[k]
+					assert.True(t, ok, "ключ %s есть в политике, но не в кэше", k)
+				}
+				if t.Failed() {
+					t.Fatalf("нарушена согласованность на шаге %d", i)
+				}
+			}
+		})
+	}
+}
+
+// TestPolicies_ScanResistance проверяет, что поток однократных записей
+// (консьюмер) не вытесняет горячие записи у частотных политик.
+func TestPolicies_ScanResistance(t *testing.T) {
+	const (
+		capacity = 100
+		hotKeys  = 10
+	)
+
+	testCases := map[string]bool{
+		PolicyLRU:     false, // LRU вымывается потоком записей
+		PolicyLFU:     true,
+		PolicyARC:     true,
+		PolicyTinyLFU: true,
+	}
+
+	for policyName, resistant := range testCases {
+		t.Run(policyName, func(t *testing.T) {
+			c := newPolicyCache(t, policyName, capacity)
+			ctx := context.Background()
+
+			// Горячие заказы: прочитаны (через кэш) несколько раз
+			for round := 0; round < 5; round++ {
+				for i := 0; i < hotKeys; i++ {
+					key := fmt.Sprintf("hot%d", i)
+					if _, found := c.Get(ctx, key); !found {
+						c.Set(ctx, key, key)
+					}
+				}
+			}
+
+			// Поток новых заказов, которые больше никто не читает
+			for i := 0; i < 10*capacity; i++ {
+				c.Set(ctx, fmt.Sprintf("cold%d", i), "cold")
+			}
+
+			retained := 0
+			for i := 0; i < hotKeys; i++ {
+				if _, found := c.Get(ctx, fmt.Sprintf("hot%d", i)); found {
+					retained++
+				}
+			}
+			if resistant {
+				assert.Equal(t, hotKeys, retained)
+			} else {
+				assert.Zero(t, retained)
+			}
+		})
+	}
+}
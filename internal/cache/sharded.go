@@ -0,0 +1,114 @@
+package cache
+
+import (
+	"context"
+	"hash/maphash"
+	"time"
+)
+
+// WithShards делит кэш на n независимых шардов, каждый со своим мьютексом.
+// Ключ закрепляется за шардом по хэшу; емкость и бюджет памяти делятся между
+// шардами поровну, поэтому порядок вытеснения соблюдается внутри шарда, а не
+// глобально. Число шардов не превышает емкость.
+func WithShards(n int) Option {
+	return func(o *options) {
+		o.shards = n
+	}
+}
+
+// newCache создает кэш с политикой newPolicy: один localCache или,
+// с опцией WithShards, шардированный кэш из нескольких.
+func newCache[K comparable, V any](capacity int, newPolicy func(capacity int) policy[K, V], opts ...Option) Cache[K, V] {
+	o := buildOptions(opts)
+
+	n := min(o.shards, capacity)
+	if n <= 1 {
+		return newLocalCacheWithOptions(capacity, newPolicy(capacity), o)
+	}
+
+	c := &shardedCache[K, V]{
+		seed:   maphash.MakeSeed(),
+		shards: make([]*localCache[K, V], n),
+	}
+
+	shardOpts := o
+	shardOpts.maxBytes = o.maxBytes / int64(n)
+	if o.maxBytes > 0 && shardOpts.maxBytes == 0 {
+		shardOpts.maxBytes = 1
+	}
+	for i := range c.shards {
+		// Остаток емкости распределяется по первым шардам
+		shardCapacity := capacity / n
+		if i < capacity%n {
+			shardCapacity++
+		}
+		c.shards[i] = newLocalCacheWithOptions(shardCapacity, newPolicy(shardCapacity), shardOpts)
+	}
+	return c
+}
+
+// shardedCache распределяет ключи по независимым шардам, чтобы обращения
+// к разным ключам не конкурировали за один мьютекс. Метрики размера шарды
+// обновляют приращениями, поэтому они показывают суммарный размер кэша.
+type shardedCache[K comparable, V any] struct {
+	seed   maphash.Seed
+	shards []*localCache[K, V]
+}
+
+// shard возвращает шард, за которым закреплен ключ.
+func (c *shardedCache[K, V]) shard(key K) *localCache[K, V] {
+	return c.shards[maphash.Comparable(c.seed, key)%uint64(len(c.shards))]
+}
+
+func (c *shardedCache[K, V]) Set(ctx context.Context, key K, value V) {
+	c.shard(key).Set(ctx, key, value)
+}
+
+func (c *shardedCache[K, V]) SetWithTTL(ctx context.Context, key K, value V, ttl time.Duration) {
+	c.shard(key).SetWithTTL(ctx, key, value, ttl)
+}
+
+func (c *shardedCache[K, V]) setStoredAt(ctx context.Context, key K, value V, ttl time.Duration, storedAt time.Time) {
+	c.shard(key).setStoredAt(ctx, key, value, ttl, storedAt)
+}
+
+func (c *shardedCache[K, V]) Get(ctx context.Context, key K) (V, bool) {
+	return c.shard(key).Get(ctx, key)
+}
+
+func (c *shardedCache[K, V]) Peek(key K) (Entry[V], bool) {
+	return c.shard(key).Peek(key)
+}
+
+func (c *shardedCache[K, V]) Delete(ctx context.Context, key K) bool {
+	return c.shard(key).Delete(ctx, key)
+}
+
+func (c *shardedCache[K, V]) Purge(ctx context.Context) {
+	for _, shard := range c.shards {
+		shard.Purge(ctx)
+	}
+}
+
+func (c *shardedCache[K, V]) Len() int {
+	n := 0
+	for _, shard := range c.shards {
+		n += shard.Len()
+	}
+	return n
+}
+
+// Keys возвращает ключи шардов подряд; порядок ценности соблюдается внутри шарда.
+func (c *shardedCache[K, V]) Keys() []K {
+	var keys []K
+	for _, shard := range c.shards {
+		keys = append(keys, shard.Keys()...)
+	}
+	return keys
+}
+
+func (c *shardedCache[K, V]) Close() {
+	for _, shard := range c.shards {
+		shard.Close()
+	}
+}
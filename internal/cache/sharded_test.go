@@ -0,0 +1,130 @@
+package cache
+
+import (
+	"L0_project/internal/metrics"
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShardedCache_Basic(t *testing.T) {
+	c := NewLRUCache[string, any](100, WithShards(4))
+	defer c.Close()
+	assertions := assert.New(t)
+	ctx := context.Background()
+
+	sharded, ok := c.(*shardedCache[string, any])
+	assertions.True(ok)
+	assertions.Len(sharded.shards, 4)
+
+	for i := 0; i < 50; i++ {
+		c.Set(ctx, fmt.Sprintf("key%d", i), i)
+	}
+	assertions.Equal(50, c.Len())
+	assertions.Len(c.Keys(), 50)
+
+	val, found := c.Get(ctx, "key7")
+	assertions.True(found)
+	assertions.Equal(7, val)
+
+	assertions.True(c.Delete(ctx, "key7"))
+	_, found = c.Get(ctx, "key7")
+	assertions.False(found)
+
+	c.Purge(ctx)
+	assertions.Equal(0, c.Len())
+}
+
+func TestShardedCache_CapacitySplit(t *testing.T) {
+	c := NewLRUCache[string, any](10, WithShards(3)).(*shardedCache[string, any])
+	ctx := context.Background()
+
+	total := 0
+	for _, shard := range c.shards {
+		total += shard.capacity
+	}
+	assert.Equal(t, 10, total)
+
+	// Емкость не превышается, сколько бы ключей ни записали
+	for i := 0; i < 1000; i++ {
+		c.Set(ctx, fmt.Sprintf("key%d", i), i)
+	}
+	assert.LessOrEqual(t, c.Len(), 10)
+}
+
+func TestShardedCache_ShardsLimitedByCapacity(t *testing.T) {
+	c := NewLRUCache[string, any](2, WithShards(16)).(*shardedCache[string, any])
+	assert.Len(t, c.shards, 2)
+
+	_, single := NewLRUCache[string, any](1, WithShards(16)).(*localCache[string, any])
+	assert.True(t, single)
+}
+
+func TestShardedCache_AggregatedSizeMetric(t *testing.T) {
+	ctx := context.Background()
+	before := testutil.ToFloat64(metrics.CacheSize)
+
+	c := NewLRUCache[string, any](100, WithShards(8))
+	for i := 0; i < 40; i++ {
+		c.Set(ctx, fmt.Sprintf("key%d", i), i)
+	}
+	assert.Equal(t, 40.0, testutil.ToFloat64(metrics.CacheSize)-before)
+
+	c.Delete(ctx, "key1")
+	assert.Equal(t, 39.0, testutil.ToFloat64(metrics.CacheSize)-before)
+
+	c.Purge(ctx)
+	assert.Equal(t, 0.0, testutil.ToFloat64(metrics.CacheSize)-before)
+}
+
+// BenchmarkCache_Parallel сравнивает один кэш с глобальным мьютексом
+// и шардированный кэш при параллельных обращениях (90% чтений).
+//
+//	go test ./internal/cache -run=^$ -bench=Parallel -cpu=1,4,16
+func BenchmarkCache_Parallel(b *testing.B) {
+	const (
+		capacity = 10_000
+		keySpace = 20_000
+	)
+
+	keys := make([]string, keySpace)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("order%d", i)
+	}
+
+	variants := []struct {
+		name string
+		opts []Option
+	}{
+		{name: "single", opts: nil},
+		{name: "sharded-16", opts: []Option{WithShards(16)}},
+		{name: "sharded-64", opts: []Option{WithShards(64)}},
+	}
+
+	for _, v := range variants {
+		b.Run(v.name, func(b *testing.B) {
+			c := NewLRUCache[string, any](capacity, append(v.opts, withoutMetrics())...)
+			ctx := context.Background()
+			for i := 0; i < capacity; i++ {
+				c.Set(ctx, keys[i], i)
+			}
+
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				r := rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+				for pb.Next() {
+					key := keys[r.IntN(keySpace)]
+					if r.IntN(10) == 0 {
+						c.Set(ctx, key, key)
+					} else {
+						c.Get(ctx, key)
+					}
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"L0_project/internal/model"
+	"encoding/json"
+	"unsafe"
+)
+
+// Sizer оценивает стоимость значения в байтах для кэша с ограничением по памяти.
+type Sizer func(value interface{}) int64
+
+// JSONSizer оценивает стоимость значения по размеру его JSON-представления.
+// Подходит для любых значений, но сериализует значение при каждой записи.
+func JSONSizer(value interface{}) int64 {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return 0
+	}
+	return int64(len(data))
+}
+
+// OrderSizer приближенно оценивает память, занимаемую заказом: размеры структур
+// плюс длины строк, в том числе у каждого товара. Для значений другого типа
+// используется JSONSizer.
+func OrderSizer(value interface{}) int64 {
+	var order *model.Order
+	switch v := value.(type) {
+	case *model.Order:
+		order = v
+	case model.Order:
+		order = &v
+	default:
+		return JSONSizer(value)
+	}
+	if order == nil {
+		return 0
+	}
+
+	size := int64(unsafe.Sizeof(model.Order{}))
+	size += strLen(order.OrderUID, order.TrackNumber, order.Entry, order.Locale, order.InternalSignature,
+		order.CustomerID, order.DeliveryService, order.Shardkey, order.OofShard)
+
+	d := order.Delivery
+	size += strLen(d.Name, d.Phone, d.Zip, d.City, d.Address, d.Region, d.Email)
+
+	p := order.Payment
+	size += strLen(p.Transaction, p.RequestID, p.Currency, p.Provider, p.Bank)
+
+	size += int64(cap(order.Items)) * int64(unsafe.Sizeof(model.Item{}))
+	for _, item := range order.Items {
+		size += strLen(item.TrackNumber, item.Rid, item.Name, item.Size, item.Brand, item.OrderUID)
+	}
+	return size
+}
+
+// strLen возвращает суммарную длину строк.
+func strLen(values ...string) int64 {
+	var n int64
+	for _, v := range values {
+		n += int64(len(v))
+	}
+	return n
+}
@@ -0,0 +1,198 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// snapshotVersion - версия формата снимка. Увеличивается при несовместимых
+// изменениях формата записи или типа значений; снимок другой версии игнорируется.
+const snapshotVersion = 1
+
+var (
+	// ErrSnapshotVersion возвращается LoadSnapshot для снимка несовместимой версии.
+	ErrSnapshotVersion = errors.New("несовместимая версия снимка кэша")
+	// ErrSnapshotCorrupt возвращается LoadSnapshot, если снимок поврежден
+	// (не разбирается или не сходится контрольная сумма).
+	ErrSnapshotCorrupt = errors.New("снимок кэша поврежден")
+	// ErrSnapshotStale возвращается LoadSnapshot для снимка старше допустимого возраста.
+	ErrSnapshotStale = errors.New("снимок кэша устарел")
+)
+
+// snapshotFile - содержимое файла снимка. Контрольная сумма считается по байтам
+// поля entries, поэтому проверяется до разбора записей.
+type snapshotFile struct {
+	Version   int             `json:"version"`
+	CreatedAt time.Time       `json:"created_at"`
+	Checksum  string          `json:"checksum"` // SHA-256 поля entries (hex)
+	Entries   json.RawMessage `json:"entries"`
+}
+
+// snapshotEntry - запись кэша в снимке.
+type snapshotEntry[K comparable, V any] struct {
+	Key       K         `json:"key"`
+	Value     V         `json:"value"`
+	ExpiresAt time.Time `json:"expires_at"`          // Нулевое значение - бессрочно
+	StoredAt  time.Time `json:"stored_at,omitempty"` // Момент записи значения в кэш
+}
+
+// storedAtSetter - кэш, принимающий запись с исходным моментом записи. Его
+// реализуют локальные кэши пакета; в остальные записи снимка загружаются как
+// только что записанные.
+type storedAtSetter[K comparable, V any] interface {
+	setStoredAt(ctx context.Context, key K, value V, ttl time.Duration, storedAt time.Time)
+}
+
+// SaveSnapshot сохраняет содержимое кэша в файл: ключи, значения, сроки жизни
+// и порядок ценности записей (как в Keys). Файл записывается атомарно - через
+// временный файл в том же каталоге, поэтому прерванная запись не портит
+// предыдущий снимок. Ключи и значения должны сериализоваться в JSON.
+func SaveSnapshot[K comparable, V any](path string, c Cache[K, V]) (int, error) {
+	keys := c.Keys()
+	entries := make([]snapshotEntry[K, V], 0, len(keys))
+	for _, key := range keys {
+		entry, found := c.Peek(key)
+		if !found {
+			continue // Истекла или вытеснена после Keys
+		}
+		entries = append(entries, snapshotEntry[K, V]{Key: key, Value: entry.Value, ExpiresAt: entry.ExpiresAt, StoredAt: entry.StoredAt})
+	}
+
+	rawEntries, err := json.Marshal(entries)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка сериализации записей кэша: %w", err)
+	}
+	checksum := sha256.Sum256(rawEntries)
+
+	data, err := json.Marshal(snapshotFile{
+		Version:   snapshotVersion,
+		CreatedAt: time.Now(),
+		Checksum:  hex.EncodeToString(checksum[:]),
+		Entries:   rawEntries,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("ошибка сериализации снимка кэша: %w", err)
+	}
+
+	if err := writeFileAtomic(path, data); err != nil {
+		return 0, fmt.Errorf("ошибка записи снимка кэша: %w", err)
+	}
+	return len(entries), nil
+}
+
+// LoadSnapshot загружает в кэш записи из снимка и возвращает их количество.
+// Записи добавляются от наименее ценных к наиболее ценным, поэтому порядок
+// вытеснения восстанавливается (для частотных политик - только порядок, но не
+// накопленные частоты). Истекшие записи пропускаются, остальные сохраняют
+// оставшийся срок жизни и момент записи, от которого отсчитывается их возраст
+// (например, мягкий срок жизни Loader).
+//
+// Записи снимка не сверяются с источником: изменения, сделанные, пока сервис
+// был остановлен, в них не попадут. Поэтому снимок старше maxAge отклоняется
+// (0 - без ограничения), а загруженные заказы можно сверить с БД через RevalidateOrders.
+//
+// Снимок используется один раз: после чтения файл удаляется, чтобы после
+// аварийной остановки не загрузить устаревшие данные. Отсутствие файла - не
+// ошибка (возвращается 0); для поврежденного, устаревшего снимка или снимка
+// другой версии возвращается ErrSnapshotCorrupt, ErrSnapshotStale или
+// ErrSnapshotVersion, кэш не меняется.
+func LoadSnapshot[K comparable, V any](ctx context.Context, path string, c Cache[K, V], maxAge time.Duration) (int, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("ошибка чтения снимка кэша: %w", err)
+	}
+	if err := os.Remove(path); err != nil {
+		return 0, fmt.Errorf("ошибка удаления прочитанного снимка кэша: %w", err)
+	}
+
+	now := time.Now()
+	entries, err := decodeSnapshot[K, V](data, now, maxAge)
+	if err != nil {
+		return 0, err
+	}
+
+	loaded := 0
+	for _, entry := range slices.Backward(entries) {
+		var ttl time.Duration
+		if !entry.ExpiresAt.IsZero() {
+			ttl = entry.ExpiresAt.Sub(now)
+			if ttl <= 0 {
+				continue
+			}
+		}
+		if setter, ok := c.(storedAtSetter[K, V]); ok {
+			setter.setStoredAt(ctx, entry.Key, entry.Value, ttl, entry.StoredAt)
+		} else {
+			c.SetWithTTL(ctx, entry.Key, entry.Value, ttl)
+		}
+		loaded++
+	}
+	return loaded, nil
+}
+
+// decodeSnapshot проверяет версию, возраст и контрольную сумму снимка и разбирает записи.
+func decodeSnapshot[K comparable, V any](data []byte, now time.Time, maxAge time.Duration) ([]snapshotEntry[K, V], error) {
+	var file snapshotFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSnapshotCorrupt, err)
+	}
+	if file.Version != snapshotVersion {
+		return nil, fmt.Errorf("%w: %d (ожидается %d)", ErrSnapshotVersion, file.Version, snapshotVersion)
+	}
+	if age := now.Sub(file.CreatedAt); maxAge > 0 && age > maxAge {
+		return nil, fmt.Errorf("%w: создан %s назад (допустимо %s)", ErrSnapshotStale, age.Round(time.Second), maxAge)
+	}
+
+	checksum := sha256.Sum256(file.Entries)
+	if hex.EncodeToString(checksum[:]) != file.Checksum {
+		return nil, fmt.Errorf("%w: не совпадает контрольная сумма", ErrSnapshotCorrupt)
+	}
+
+	var entries []snapshotEntry[K, V]
+	if err := json.Unmarshal(file.Entries, &entries); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSnapshotCorrupt, err)
+	}
+	return entries, nil
+}
+
+// writeFileAtomic записывает данные во временный файл и переименовывает его в path.
+func writeFileAtomic(path string, data []byte) (err error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
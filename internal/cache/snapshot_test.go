@@ -0,0 +1,163 @@
+package cache
+
+import (
+	"L0_project/internal/model"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshot_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "cache.snapshot")
+
+	src := NewLRUCache[string, *model.Order](10, WithTTL(time.Hour))
+	for _, uid := range []string{"uid-1", "uid-2", "uid-3"} {
+		src.Set(ctx, uid, &model.Order{OrderUID: uid, Items: []model.Item{{Name: "<item & co>"}}})
+	}
+	src.SetWithTTL(ctx, "forever", &model.Order{OrderUID: "forever"}, 0)
+	src.Get(ctx, "uid-1") // uid-1 становится самым свежим
+
+	saved, err := SaveSnapshot(path, src)
+	require.NoError(t, err)
+	assert.Equal(t, 4, saved)
+
+	dst := NewLRUCache[string, *model.Order](10, WithTTL(time.Hour))
+	loaded, err := LoadSnapshot(ctx, path, dst, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 4, loaded)
+
+	// Порядок вытеснения и значения восстановлены
+	assert.Equal(t, src.Keys(), dst.Keys())
+	entry, found := dst.Peek("uid-2")
+	require.True(t, found)
+	assert.Equal(t, "<item & co>", entry.Value.Items[0].Name)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), entry.ExpiresAt, time.Minute)
+
+	entry, found = dst.Peek("forever")
+	require.True(t, found)
+	assert.True(t, entry.ExpiresAt.IsZero(), "бессрочная запись должна остаться бессрочной")
+
+	// Снимок используется один раз
+	_, err = os.Stat(path)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestSnapshot_SkipsExpiredEntries(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "cache.snapshot")
+
+	src := NewLRUCache[string, string](10)
+	src.SetWithTTL(ctx, "short", "value", 50*time.Millisecond)
+	src.SetWithTTL(ctx, "long", "value", time.Hour)
+	_, err := SaveSnapshot(path, src)
+	require.NoError(t, err)
+
+	time.Sleep(100 * time.Millisecond)
+
+	dst := NewLRUCache[string, string](10)
+	loaded, err := LoadSnapshot(ctx, path, dst, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 1, loaded)
+	assert.Equal(t, []string{"long"}, dst.Keys())
+}
+
+func TestSnapshot_MissingFile(t *testing.T) {
+	dst := NewLRUCache[string, string](10)
+	loaded, err := LoadSnapshot(context.Background(), filepath.Join(t.TempDir(), "absent"), dst, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, loaded)
+}
+
+func TestSnapshot_RejectsBrokenFiles(t *testing.T) {
+	ctx := context.Background()
+
+	src := NewLRUCache[string, string](10)
+	src.Set(ctx, "key", "value")
+	valid := filepath.Join(t.TempDir(), "valid.snapshot")
+	_, err := SaveSnapshot(valid, src)
+	require.NoError(t, err)
+	data, err := os.ReadFile(valid)
+	require.NoError(t, err)
+
+	testCases := map[string]struct {
+		data    string
+		wantErr error
+	}{
+		"Not JSON":         {data: "not a snapshot", wantErr: ErrSnapshotCorrupt},
+		"Truncated":        {data: string(data[:len(data)/2]), wantErr: ErrSnapshotCorrupt},
+		"Checksum":         {data: strings.Replace(string(data), `"value"`, `"other"`, 1), wantErr: ErrSnapshotCorrupt},
+		"Newer version":    {data: strings.Replace(string(data), `"version":1`, `"version":2`, 1), wantErr: ErrSnapshotVersion},
+		"Wrong value type": {data: `{"version":1,"checksum":"` + checksumOf(`[{"key":"k","value":1}]`) + `","entries":[{"key":"k","value":1}]}`, wantErr: ErrSnapshotCorrupt},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "cache.snapshot")
+			require.NoError(t, os.WriteFile(path, []byte(tc.data), 0o644))
+
+			dst := NewLRUCache[string, string](10)
+			loaded, err := LoadSnapshot(ctx, path, dst, 0)
+			assert.ErrorIs(t, err, tc.wantErr)
+			assert.Equal(t, 0, loaded)
+			assert.Equal(t, 0, dst.Len(), "кэш не должен меняться")
+		})
+	}
+}
+
+func TestSnapshot_RejectsStale(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "cache.snapshot")
+
+	src := NewLRUCache[string, string](10)
+	src.SetWithTTL(ctx, "key", "value", 0)
+	_, err := SaveSnapshot(path, src)
+	require.NoError(t, err)
+
+	time.Sleep(50 * time.Millisecond)
+
+	// Бессрочная запись не загружается из снимка старше допустимого возраста
+	dst := NewLRUCache[string, string](10)
+	loaded, err := LoadSnapshot(ctx, path, dst, 10*time.Millisecond)
+	assert.ErrorIs(t, err, ErrSnapshotStale)
+	assert.Equal(t, 0, loaded)
+	assert.Equal(t, 0, dst.Len())
+}
+
+func TestSnapshot_RestoresStoredAt(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "cache.snapshot")
+	clock := newFakeClock()
+
+	src := NewLRUCache[string, string](10, withClock(clock))
+	src.Set(ctx, "key", "value")
+
+	// Возраст записи отсчитывается от исходной записи, а не от загрузки снимка
+	for _, dst := range []Cache[string, string]{
+		NewLRUCache[string, string](10),
+		newCache(10, newLRUPolicy[string, string], WithShards(2)),
+	} {
+		_, err := SaveSnapshot(path, src)
+		require.NoError(t, err)
+		_, err = LoadSnapshot(ctx, path, dst, 0)
+		require.NoError(t, err)
+
+		entry, found := dst.Peek("key")
+		require.True(t, found)
+		assert.True(t, clock.Now().Equal(entry.StoredAt), "StoredAt: %v", entry.StoredAt)
+	}
+}
+
+// checksumOf считает контрольную сумму записей так же, как SaveSnapshot.
+func checksumOf(entries string) string {
+	sum := sha256.Sum256([]byte(entries))
+	return hex.EncodeToString(sum[:])
+}
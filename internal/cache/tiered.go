@@ -0,0 +1,263 @@
+package cache
+
+import (
+	"L0_project/internal/metrics"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// defaultRemoteRetryAfter - на сколько общий уровень отключается после ошибки.
+// Пока он отключен, кэш работает только с локальным уровнем и не ждет таймаутов
+// на каждом запросе.
+const defaultRemoteRetryAfter = 5 * time.Second
+
+// remotePurgeBatch - сколько ключей удаляется за одну команду при Purge.
+const remotePurgeBatch = 500
+
+// TieredOption настраивает двухуровневый кэш при создании.
+type TieredOption func(*tieredOptions)
+
+type tieredOptions struct {
+	ttl     time.Duration // Срок жизни записей в общем уровне (0 - бессрочно)
+	timeout time.Duration // Таймаут одной операции с общим уровнем
+	prefix  string        // Префикс ключей в общем уровне
+}
+
+// WithRemoteTTL задает срок жизни записей в общем уровне. Запись с собственным
+// более коротким сроком (SetWithTTL) живет в общем уровне не дольше него.
+func WithRemoteTTL(ttl time.Duration) TieredOption {
+	return func(o *tieredOptions) {
+		o.ttl = ttl
+	}
+}
+
+// WithRemoteTimeout ограничивает время одной операции с общим уровнем.
+func WithRemoteTimeout(timeout time.Duration) TieredOption {
+	return func(o *tieredOptions) {
+		o.timeout = timeout
+	}
+}
+
+// WithRemoteKeyPrefix задает префикс ключей в общем уровне, чтобы несколько
+// кэшей могли использовать один сервер.
+func WithRemoteKeyPrefix(prefix string) TieredOption {
+	return func(o *tieredOptions) {
+		o.prefix = prefix
+	}
+}
+
+// tieredCache - двухуровневый кэш: локальный кэш процесса перед общим для всех
+// реплик кэшем с протоколом Redis. Значения в общем уровне хранятся в JSON.
+//
+// Чтение идет сначала в локальный уровень, при промахе - в общий; найденное там
+// значение копируется в локальный. Запись идет в оба уровня (write-through).
+// Ошибки общего уровня не возвращаются вызывающему: операция выполняется только
+// с локальным уровнем, а общий отключается на retryAfter.
+//
+// Len, Keys и Peek относятся только к локальному уровню.
+type tieredCache[V any] struct {
+	local  Cache[string, V]
+	remote redis.UniversalClient
+	opts   tieredOptions
+	tracer trace.Tracer
+
+	retryAfter time.Duration
+	downUntil  atomic.Int64 // UnixNano, до которого общий уровень не используется
+	now        func() time.Time
+}
+
+// NewTieredCache создает двухуровневый кэш поверх локального кэша и клиента
+// Redis. Клиент принадлежит вызывающему: Close кэша его не закрывает.
+func NewTieredCache[V any](local Cache[string, V], remote redis.UniversalClient, opts ...TieredOption) Cache[string, V] {
+	o := tieredOptions{timeout: 100 * time.Millisecond}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return &tieredCache[V]{
+		local:      local,
+		remote:     remote,
+		opts:       o,
+		tracer:     otel.Tracer("tiered-cache"),
+		retryAfter: defaultRemoteRetryAfter,
+		now:        time.Now,
+	}
+}
+
+func (c *tieredCache[V]) Set(ctx context.Context, key string, value V) {
+	c.local.Set(ctx, key, value)
+	c.setRemote(ctx, key, value, c.opts.ttl)
+}
+
+func (c *tieredCache[V]) SetWithTTL(ctx context.Context, key string, value V, ttl time.Duration) {
+	c.local.SetWithTTL(ctx, key, value, ttl)
+
+	remoteTTL := c.opts.ttl
+	if ttl > 0 && (remoteTTL <= 0 || ttl < remoteTTL) {
+		remoteTTL = ttl
+	}
+	c.setRemote(ctx, key, value, remoteTTL)
+}
+
+func (c *tieredCache[V]) Get(ctx context.Context, key string) (V, bool) {
+	if value, found := c.local.Get(ctx, key); found {
+		return value, true
+	}
+
+	var zero V
+	if !c.remoteAvailable() {
+		return zero, false
+	}
+
+	// Создаем span для трассировки
+	ctx, span := c.tracer.Start(ctx, "Cache.RemoteGet")
+	defer span.End()
+
+	opCtx, cancel := context.WithTimeout(ctx, c.opts.timeout)
+	defer cancel()
+
+	data, err := c.remote.Get(opCtx, c.opts.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return zero, false
+	}
+	if err != nil {
+		c.remoteFailed("get", err)
+		return zero, false
+	}
+
+	var value V
+	if err := json.Unmarshal(data, &value); err != nil {
+		// Значение другого формата (например, от другой версии сервиса) считается промахом
+		log.Printf("Общий кэш: не удалось разобрать значение ключа %s: %v", key, err)
+		metrics.CacheRemoteErrors.WithLabelValues("decode").Inc()
+		return zero, false
+	}
+
+	metrics.CacheRemoteHits.Inc()
+	c.local.Set(ctx, key, value)
+	return value, true
+}
+
+func (c *tieredCache[V]) Peek(key string) (Entry[V], bool) {
+	return c.local.Peek(key)
+}
+
+func (c *tieredCache[V]) Delete(ctx context.Context, key string) bool {
+	deleted := c.local.Delete(ctx, key)
+	if !c.remoteAvailable() {
+		return deleted
+	}
+
+	// Создаем span для трассировки
+	ctx, span := c.tracer.Start(ctx, "Cache.RemoteDelete")
+	defer span.End()
+
+	opCtx, cancel := context.WithTimeout(ctx, c.opts.timeout)
+	defer cancel()
+
+	n, err := c.remote.Del(opCtx, c.opts.prefix+key).Result()
+	if err != nil {
+		c.remoteFailed("delete", err)
+		return deleted
+	}
+	return deleted || n > 0
+}
+
+// Purge очищает оба уровня. Из общего уровня удаляются все ключи с префиксом
+// кэша, поэтому операция затрагивает все реплики; таймаут одной операции к ней
+// не применяется.
+func (c *tieredCache[V]) Purge(ctx context.Context) {
+	c.local.Purge(ctx)
+	if !c.remoteAvailable() {
+		return
+	}
+
+	// Создаем span для трассировки
+	ctx, span := c.tracer.Start(ctx, "Cache.RemotePurge")
+	defer span.End()
+
+	batch := make([]string, 0, remotePurgeBatch)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		err := c.remote.Del(ctx, batch...).Err()
+		batch = batch[:0]
+		return err
+	}
+
+	iter := c.remote.Scan(ctx, 0, c.opts.prefix+"*", remotePurgeBatch).Iterator()
+	for iter.Next(ctx) {
+		batch = append(batch, iter.Val())
+		if len(batch) == remotePurgeBatch {
+			if err := flush(); err != nil {
+				c.remoteFailed("purge", err)
+				return
+			}
+		}
+	}
+	if err := iter.Err(); err != nil {
+		c.remoteFailed("purge", err)
+		return
+	}
+	if err := flush(); err != nil {
+		c.remoteFailed("purge", err)
+	}
+}
+
+func (c *tieredCache[V]) Len() int       { return c.local.Len() }
+func (c *tieredCache[V]) Keys() []string { return c.local.Keys() }
+
+// Close останавливает локальный уровень. Клиент общего уровня закрывает владелец.
+func (c *tieredCache[V]) Close() {
+	c.local.Close()
+}
+
+// setRemote записывает значение в общий уровень.
+func (c *tieredCache[V]) setRemote(ctx context.Context, key string, value V, ttl time.Duration) {
+	if !c.remoteAvailable() {
+		return
+	}
+
+	// Создаем span для трассировки
+	ctx, span := c.tracer.Start(ctx, "Cache.RemoteSet")
+	defer span.End()
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		log.Printf("Общий кэш: не удалось сериализовать значение ключа %s: %v", key, err)
+		metrics.CacheRemoteErrors.WithLabelValues("encode").Inc()
+		return
+	}
+
+	opCtx, cancel := context.WithTimeout(ctx, c.opts.timeout)
+	defer cancel()
+
+	if err := c.remote.Set(opCtx, c.opts.prefix+key, data, max(ttl, 0)).Err(); err != nil {
+		c.remoteFailed("set", err)
+	}
+}
+
+// remoteAvailable сообщает, можно ли обращаться к общему уровню.
+func (c *tieredCache[V]) remoteAvailable() bool {
+	return c.now().UnixNano() >= c.downUntil.Load()
+}
+
+// remoteFailed учитывает ошибку общего уровня и отключает его на retryAfter.
+func (c *tieredCache[V]) remoteFailed(operation string, err error) {
+	metrics.CacheRemoteErrors.WithLabelValues(operation).Inc()
+
+	until := c.now().Add(c.retryAfter).UnixNano()
+	if previous := c.downUntil.Swap(until); previous < c.now().UnixNano() {
+		// Логируем только переход в деградированный режим, а не каждую ошибку
+		log.Printf("Общий кэш недоступен (%s: %v). Работаем только с локальным кэшем в течение %s.", operation, err, c.retryAfter)
+	}
+}
@@ -0,0 +1,174 @@
+package cache
+
+import (
+	"L0_project/internal/metrics"
+	"L0_project/internal/model"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestTieredCache создает двухуровневый кэш поверх miniredis.
+func newTestTieredCache(t *testing.T, server *miniredis.Miniredis, opts ...TieredOption) *tieredCache[*model.Order] {
+	t.Helper()
+	client := redis.NewClient(&redis.Options{Addr: server.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { _ = client.Close() })
+
+	opts = append([]TieredOption{WithRemoteKeyPrefix("test:")}, opts...)
+	return NewTieredCache(NewLRUCache[string, *model.Order](10), client, opts...).(*tieredCache[*model.Order])
+}
+
+func TestTieredCache_SharedBetweenReplicas(t *testing.T) {
+	server := miniredis.RunT(t)
+	replicaA := newTestTieredCache(t, server, WithRemoteTTL(time.Hour))
+	replicaB := newTestTieredCache(t, server, WithRemoteTTL(time.Hour))
+	ctx := context.Background()
+
+	// Консьюмер реплики A записывает заказ в оба уровня
+	replicaA.Set(ctx, "uid-1", &model.Order{OrderUID: "uid-1", Items: []model.Item{{Name: "Item 1"}}})
+	assert.True(t, server.Exists("test:uid-1"))
+	assert.Equal(t, time.Hour, server.TTL("test:uid-1"))
+
+	// Реплика B находит его в общем уровне и копирует в локальный
+	hitsBefore := testutil.ToFloat64(metrics.CacheRemoteHits)
+	order, found := replicaB.Get(ctx, "uid-1")
+	require.True(t, found)
+	assert.Equal(t, "Item 1", order.Items[0].Name)
+	assert.Equal(t, hitsBefore+1, testutil.ToFloat64(metrics.CacheRemoteHits))
+
+	_, found = replicaB.local.Peek("uid-1")
+	assert.True(t, found)
+
+	// Удаление затрагивает общий уровень
+	assert.True(t, replicaB.Delete(ctx, "uid-1"))
+	assert.False(t, server.Exists("test:uid-1"))
+}
+
+func TestTieredCache_SetWithTTL_ShorterThanRemoteTTL(t *testing.T) {
+	server := miniredis.RunT(t)
+	c := newTestTieredCache(t, server, WithRemoteTTL(time.Hour))
+
+	c.SetWithTTL(context.Background(), "uid-1", &model.Order{OrderUID: "uid-1"}, time.Minute)
+	assert.Equal(t, time.Minute, server.TTL("test:uid-1"))
+}
+
+func TestTieredCache_InvalidationRemovesStaleRemoteCopy(t *testing.T) {
+	server := miniredis.RunT(t)
+	replicaA := NewLoader[string, *model.Order](newTestTieredCache(t, server))
+	replicaB := NewLoader[string, *model.Order](newTestTieredCache(t, server))
+	ctx := context.Background()
+
+	oldOrder := &model.Order{OrderUID: "uid-1", Status: model.StatusCreated, StatusVersion: 1}
+	newOrder := &model.Order{OrderUID: "uid-1", Status: model.StatusPaid, StatusVersion: 2}
+
+	// Реплика B читает заказ из БД до фиксации изменения
+	started := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _, _ = replicaB.GetOrLoad(ctx, "uid-1", func(context.Context) (*model.Order, error) {
+			close(started)
+			<-release
+			return oldOrder, nil
+		})
+	}()
+	<-started
+
+	// Реплика A сохраняет изменение и записывает заказ в оба уровня
+	require.NoError(t, replicaA.Refresh(ctx, "uid-1", func(context.Context) (*model.Order, error) {
+		return newOrder, nil
+	}))
+
+	// Загрузка B завершается позже и перезаписывает общий уровень прежним значением
+	close(release)
+	<-done
+	data, err := server.Get("test:uid-1")
+	require.NoError(t, err)
+	assert.Contains(t, data, `"status":"created"`)
+
+	// Уведомление об изменении удаляет устаревшую копию из локального и общего уровней
+	replicaB.Invalidate(ctx, "uid-1")
+	assert.False(t, server.Exists("test:uid-1"))
+
+	// Следующий запрос любой реплики читает актуальный заказ из БД
+	order, status, err := replicaB.GetOrLoad(ctx, "uid-1", func(context.Context) (*model.Order, error) {
+		return newOrder, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, StatusMiss, status)
+	assert.Equal(t, model.StatusPaid, order.Status)
+}
+
+func TestTieredCache_UndecodableRemoteValueIsMiss(t *testing.T) {
+	server := miniredis.RunT(t)
+	c := newTestTieredCache(t, server)
+	require.NoError(t, server.Set("test:uid-1", "not json"))
+
+	_, found := c.Get(context.Background(), "uid-1")
+	assert.False(t, found)
+}
+
+func TestTieredCache_Purge(t *testing.T) {
+	server := miniredis.RunT(t)
+	c := newTestTieredCache(t, server)
+	ctx := context.Background()
+	require.NoError(t, server.Set("other:key", "foreign"))
+
+	for _, uid := range []string{"uid-1", "uid-2", "uid-3"} {
+		c.Set(ctx, uid, &model.Order{OrderUID: uid})
+	}
+	c.Purge(ctx)
+
+	assert.Equal(t, 0, c.Len())
+	assert.Equal(t, []string{"other:key"}, server.Keys(), "ключи других кэшей не затрагиваются")
+}
+
+func TestTieredCache_DegradesToLocalWhenRemoteFails(t *testing.T) {
+	server := miniredis.RunT(t)
+	c := newTestTieredCache(t, server, WithRemoteTimeout(50*time.Millisecond))
+	ctx := context.Background()
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	server.SetError("LOADING Redis is loading the dataset in memory")
+	errorsBefore := testutil.ToFloat64(metrics.CacheRemoteErrors.WithLabelValues("set"))
+
+	// Запись и чтение работают через локальный уровень
+	c.Set(ctx, "uid-1", &model.Order{OrderUID: "uid-1"})
+	order, found := c.Get(ctx, "uid-1")
+	require.True(t, found)
+	assert.Equal(t, "uid-1", order.OrderUID)
+	assert.Equal(t, errorsBefore+1, testutil.ToFloat64(metrics.CacheRemoteErrors.WithLabelValues("set")))
+
+	// Пока общий уровень отключен, к нему не обращаемся
+	server.SetError("")
+	c.Set(ctx, "uid-2", &model.Order{OrderUID: "uid-2"})
+	assert.False(t, server.Exists("test:uid-2"))
+
+	// После паузы общий уровень снова используется
+	now = now.Add(defaultRemoteRetryAfter)
+	c.Set(ctx, "uid-3", &model.Order{OrderUID: "uid-3"})
+	assert.True(t, server.Exists("test:uid-3"))
+}
+
+func TestTieredCache_RemoteDown(t *testing.T) {
+	server := miniredis.RunT(t)
+	c := newTestTieredCache(t, server, WithRemoteTimeout(50*time.Millisecond))
+	ctx := context.Background()
+	server.Close()
+
+	c.Set(ctx, "uid-1", &model.Order{OrderUID: "uid-1"})
+	_, found := c.Get(ctx, "uid-1")
+	assert.True(t, found)
+
+	_, found = c.Get(ctx, "uid-2")
+	assert.False(t, found)
+	assert.True(t, c.Delete(ctx, "uid-1"))
+}
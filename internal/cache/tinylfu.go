@@ -0,0 +1,298 @@
+package cache
+
+import (
+	"container/list"
+	"hash/maphash"
+)
+
+// NewTinyLFUCache создает W-TinyLFU-кэш с заданной емкостью.
+func NewTinyLFUCache[K comparable, V any](capacity int, opts ...Option) Cache[K, V] {
+	return newCache(capacity, newTinyLFUPolicy[K, V], opts...)
+}
+
+// Сегменты W-TinyLFU.
+const (
+	tinyWindow    uint8 = iota + 1 // LRU-окно для новых записей
+	tinyProbation                  // Основная часть: испытательный сегмент
+	tinyProtected                  // Основная часть: защищенный сегмент
+)
+
+// tinyLFUPolicy реализует W-TinyLFU (Einziger, Friedman, Manes). Новые записи
+// попадают в небольшое LRU-окно (1% емкости). Вытесненная из окна запись
+// допускается в основную часть (SLRU: испытательный и защищенный сегменты),
+// только если по оценке частоты она востребованнее, чем кандидат на вытеснение
+// оттуда. Частоты оцениваются компактным count-min sketch со старением, поэтому
+// поток однократных записей не вымывает горячие записи.
+type tinyLFUPolicy[K comparable, V any] struct {
+	window, probation, protected *list.List // *cacheItem[K, V], от свежих к старым
+
+	windowCap    int
+	mainCap      int
+	protectedCap int
+
+	sketch *countMinSketch[K]
+}
+
+func newTinyLFUPolicy[K comparable, V any](capacity int) policy[K, V] {
+	windowCap := max(1, capacity/100)
+	mainCap := max(0, capacity-windowCap)
+	return &tinyLFUPolicy[K, V]{
+		window:       list.New(),
+		probation:    list.New(),
+		protected:    list.New(),
+		windowCap:    windowCap,
+		mainCap:      mainCap,
+		protectedCap: mainCap * 8 / 10,
+		sketch:       newCountMinSketch[K](capacity),
+	}
+}
+
+func (p *tinyLFUPolicy[K, V]) name() string { return PolicyTinyLFU }
+
+func (p *tinyLFUPolicy[K, V]) add(item *cacheItem[K, V]) []*cacheItem[K, V] {
+	p.sketch.increment(item.key)
+	p.push(item, tinyWindow)
+	if p.window.Len() <= p.windowCap {
+		return nil
+	}
+
+	// Окно переполнено: самая старая запись окна претендует на место в основной части
+	candidate := p.window.Remove(p.window.Back()).(*cacheItem[K, V])
+	if p.probation.Len()+p.protected.Len() < p.mainCap {
+		p.push(candidate, tinyProbation)
+		return nil
+	}
+
+	victim := p.mainVictim()
+	if victim == nil || p.sketch.estimate(candidate.key) <= p.sketch.estimate(victim.key) {
+		return []*cacheItem[K, V]{candidate}
+	}
+	p.remove(victim)
+	p.push(candidate, tinyProbation)
+	return []*cacheItem[K, V]{victim}
+}
+
+func (p *tinyLFUPolicy[K, V]) access(item *cacheItem[K, V]) {
+	p.sketch.increment(item.key)
+
+	switch item.segment {
+	case tinyWindow:
+		p.window.MoveToFront(item.element)
+	case tinyProtected:
+		p.protected.MoveToFront(item.element)
+	case tinyProbation:
+		// Повторное обращение переводит запись в защищенный сегмент
+		p.probation.Remove(item.element)
+		p.push(item, tinyProtected)
+		if p.protected.Len() > p.protectedCap {
+			demoted := p.protected.Remove(p.protected.Back()).(*cacheItem[K, V])
+			p.push(demoted, tinyProbation)
+		}
+	}
+}
+
+// miss учитывает обращение к отсутствующему ключу: если его вскоре запишут,
+// он уже будет иметь вес при допуске в основную часть.
+func (p *tinyLFUPolicy[K, V]) miss(key K) {
+	p.sketch.increment(key)
+}
+
+func (p *tinyLFUPolicy[K, V]) remove(item *cacheItem[K, V]) {
+	p.list(item.segment).Remove(item.element)
+}
+
+func (p *tinyLFUPolicy[K, V]) evict() *cacheItem[K, V] {
+	for _, l := range []*list.List{p.probation, p.window, p.protected} {
+		if element := l.Back(); element != nil {
+			return l.Remove(element).(*cacheItem[K, V])
+		}
+	}
+	return nil
+}
+
+// keys возвращает ключи защищенного, испытательного сегментов и окна.
+func (p *tinyLFUPolicy[K, V]) keys() []K {
+	keys := make([]K, 0, p.window.Len()+p.probation.Len()+p.protected.Len())
+	keys = listKeys[K, V](keys, p.protected)
+	keys = listKeys[K, V](keys, p.probation)
+	return listKeys[K, V](keys, p.window)
+}
+
+func (p *tinyLFUPolicy[K, V]) reset() {
+	p.window.Init()
+	p.probation.Init()
+	p.protected.Init()
+	p.sketch.reset()
+}
+
+// mainVictim возвращает кандидата на вытеснение из основной части.
+func (p *tinyLFUPolicy[K, V]) mainVictim() *cacheItem[K, V] {
+	if element := p.probation.Back(); element != nil {
+		return element.Value.(*cacheItem[K, V])
+	}
+	if element := p.protected.Back(); element != nil {
+		return element.Value.(*cacheItem[K, V])
+	}
+	return nil
+}
+
+// push добавляет запись в начало сегмента.
+func (p *tinyLFUPolicy[K, V]) push(item *cacheItem[K, V], segment uint8) {
+	item.segment = segment
+	item.element = p.list(segment).PushFront(item)
+}
+
+func (p *tinyLFUPolicy[K, V]) list(segment uint8) *list.List {
+	switch segment {
+	case tinyProbation:
+		return p.probation
+	case tinyProtected:
+		return p.protected
+	default:
+		return p.window
+	}
+}
+
+// sketchDepth - число строк count-min sketch (независимых хэшей).
+const sketchDepth = 4
+
+// sketchMaxCount - предел счетчика (4 бита, как в TinyLFU).
+const sketchMaxCount = 15
+
+// sketchWidthFactor - число счетчиков строки на одну запись емкости.
+const sketchWidthFactor = 4
+
+// sketchMinCapacity - минимальная емкость, под которую строится sketch.
+const sketchMinCapacity = 16
+
+// sketchMultipliers - нечетные множители для получения индексов каждой строки
+// из одного 64-битного хэша (мультипликативное хэширование).
+var sketchMultipliers = [sketchDepth]uint64{
+	0xc3a5c85c97cb3127, 0xb492b66fbe98f273, 0x9ae16a3b2f90404f, 0xcbf29ce484222325,
+}
+
+// countMinSketch оценивает частоты ключей с ограниченной памятью. После
+// resetAt увеличений (10 на запись емкости) все счетчики делятся пополам,
+// так что старая популярность постепенно забывается.
+//
+// Первое обращение к ключу отмечается только в doorkeeper (фильтр Блума) и не
+// попадает в счетчики: однократные ключи, которых большинство, не засоряют
+// sketch и не завышают оценки горячих записей через коллизии.
+type countMinSketch[K comparable] struct {
+	seed       maphash.Seed
+	rows       [sketchDepth][]uint8
+	rowShift   uint     // Сдвиг хэша до индекса счетчика в строке
+	doorkeeper []uint64 // Битовая маска фильтра Блума
+	doorShift  uint     // Сдвиг хэша до индекса бита doorkeeper
+	additions  int
+	resetAt    int
+}
+
+func newCountMinSketch[K comparable](capacity int) *countMinSketch[K] {
+	// Ширина с запасом относительно емкости, чтобы поток однократных ключей
+	// не набирал в счетчиках частоту горячих записей из-за коллизий
+	capacity = max(capacity, sketchMinCapacity)
+	s := &countMinSketch[K]{seed: maphash.MakeSeed(), resetAt: 10 * capacity}
+
+	width, widthBits := powerOfTwo(sketchWidthFactor * capacity)
+	s.rowShift = 64 - widthBits
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, width)
+	}
+
+	// doorkeeper рассчитан на все ключи между старениями (до resetAt разных ключей),
+	// по 8 бит на ключ, иначе он быстро заполняется и перестает отсеивать
+	doorBits, doorBitsLog := powerOfTwo(max(64, 8*s.resetAt))
+	s.doorShift = 64 - doorBitsLog
+	s.doorkeeper = make([]uint64, doorBits/64)
+	return s
+}
+
+// powerOfTwo возвращает наименьшую степень двойки не меньше n и ее показатель.
+func powerOfTwo(n int) (int, uint) {
+	value, exp := 1, uint(0)
+	for value < n {
+		value <<= 1
+		exp++
+	}
+	return value, exp
+}
+
+// index возвращает индекс для строки row по хэшу ключа и сдвигу таблицы.
+func index(sum uint64, row int, shift uint) uint64 {
+	return (sum * sketchMultipliers[row]) >> shift
+}
+
+func (s *countMinSketch[K]) increment(key K) {
+	sum := maphash.Comparable(s.seed, key)
+	s.additions++
+	if s.admit(sum) {
+		for i := range s.rows {
+			idx := index(sum, i, s.rowShift)
+			if s.rows[i][idx] < sketchMaxCount {
+				s.rows[i][idx]++
+			}
+		}
+	}
+
+	if s.additions >= s.resetAt {
+		s.age()
+	}
+}
+
+func (s *countMinSketch[K]) estimate(key K) uint8 {
+	sum := maphash.Comparable(s.seed, key)
+	estimate := uint8(sketchMaxCount)
+	for i := range s.rows {
+		estimate = min(estimate, s.rows[i][index(sum, i, s.rowShift)])
+	}
+	if s.seen(sum) && estimate < sketchMaxCount {
+		estimate++
+	}
+	return estimate
+}
+
+// admit отмечает ключ в doorkeeper. Возвращает true, если ключ там уже был
+// и обращение нужно учесть в счетчиках.
+func (s *countMinSketch[K]) admit(sum uint64) bool {
+	seen := true
+	for i := range sketchDepth {
+		idx := index(sum, i, s.doorShift)
+		word, bit := idx/64, uint64(1)<<(idx%64)
+		if s.doorkeeper[word]&bit == 0 {
+			seen = false
+			s.doorkeeper[word] |= bit
+		}
+	}
+	return seen
+}
+
+// seen сообщает, отмечен ли ключ в doorkeeper.
+func (s *countMinSketch[K]) seen(sum uint64) bool {
+	for i := range sketchDepth {
+		idx := index(sum, i, s.doorShift)
+		if s.doorkeeper[idx/64]&(uint64(1)<<(idx%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// age делит все счетчики пополам и очищает doorkeeper.
+func (s *countMinSketch[K]) age() {
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			s.rows[i][j] >>= 1
+		}
+	}
+	clear(s.doorkeeper)
+	s.additions /= 2
+}
+
+func (s *countMinSketch[K]) reset() {
+	for i := range s.rows {
+		clear(s.rows[i])
+	}
+	clear(s.doorkeeper)
+	s.additions = 0
+}
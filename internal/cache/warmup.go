@@ -0,0 +1,89 @@
+package cache
+
+import (
+	"L0_project/internal/database"
+	"L0_project/internal/metrics"
+	"L0_project/internal/model"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+)
+
+// WarmUp загружает в кэш до limit самых свежих заказов из БД. Заказы читаются
+// из БД потоком и сразу попадают в кэш, поэтому память не растет с размером
+// таблицы. limit обычно равен емкости кэша: больше заказов кэш все равно не удержит.
+//
+// Прогрев может идти в фоне, пока сервис уже обслуживает запросы: заказы, которые
+// к этому моменту уже есть в кэше (загружены по запросу), не перезаписываются
+// данными прогрева. Наличие заказа проверяется через Peek, чтобы прогрев не
+// влиял на статистику попаданий и порядок вытеснения. При отмене ctx прогрев
+// прерывается, а уже загруженные заказы остаются в кэше.
+func WarmUp(ctx context.Context, storage database.Storage, cache Cache[string, *model.Order], limit int) error {
+	if limit <= 0 {
+		return nil
+	}
+
+	log.Printf("Выполняется прогрев кэша (до %d заказов)...", limit)
+	start := time.Now()
+	loaded := 0
+
+	err := storage.StreamRecentOrders(ctx, limit, func(order *model.Order) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if _, found := cache.Peek(order.OrderUID); found {
+			return nil
+		}
+		cache.Set(ctx, order.OrderUID, order)
+		loaded++
+		return nil
+	})
+
+	duration := time.Since(start)
+	metrics.CacheWarmUpDuration.Set(duration.Seconds())
+	metrics.CacheWarmUpOrders.Set(float64(loaded))
+
+	if err != nil {
+		return fmt.Errorf("прогрев кэша прерван после %d заказов: %w", loaded, err)
+	}
+
+	log.Printf("Кэш прогрет за %s. Загружено %d заказов.", duration.Round(time.Millisecond), loaded)
+	return nil
+}
+
+// RevalidateOrders перечитывает из БД заказы keys и обновляет их в кэше через
+// loader: измененные перезаписываются, удаленные удаляются. Используется после
+// загрузки снимка, если изменения, сделанные другими экземплярами за время
+// остановки, могли быть пропущены. keys перечисляются в порядке Keys и
+// обновляются от наименее ценных, чтобы сохранить порядок вытеснения.
+//
+// Если сверка прервана (ошибка БД или отмена ctx), еще не сверенные заказы
+// удаляются из кэша: они могут быть устаревшими.
+func RevalidateOrders(ctx context.Context, storage database.Storage, loader *Loader[string, *model.Order], keys []string) error {
+	start := time.Now()
+	for i, key := range slices.Backward(keys) {
+		err := ctx.Err()
+		if err == nil {
+			err = loader.Refresh(ctx, key, func(ctx context.Context) (*model.Order, error) {
+				order, err := storage.GetOrderByUID(ctx, key)
+				if errors.Is(err, sql.ErrNoRows) {
+					return nil, ErrNotFound
+				}
+				return order, err
+			})
+		}
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			for _, rest := range keys[:i] {
+				loader.Invalidate(ctx, rest)
+			}
+			return fmt.Errorf("сверка кэша с БД прервана на заказе %s, несверенные заказы удалены: %w", key, err)
+		}
+	}
+
+	log.Printf("Кэш сверен с БД за %s: %d заказов.", time.Since(start).Round(time.Millisecond), len(keys))
+	return nil
+}
@@ -0,0 +1,131 @@
+package cache
+
+import (
+	"L0_project/internal/database/mocks"
+	"L0_project/internal/metrics"
+	"L0_project/internal/model"
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+// streamOrders возвращает реализацию StreamRecentOrders, отдающую заказы по очереди.
+func streamOrders(orders ...*model.Order) func(context.Context, int, func(*model.Order) error) error {
+	return func(_ context.Context, _ int, fn func(*model.Order) error) error {
+		for _, order := range orders {
+			if err := fn(order); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func TestWarmUp_LoadsRecentOrders(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	storage := mocks.NewMockStorage(ctrl)
+	c := NewLRUCache[string, *model.Order](10)
+	ctx := context.Background()
+
+	// Заказ, загруженный по запросу до прогрева, не перезаписывается
+	fresh := &model.Order{OrderUID: "uid-2", TrackNumber: "fresh"}
+	c.Set(ctx, fresh.OrderUID, fresh)
+
+	storage.EXPECT().StreamRecentOrders(gomock.Any(), 3, gomock.Any()).DoAndReturn(streamOrders(
+		&model.Order{OrderUID: "uid-1"},
+		&model.Order{OrderUID: "uid-2", TrackNumber: "stale"},
+		&model.Order{OrderUID: "uid-3"},
+	))
+
+	err := WarmUp(ctx, storage, c, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, c.Len())
+
+	entry, found := c.Peek("uid-2")
+	assert.True(t, found)
+	assert.Equal(t, "fresh", entry.Value.TrackNumber)
+
+	// Проверка наличия не продвигает заказ в порядке вытеснения
+	assert.Equal(t, []string{"uid-3", "uid-1", "uid-2"}, c.Keys())
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(metrics.CacheWarmUpOrders))
+}
+
+func TestWarmUp_StopsOnCancel(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	storage := mocks.NewMockStorage(ctrl)
+	c := NewLRUCache[string, *model.Order](10)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	storage.EXPECT().StreamRecentOrders(gomock.Any(), 10, gomock.Any()).DoAndReturn(
+		func(ctx context.Context, limit int, fn func(*model.Order) error) error {
+			if err := fn(&model.Order{OrderUID: "uid-1"}); err != nil {
+				return err
+			}
+			cancel() // Сервис останавливается во время фонового прогрева
+			return fn(&model.Order{OrderUID: "uid-2"})
+		})
+
+	err := WarmUp(ctx, storage, c, 10)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, []string{"uid-1"}, c.Keys())
+}
+
+func TestWarmUp_StorageError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	storage := mocks.NewMockStorage(ctrl)
+	c := NewLRUCache[string, *model.Order](10)
+	dbErr := errors.New("db down")
+
+	storage.EXPECT().StreamRecentOrders(gomock.Any(), 5, gomock.Any()).Return(dbErr)
+
+	err := WarmUp(context.Background(), storage, c, 5)
+	assert.ErrorIs(t, err, dbErr)
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestRevalidateOrders(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	storage := mocks.NewMockStorage(ctrl)
+	c := NewLRUCache[string, *model.Order](10)
+	loader := NewLoader(c)
+	ctx := context.Background()
+
+	// Снимок: uid-1 изменен за время остановки, uid-2 удален, uid-3 не менялся
+	for _, uid := range []string{"uid-3", "uid-2", "uid-1"} {
+		c.Set(ctx, uid, &model.Order{OrderUID: uid, Status: model.StatusCreated})
+	}
+	storage.EXPECT().GetOrderByUID(gomock.Any(), "uid-1").Return(&model.Order{OrderUID: "uid-1", Status: model.StatusPaid}, nil)
+	storage.EXPECT().GetOrderByUID(gomock.Any(), "uid-2").Return(nil, sql.ErrNoRows)
+	storage.EXPECT().GetOrderByUID(gomock.Any(), "uid-3").Return(&model.Order{OrderUID: "uid-3", Status: model.StatusCreated}, nil)
+
+	assert.NoError(t, RevalidateOrders(ctx, storage, loader, c.Keys()))
+
+	entry, found := c.Peek("uid-1")
+	assert.True(t, found)
+	assert.Equal(t, model.StatusPaid, entry.Value.Status)
+	assert.Equal(t, []string{"uid-1", "uid-3"}, c.Keys(), "порядок вытеснения сохраняется")
+}
+
+func TestRevalidateOrders_StorageErrorDropsUnverified(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	storage := mocks.NewMockStorage(ctrl)
+	c := NewLRUCache[string, *model.Order](10)
+	ctx := context.Background()
+	dbErr := errors.New("db down")
+
+	for _, uid := range []string{"uid-3", "uid-2", "uid-1"} {
+		c.Set(ctx, uid, &model.Order{OrderUID: uid})
+	}
+	storage.EXPECT().GetOrderByUID(gomock.Any(), "uid-3").Return(&model.Order{OrderUID: "uid-3"}, nil)
+	storage.EXPECT().GetOrderByUID(gomock.Any(), "uid-2").Return(nil, dbErr)
+
+	err := RevalidateOrders(ctx, storage, NewLoader(c), c.Keys())
+	assert.ErrorIs(t, err, dbErr)
+	assert.Equal(t, []string{"uid-3"}, c.Keys(), "несверенные заказы удалены")
+}
@@ -0,0 +1,258 @@
+package database
+
+import (
+	"L0_project/internal/metrics"
+	"L0_project/internal/model"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// SaveOrders сохраняет пакет заказов в одной транзакции.
+//
+// Новые заказы записываются через COPY (по одному COPY на таблицу), что сводит
+// число обращений к БД к константе независимо от размера пакета. Уже существующие
+// заказы и повторы UID внутри пакета обрабатываются по одному, как в SaveOrder.
+// Если COPY завершился ошибкой (например, конфликт уникальности у одного из заказов),
+// пакет сохраняется по одному заказу, каждый в своей точке сохранения, так что
+// ошибка одного заказа не влияет на остальные.
+//
+// Возвращаемый срез содержит результат для каждого заказа в порядке входа
+// (nil, ErrOrderUnchanged, *OrderConflictError или другая ошибка). Ошибка второго
+// результата означает, что не удалось сохранить пакет целиком (транзакция откачена).
+//
+// Об изменении каждого сохраненного заказа публикуется уведомление в OrderChangesChannel.
+func (s *postgresStorage) SaveOrders(ctx context.Context, orders []*model.Order) (results []error, err error) {
+	// Создаем span для трассировки
+	ctx, span := s.tracer.Start(ctx, "DB.SaveOrders")
+	defer span.End()
+
+	if len(orders) == 0 {
+		return nil, nil
+	}
+
+	var tx *sqlx.Tx
+	tx, err = s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+				log.Printf("Ошибка отката транзакции (после ошибки: %v): %v", err, rbErr)
+			}
+		}
+	}()
+
+	uids := make([]string, len(orders))
+	for i, order := range orders {
+		uids[i] = order.OrderUID
+	}
+
+	var existing []string
+	if err = tx.SelectContext(ctx, &existing, `SELECT order_uid FROM orders WHERE order_uid = ANY($1) FOR UPDATE`, pq.Array(uids)); err != nil {
+		return nil, fmt.Errorf("ошибка проверки существующих заказов: %w", err)
+	}
+
+	// Новые заказы (первое вхождение UID) - через COPY, остальные - по одному.
+	seen := make(map[string]bool, len(orders))
+	for _, uid := range existing {
+		seen[uid] = true
+	}
+	var fresh, sequential []int
+	for i, order := range orders {
+		if seen[order.OrderUID] {
+			sequential = append(sequential, i)
+		} else {
+			fresh = append(fresh, i)
+		}
+		seen[order.OrderUID] = true
+	}
+
+	if len(fresh) > 0 {
+		var copied bool
+		if copied, err = copyOrdersInSavepoint(ctx, tx, orders, fresh); err != nil {
+			return nil, err
+		}
+		if !copied {
+			sequential = append(sequential, fresh...)
+			sort.Ints(sequential) // Сохраняем порядок пакета
+		}
+	}
+
+	results = make([]error, len(orders))
+	for _, i := range sequential {
+		if results[i], err = saveOrderInSavepoint(ctx, tx, orders[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	var changed []string
+	for i, result := range results {
+		if result == nil {
+			changed = append(changed, orders[i].OrderUID)
+		}
+	}
+	if err = notifyOrdersChanged(ctx, tx, changed...); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// copyOrdersInSavepoint пытается записать новые заказы через COPY.
+// При ошибке COPY откатывается к точке сохранения и возвращает false,
+// чтобы заказы были сохранены по одному. Ошибка возвращается, только если
+// транзакция стала непригодной.
+func copyOrdersInSavepoint(ctx context.Context, tx *sqlx.Tx, orders []*model.Order, indexes []int) (bool, error) {
+	if _, err := tx.ExecContext(ctx, `SAVEPOINT batch_copy`); err != nil {
+		return false, fmt.Errorf("ошибка создания точки сохранения: %w", err)
+	}
+
+	batch := make([]*model.Order, len(indexes))
+	for j, i := range indexes {
+		batch[j] = orders[i]
+	}
+
+	if copyErr := copyOrders(ctx, tx, batch); copyErr != nil {
+		metrics.DBErrors.WithLabelValues("save_orders_copy").Inc()
+		log.Printf("COPY пакета из %d заказов не удался, сохраняем по одному: %v", len(batch), copyErr)
+		if _, err := tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT batch_copy`); err != nil {
+			return false, fmt.Errorf("ошибка отката к точке сохранения: %w", err)
+		}
+		return false, nil
+	}
+
+	if _, err := tx.ExecContext(ctx, `RELEASE SAVEPOINT batch_copy`); err != nil {
+		return false, fmt.Errorf("ошибка освобождения точки сохранения: %w", err)
+	}
+	return true, nil
+}
+
+// saveOrderInSavepoint сохраняет один заказ в собственной точке сохранения.
+// Первый результат - итог для заказа; второй - ошибка, после которой
+// транзакцию продолжать нельзя. При гонке вставок (заказ параллельно вставлен
+// другой транзакцией, см. isInsertRace) сохранение повторяется один раз:
+// новый запрос видит зафиксированный заказ и обновляет его как существующий.
+func saveOrderInSavepoint(ctx context.Context, tx *sqlx.Tx, order *model.Order) (error, error) {
+	orderErr, err := trySaveOrderInSavepoint(ctx, tx, order)
+	if err == nil && isInsertRace(ctx, tx, order, orderErr) {
+		orderErr, err = trySaveOrderInSavepoint(ctx, tx, order)
+	}
+	return orderErr, err
+}
+
+// trySaveOrderInSavepoint выполняет одну попытку saveOrderInSavepoint.
+func trySaveOrderInSavepoint(ctx context.Context, tx *sqlx.Tx, order *model.Order) (error, error) {
+	if _, err := tx.ExecContext(ctx, `SAVEPOINT order_save`); err != nil {
+		return nil, fmt.Errorf("ошибка создания точки сохранения: %w", err)
+	}
+
+	if orderErr := saveOrderTx(ctx, tx, order); orderErr != nil {
+		if _, err := tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT order_save`); err != nil {
+			return nil, fmt.Errorf("ошибка отката к точке сохранения: %w", err)
+		}
+		return orderErr, nil
+	}
+
+	if _, err := tx.ExecContext(ctx, `RELEASE SAVEPOINT order_save`); err != nil {
+		return nil, fmt.Errorf("ошибка освобождения точки сохранения: %w", err)
+	}
+	return nil, nil
+}
+
+// copyOrders записывает новые заказы через COPY. Идентификаторы доставок и
+// платежей резервируются заранее из последовательностей, чтобы связать строки
+// без RETURNING.
+func copyOrders(ctx context.Context, tx *sqlx.Tx, orders []*model.Order) error {
+	deliveryIDs, err := reserveIDs(ctx, tx, "deliveries", len(orders))
+	if err != nil {
+		return err
+	}
+	paymentIDs, err := reserveIDs(ctx, tx, "payments", len(orders))
+	if err != nil {
+		return err
+	}
+
+	deliveries := make([][]interface{}, len(orders))
+	payments := make([][]interface{}, len(orders))
+	orderRows := make([][]interface{}, len(orders))
+	var items [][]interface{}
+
+	for i, o := range orders {
+		d, p := o.Delivery, o.Payment
+		deliveries[i] = []interface{}{deliveryIDs[i], d.Name, d.Phone, d.Zip, d.City, d.Address, d.Region, d.Email}
+		payments[i] = []interface{}{paymentIDs[i], p.Transaction, p.RequestID, p.Currency, p.Provider, p.Amount, p.PaymentDt, p.Bank, p.DeliveryCost, p.GoodsTotal, p.CustomFee}
+		orderRows[i] = []interface{}{o.OrderUID, o.TrackNumber, o.Entry, deliveryIDs[i], paymentIDs[i], o.Locale, o.InternalSignature, o.CustomerID, o.DeliveryService, o.Shardkey, o.SmID, o.DateCreated, o.OofShard}
+		for _, item := range o.Items {
+			items = append(items, []interface{}{o.OrderUID, item.ChrtID, item.TrackNumber, item.Price, item.Rid, item.Name, item.Sale, item.Size, item.TotalPrice, item.NmID, item.Brand, item.Status})
+		}
+	}
+
+	if err := copyRows(ctx, tx, "deliveries", []string{"id", "name", "phone", "zip", "city", "address", "region", "email"}, deliveries); err != nil {
+		return fmt.Errorf("ошибка сохранения доставок: %w", err)
+	}
+	if err := copyRows(ctx, tx, "payments", []string{"id", "transaction", "request_id", "currency", "provider", "amount", "payment_dt", "bank", "delivery_cost", "goods_total", "custom_fee"}, payments); err != nil {
+		return fmt.Errorf("ошибка сохранения платежей: %w", err)
+	}
+	if err := copyRows(ctx, tx, "orders", []string{"order_uid", "track_number", "entry", "delivery_id", "payment_id", "locale", "internal_signature", "customer_id", "delivery_service", "shardkey", "sm_id", "date_created", "oof_shard"}, orderRows); err != nil {
+		return fmt.Errorf("ошибка сохранения заказов: %w", err)
+	}
+	if err := copyRows(ctx, tx, "items", []string{"order_uid", "chrt_id", "track_number", "price", "rid", "name", "sale", "size", "total_price", "nm_id", "brand", "status"}, items); err != nil {
+		return fmt.Errorf("ошибка сохранения товаров: %w", err)
+	}
+	return nil
+}
+
+// reserveIDs выделяет n значений из последовательности столбца id таблицы.
+func reserveIDs(ctx context.Context, tx *sqlx.Tx, table string, n int) ([]int, error) {
+	var ids []int
+	query := `SELECT nextval(pg_get_serial_sequence($1, 'id')) FROM generate_series(1, $2)`
+	if err := tx.SelectContext(ctx, &ids, query, table, n); err != nil {
+		return nil, fmt.Errorf("ошибка резервирования идентификаторов %s: %w", table, err)
+	}
+	if len(ids) != n {
+		return nil, fmt.Errorf("зарезервировано %d идентификаторов %s вместо %d", len(ids), table, n)
+	}
+	return ids, nil
+}
+
+// copyRows записывает строки в таблицу одним COPY FROM STDIN.
+func copyRows(ctx context.Context, tx *sqlx.Tx, table string, columns []string, rows [][]interface{}) (err error) {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn(table, columns...))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := stmt.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	for _, row := range rows {
+		if _, err = stmt.ExecContext(ctx, row...); err != nil {
+			return err
+		}
+	}
+
+	// Пустой Exec завершает COPY; ошибки ограничений проявляются здесь.
+	_, err = stmt.ExecContext(ctx)
+	return err
+}
@@ -0,0 +1,127 @@
+package database
+
+import (
+	"L0_project/internal/model"
+	"context"
+	"errors"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+// batchTestOrder возвращает копию тестового заказа с другим UID и транзакцией
+func batchTestOrder(uid string) *model.Order {
+	order := *helperTestOrder
+	order.OrderUID = uid
+	order.TrackNumber = "track-" + uid
+	order.Payment.Transaction = uid
+	return &order
+}
+
+// expectCopy ожидает COPY в таблицу с заданным числом строк
+func expectCopy(mock sqlmock.Sqlmock, table string, rows int) {
+	prep := mock.ExpectPrepare(`COPY "` + table + `"`)
+	for i := 0; i < rows; i++ {
+		prep.ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	prep.ExpectExec().WithoutArgs().WillReturnResult(sqlmock.NewResult(0, int64(rows)))
+	prep.WillBeClosed()
+}
+
+func TestPostgresStorage_SaveOrders_CopyPath(t *testing.T) {
+	storage, mock := setupStorageWithMock(t)
+	ctx := context.Background()
+	orders := []*model.Order{batchTestOrder("uid-1"), batchTestOrder("uid-2")}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT order_uid FROM orders WHERE order_uid = ANY\(\$1\) FOR UPDATE`).
+		WillReturnRows(sqlmock.NewRows([]string{"order_uid"}))
+	mock.ExpectExec(`SAVEPOINT batch_copy`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT nextval`).WithArgs("deliveries", 2).WillReturnRows(sqlmock.NewRows([]string{"nextval"}).AddRow(10).AddRow(11))
+	mock.ExpectQuery(`SELECT nextval`).WithArgs("payments", 2).WillReturnRows(sqlmock.NewRows([]string{"nextval"}).AddRow(20).AddRow(21))
+	expectCopy(mock, "deliveries", 2)
+	expectCopy(mock, "payments", 2)
+	expectCopy(mock, "orders", 2)
+	expectCopy(mock, "items", 2)
+	mock.ExpectExec(`RELEASE SAVEPOINT batch_copy`).WillReturnResult(sqlmock.NewResult(0, 0))
+	expectNotify(mock, "uid-1", "uid-2")
+	mock.ExpectCommit()
+
+	results, err := storage.SaveOrders(ctx, orders)
+	assert.NoError(t, err)
+	assert.Equal(t, []error{nil, nil}, results)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStorage_SaveOrders_CopyFailureIsolatesBadOrder(t *testing.T) {
+	storage, mock := setupStorageWithMock(t)
+	ctx := context.Background()
+	good, bad := batchTestOrder("uid-good"), batchTestOrder("uid-bad")
+	conflictErr := &pq.Error{Code: "23505", Constraint: "payments_transaction_key"}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT order_uid FROM orders`).WillReturnRows(sqlmock.NewRows([]string{"order_uid"}))
+	mock.ExpectExec(`SAVEPOINT batch_copy`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT nextval`).WillReturnError(conflictErr)
+	mock.ExpectExec(`ROLLBACK TO SAVEPOINT batch_copy`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	// Заказы сохраняются по одному, каждый в своей точке сохранения
+	mock.ExpectExec(`SAVEPOINT order_save`).WillReturnResult(sqlmock.NewResult(0, 0))
+	expectNoExistingOrder(mock, good.OrderUID)
+	mock.ExpectQuery(`INSERT INTO deliveries`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery(`INSERT INTO payments`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec(`INSERT INTO orders`).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO items`).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`RELEASE SAVEPOINT order_save`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	// Транзакция платежа занята другим заказом: сохранение не повторяется
+	mock.ExpectExec(`SAVEPOINT order_save`).WillReturnResult(sqlmock.NewResult(0, 0))
+	expectNoExistingOrder(mock, bad.OrderUID)
+	mock.ExpectQuery(`INSERT INTO deliveries`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	mock.ExpectQuery(`INSERT INTO payments`).WillReturnError(conflictErr)
+	mock.ExpectExec(`ROLLBACK TO SAVEPOINT order_save`).WillReturnResult(sqlmock.NewResult(0, 0))
+	expectTransactionOwner(mock, bad.Payment.Transaction, "other-order")
+
+	// Уведомление публикуется только о сохраненном заказе
+	expectNotify(mock, good.OrderUID)
+	mock.ExpectCommit()
+
+	results, err := storage.SaveOrders(ctx, []*model.Order{good, bad})
+	assert.NoError(t, err)
+	assert.Len(t, results, 2)
+	assert.NoError(t, results[0])
+
+	var conflict *OrderConflictError
+	assert.ErrorAs(t, results[1], &conflict)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStorage_SaveOrders_ExistingOrderGoesSequential(t *testing.T) {
+	storage, mock := setupStorageWithMock(t)
+	ctx := context.Background()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT order_uid FROM orders`).
+		WillReturnRows(sqlmock.NewRows([]string{"order_uid"}).AddRow(helperTestOrder.OrderUID))
+	mock.ExpectExec(`SAVEPOINT order_save`).WillReturnResult(sqlmock.NewResult(0, 0))
+	expectStoredOrder(mock, helperTestOrder)
+	mock.ExpectExec(`ROLLBACK TO SAVEPOINT order_save`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	results, err := storage.SaveOrders(ctx, []*model.Order{helperTestOrder})
+	assert.NoError(t, err)
+	assert.ErrorIs(t, results[0], ErrOrderUnchanged)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStorage_SaveOrders_BeginError(t *testing.T) {
+	storage, mock := setupStorageWithMock(t)
+	mock.ExpectBegin().WillReturnError(errors.New("begin error"))
+
+	results, err := storage.SaveOrders(context.Background(), []*model.Order{helperTestOrder})
+	assert.Error(t, err)
+	assert.Nil(t, results)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,188 @@
+package database
+
+import (
+	"L0_project/internal/model"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"syscall"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Коды ошибок PostgreSQL (SQLSTATE), которые обрабатываются явно.
+const (
+	pgUniqueViolation       = "23505"
+	pgSerializationFailure  = "40001"
+	pgDeadlockDetected      = "40P01"
+	pgLockNotAvailable      = "55P03"
+	pgQueryCanceled         = "57014"
+	pgAdminShutdown         = "57P01"
+	pgCrashShutdown         = "57P02"
+	pgCannotConnectNow      = "57P03"
+	pgConnectionException   = "08" // Класс: ошибки соединения
+	pgInsufficientResources = "53" // Класс: нехватка ресурсов (too_many_connections и т.п.)
+	pgDataException         = "22" // Класс: некорректные данные
+	pgIntegrityViolation    = "23" // Класс: нарушения ограничений целостности
+	pgSyntaxOrAccessError   = "42" // Класс: синтаксис запроса и права доступа
+)
+
+// ErrorKind - класс ошибки БД с точки зрения повторных попыток.
+type ErrorKind int
+
+const (
+	// ErrorUnknown - ошибка не распознана; повторять ограниченное число раз.
+	ErrorUnknown ErrorKind = iota
+	// ErrorTransient - временная ошибка (сеть, перезапуск БД, deadlock); повтор имеет смысл.
+	ErrorTransient
+	// ErrorPermanent - постоянная ошибка (нарушение ограничений, некорректные данные); повтор не поможет.
+	ErrorPermanent
+)
+
+func (k ErrorKind) String() string {
+	switch k {
+	case ErrorTransient:
+		return "transient"
+	case ErrorPermanent:
+		return "permanent"
+	default:
+		return "unknown"
+	}
+}
+
+// Classify определяет, стоит ли повторять операцию, завершившуюся ошибкой err.
+// Решение принимается по SQLSTATE-коду pq.Error, а для ошибок драйвера и сети -
+// по их типу.
+func Classify(err error) ErrorKind {
+	if err == nil {
+		return ErrorUnknown
+	}
+
+	var conflict *OrderConflictError
+	if errors.As(err, &conflict) {
+		return ErrorPermanent
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return classifySQLState(pqErr.Code)
+	}
+
+	switch {
+	case errors.Is(err, driver.ErrBadConn),
+		errors.Is(err, sql.ErrConnDone),
+		errors.Is(err, io.EOF),
+		errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, syscall.ECONNREFUSED),
+		errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, syscall.EPIPE):
+		return ErrorTransient
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return ErrorTransient
+	}
+
+	return ErrorUnknown
+}
+
+// classifySQLState классифицирует ошибку PostgreSQL по ее SQLSTATE-коду.
+func classifySQLState(code pq.ErrorCode) ErrorKind {
+	switch code {
+	case pgSerializationFailure, pgDeadlockDetected, pgLockNotAvailable, pgQueryCanceled,
+		pgAdminShutdown, pgCrashShutdown, pgCannotConnectNow:
+		return ErrorTransient
+	}
+
+	switch code.Class() {
+	case pgConnectionException, pgInsufficientResources:
+		return ErrorTransient
+	case pgDataException, pgIntegrityViolation, pgSyntaxOrAccessError:
+		return ErrorPermanent
+	}
+
+	return ErrorUnknown
+}
+
+// IsTransient сообщает, что операцию имеет смысл повторить.
+func IsTransient(err error) bool {
+	return Classify(err) == ErrorTransient
+}
+
+// IsPermanent сообщает, что повтор операции не поможет.
+func IsPermanent(err error) bool {
+	return Classify(err) == ErrorPermanent
+}
+
+// ordersPrimaryKey - имя ограничения первичного ключа таблицы orders.
+// Нарушение этого ограничения означает гонку двух вставок одного и того же
+// заказа, а не конфликт с другим заказом (см. isInsertRace).
+const ordersPrimaryKey = "orders_pkey"
+
+// ErrOrderUnchanged возвращается SaveOrder, если заказ уже сохранен
+// и входящие данные полностью совпадают с сохраненными (повторная доставка).
+var ErrOrderUnchanged = errors.New("заказ уже сохранен, данные не изменились")
+
+// OrderConflictError возвращается SaveOrder, если уникальное поле заказа
+// (например, payments.transaction или orders.track_number) уже принадлежит
+// другому заказу. Такую ошибку бессмысленно ретраить.
+type OrderConflictError struct {
+	OrderUID   string
+	Constraint string
+	Err        error
+}
+
+func (e *OrderConflictError) Error() string {
+	return fmt.Sprintf("конфликт уникальности для заказа %s (ограничение %s): %v", e.OrderUID, e.Constraint, e.Err)
+}
+
+func (e *OrderConflictError) Unwrap() error {
+	return e.Err
+}
+
+// asConflict оборачивает нарушение уникальности в *OrderConflictError.
+// Остальные ошибки возвращаются без изменений.
+func asConflict(orderUID string, err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == pgUniqueViolation && pqErr.Constraint != ordersPrimaryKey {
+		return &OrderConflictError{OrderUID: orderUID, Constraint: pqErr.Constraint, Err: err}
+	}
+	return err
+}
+
+// ErrStatusVersionConflict возвращается TransitionStatus, если версия статуса
+// заказа не совпала с ожидаемой: статус успели изменить параллельно.
+var ErrStatusVersionConflict = errors.New("статус заказа изменен параллельно")
+
+// sameOrder сообщает, совпадают ли бизнес-данные двух заказов.
+// Суррогатные ключи (id) игнорируются, а дата создания сравнивается
+// с точностью до микросекунд, как она хранится в PostgreSQL.
+func sameOrder(a, b *model.Order) bool {
+	return reflect.DeepEqual(normalizeOrder(a), normalizeOrder(b))
+}
+
+// normalizeOrder возвращает копию заказа, пригодную для сравнения.
+// Статусы заказа и товаров ведут события статуса, поэтому они не сравниваются.
+func normalizeOrder(o *model.Order) model.Order {
+	n := *o
+	n.Delivery.ID = 0
+	n.Payment.ID = 0
+	n.Status = ""
+	n.StatusVersion = 0
+	n.DateCreated = o.DateCreated.UTC().Truncate(time.Microsecond)
+
+	n.Items = make([]model.Item, len(o.Items))
+	for i, item := range o.Items {
+		item.ID = 0
+		item.OrderUID = ""
+		item.Status = 0
+		n.Items[i] = item
+	}
+	return n
+}
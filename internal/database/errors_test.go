@@ -0,0 +1,41 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want ErrorKind
+	}{
+		{"connection failure", &pq.Error{Code: "08006"}, ErrorTransient},
+		{"serialization failure", &pq.Error{Code: "40001"}, ErrorTransient},
+		{"deadlock", &pq.Error{Code: "40P01"}, ErrorTransient},
+		{"too many connections", &pq.Error{Code: "53300"}, ErrorTransient},
+		{"admin shutdown", &pq.Error{Code: "57P01"}, ErrorTransient},
+		{"unique violation", &pq.Error{Code: "23505", Constraint: "payments_transaction_key"}, ErrorPermanent},
+		{"foreign key violation", &pq.Error{Code: "23503"}, ErrorPermanent},
+		{"check violation", &pq.Error{Code: "23514"}, ErrorPermanent},
+		{"value too long", &pq.Error{Code: "22001"}, ErrorPermanent},
+		{"wrapped pq error", fmt.Errorf("ошибка сохранения заказа: %w", &pq.Error{Code: "40001"}), ErrorTransient},
+		{"order conflict", &OrderConflictError{OrderUID: "uid", Err: errors.New("dup")}, ErrorPermanent},
+		{"bad conn", driver.ErrBadConn, ErrorTransient},
+		{"unknown", errors.New("something odd"), ErrorUnknown},
+		{"context canceled", context.Canceled, ErrorUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Classify(tt.err))
+		})
+	}
+}
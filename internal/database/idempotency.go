@@ -0,0 +1,104 @@
+package database
+
+import (
+	"L0_project/internal/metrics"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// IdempotencyRecord - запрос, выполненный (или выполняемый) с ключом идемпотентности.
+type IdempotencyRecord struct {
+	Key         string `db:"key"`
+	RequestHash string `db:"request_hash"` // Хэш метода, пути и тела запроса
+	// StatusCode и Response пусты, пока запрос с этим ключом выполняется
+	StatusCode sql.NullInt32 `db:"status_code"`
+	Response   []byte        `db:"response"`
+	CreatedAt  time.Time     `db:"created_at"`
+}
+
+// Completed сообщает, что ответ на запрос уже сохранен.
+func (r *IdempotencyRecord) Completed() bool {
+	return r.StatusCode.Valid
+}
+
+// reserveIdempotencyKeyAttempts ограничивает повторы резервирования, если
+// запись ключа удаляется (очисткой просроченных) между запросами.
+const reserveIdempotencyKeyAttempts = 3
+
+// ReserveIdempotencyKey резервирует ключ за запросом с хэшем requestHash.
+// Возвращает nil, если ключ свободен (или прежняя запись старше ttl) и теперь
+// зарезервирован; иначе возвращает существующую запись, по которой вызывающий
+// решает, повторить ли сохраненный ответ.
+func (s *postgresStorage) ReserveIdempotencyKey(ctx context.Context, key, requestHash string, ttl time.Duration) (*IdempotencyRecord, error) {
+	// Создаем span для трассировки
+	ctx, span := s.tracer.Start(ctx, "DB.ReserveIdempotencyKey")
+	defer span.End()
+
+	// Просроченная запись занимается заново
+	reserveQuery := `
+        INSERT INTO idempotency_keys (key, request_hash) VALUES ($1, $2)
+        ON CONFLICT (key) DO UPDATE SET request_hash = EXCLUDED.request_hash, status_code = NULL, response = NULL, created_at = now()
+        WHERE idempotency_keys.created_at < now() - $3 * interval '1 millisecond'
+        RETURNING key`
+
+	for attempt := 1; attempt <= reserveIdempotencyKeyAttempts; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		var reserved string
+		err := s.db.GetContext(ctx, &reserved, reserveQuery, key, requestHash, ttl.Milliseconds())
+		if err == nil {
+			return nil, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			metrics.DBErrors.WithLabelValues("reserve_idempotency_key").Inc() // Метрика ошибки
+			return nil, fmt.Errorf("ошибка резервирования ключа идемпотентности: %w", err)
+		}
+
+		var record IdempotencyRecord
+		err = s.db.GetContext(ctx, &record, `SELECT key, request_hash, status_code, response, created_at FROM idempotency_keys WHERE key = $1`, key)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue // Запись удалили между запросами: повторяем резервирование
+		}
+		if err != nil {
+			metrics.DBErrors.WithLabelValues("reserve_idempotency_key").Inc() // Метрика ошибки
+			return nil, fmt.Errorf("ошибка чтения ключа идемпотентности: %w", err)
+		}
+		return &record, nil
+	}
+
+	metrics.DBErrors.WithLabelValues("reserve_idempotency_key").Inc() // Метрика ошибки
+	return nil, fmt.Errorf("ключ идемпотентности %s не удалось зарезервировать за %d попыток: запись удалялась между запросами", key, reserveIdempotencyKeyAttempts)
+}
+
+// CompleteIdempotencyKey сохраняет ответ на запрос с зарезервированным ключом.
+func (s *postgresStorage) CompleteIdempotencyKey(ctx context.Context, key string, statusCode int, response []byte) error {
+	// Создаем span для трассировки
+	ctx, span := s.tracer.Start(ctx, "DB.CompleteIdempotencyKey")
+	defer span.End()
+
+	query := `UPDATE idempotency_keys SET status_code = $1, response = $2 WHERE key = $3`
+	if _, err := s.db.ExecContext(ctx, query, statusCode, response, key); err != nil {
+		metrics.DBErrors.WithLabelValues("complete_idempotency_key").Inc() // Метрика ошибки
+		return fmt.Errorf("ошибка сохранения ответа для ключа идемпотентности: %w", err)
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey освобождает ключ запроса, который не удалось выполнить
+// (например, БД была недоступна), чтобы клиент мог повторить его с тем же ключом.
+func (s *postgresStorage) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	// Создаем span для трассировки
+	ctx, span := s.tracer.Start(ctx, "DB.ReleaseIdempotencyKey")
+	defer span.End()
+
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE key = $1 AND status_code IS NULL`, key); err != nil {
+		metrics.DBErrors.WithLabelValues("release_idempotency_key").Inc() // Метрика ошибки
+		return fmt.Errorf("ошибка освобождения ключа идемпотентности: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresStorage_ReserveIdempotencyKey_Reserved(t *testing.T) {
+	storage, mock := setupStorageWithMock(t)
+
+	mock.ExpectQuery(`INSERT INTO idempotency_keys \(key, request_hash\) VALUES \(\$1, \$2\) ON CONFLICT \(key\) DO UPDATE`).
+		WithArgs("key-1", "hash", int64(3600000)).
+		WillReturnRows(sqlmock.NewRows([]string{"key"}).AddRow("key-1"))
+
+	record, err := storage.ReserveIdempotencyKey(context.Background(), "key-1", "hash", time.Hour)
+	require.NoError(t, err)
+	assert.Nil(t, record)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStorage_ReserveIdempotencyKey_Existing(t *testing.T) {
+	storage, mock := setupStorageWithMock(t)
+	createdAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// Ключ уже занят действующей записью
+	mock.ExpectQuery(`INSERT INTO idempotency_keys`).WillReturnRows(sqlmock.NewRows([]string{"key"}))
+	mock.ExpectQuery(`SELECT key, request_hash, status_code, response, created_at FROM idempotency_keys WHERE key = \$1`).
+		WithArgs("key-1").
+		WillReturnRows(sqlmock.NewRows([]string{"key", "request_hash", "status_code", "response", "created_at"}).
+			AddRow("key-1", "hash", 201, []byte(`{"status":"saved"}`), createdAt))
+
+	record, err := storage.ReserveIdempotencyKey(context.Background(), "key-1", "hash", time.Hour)
+	require.NoError(t, err)
+	require.NotNil(t, record)
+	assert.True(t, record.Completed())
+	assert.Equal(t, int32(201), record.StatusCode.Int32)
+	assert.JSONEq(t, `{"status":"saved"}`, string(record.Response))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStorage_ReserveIdempotencyKey_DeletedBetweenQueries(t *testing.T) {
+	storage, mock := setupStorageWithMock(t)
+
+	// Запись ключа удаляется после каждого неудачного резервирования: попытки ограничены
+	for i := 0; i < reserveIdempotencyKeyAttempts; i++ {
+		mock.ExpectQuery(`INSERT INTO idempotency_keys`).WillReturnRows(sqlmock.NewRows([]string{"key"}))
+		mock.ExpectQuery(`SELECT key, request_hash, status_code, response, created_at FROM idempotency_keys`).
+			WillReturnRows(sqlmock.NewRows([]string{"key", "request_hash", "status_code", "response", "created_at"}))
+	}
+
+	record, err := storage.ReserveIdempotencyKey(context.Background(), "key-1", "hash", time.Hour)
+	assert.Error(t, err)
+	assert.Nil(t, record)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStorage_ReserveIdempotencyKey_Canceled(t *testing.T) {
+	storage, mock := setupStorageWithMock(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := storage.ReserveIdempotencyKey(ctx, "key-1", "hash", time.Hour)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStorage_CompleteAndReleaseIdempotencyKey(t *testing.T) {
+	storage, mock := setupStorageWithMock(t)
+
+	mock.ExpectExec(`UPDATE idempotency_keys SET status_code = \$1, response = \$2 WHERE key = \$3`).
+		WithArgs(201, []byte(`{}`), "key-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, storage.CompleteIdempotencyKey(context.Background(), "key-1", 201, []byte(`{}`)))
+
+	// Освобождается только ключ незавершенного запроса
+	mock.ExpectExec(`DELETE FROM idempotency_keys WHERE key = \$1 AND status_code IS NULL`).
+		WithArgs("key-2").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, storage.ReleaseIdempotencyKey(context.Background(), "key-2"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,139 @@
+package database
+
+import (
+	"L0_project/internal/model"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultListLimit - размер страницы по умолчанию для ListOrders.
+	DefaultListLimit = 50
+	// MaxListLimit - максимально допустимый размер страницы.
+	MaxListLimit = 500
+)
+
+// ErrInvalidCursor возвращается, если курсор пагинации не удалось разобрать.
+var ErrInvalidCursor = errors.New("некорректный курсор пагинации")
+
+// OrderFilter описывает фильтры для постраничного списка заказов.
+// Пустые строковые поля и нулевые даты не участвуют в фильтрации.
+type OrderFilter struct {
+	CustomerID      string
+	TrackNumber     string
+	DeliveryService string
+	Locale          string
+
+	// Фильтры по платежу
+	Provider string
+	Bank     string
+	Currency string
+
+	// Диапазон date_created: [CreatedFrom, CreatedTo)
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+
+	Limit int
+}
+
+// OrderCursor - позиция в keyset-пагинации по (date_created, order_uid).
+// Страница содержит заказы, строго "старше" курсора.
+type OrderCursor struct {
+	DateCreated time.Time
+	OrderUID    string
+}
+
+// OrderPage - одна страница списка заказов.
+type OrderPage struct {
+	Orders     []model.Order
+	NextCursor *OrderCursor // nil, если страниц больше нет
+}
+
+// Encode сериализует курсор в непрозрачную строку для передачи клиенту.
+func (c OrderCursor) Encode() string {
+	raw := c.DateCreated.UTC().Format(time.RFC3339Nano) + "|" + c.OrderUID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeOrderCursor разбирает строку, полученную из OrderCursor.Encode.
+func DecodeOrderCursor(s string) (*OrderCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+
+	ts, uid, found := strings.Cut(string(raw), "|")
+	if !found || uid == "" {
+		return nil, ErrInvalidCursor
+	}
+
+	dateCreated, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+
+	return &OrderCursor{DateCreated: dateCreated, OrderUID: uid}, nil
+}
+
+// normalizedLimit возвращает размер страницы в допустимых границах.
+func (f OrderFilter) normalizedLimit() int {
+	switch {
+	case f.Limit <= 0:
+		return DefaultListLimit
+	case f.Limit > MaxListLimit:
+		return MaxListLimit
+	default:
+		return f.Limit
+	}
+}
+
+// buildListConditions формирует WHERE-условия и аргументы запроса ListOrders.
+func buildListConditions(filter OrderFilter, cursor *OrderCursor) ([]string, []interface{}) {
+	var (
+		conditions []string
+		args       []interface{}
+	)
+
+	add := func(expr string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(expr, len(args)))
+	}
+
+	if filter.CustomerID != "" {
+		add("o.customer_id = $%d", filter.CustomerID)
+	}
+	if filter.TrackNumber != "" {
+		add("o.track_number = $%d", filter.TrackNumber)
+	}
+	if filter.DeliveryService != "" {
+		add("o.delivery_service = $%d", filter.DeliveryService)
+	}
+	if filter.Locale != "" {
+		add("o.locale = $%d", filter.Locale)
+	}
+	if filter.Provider != "" {
+		add("p.provider = $%d", filter.Provider)
+	}
+	if filter.Bank != "" {
+		add("p.bank = $%d", filter.Bank)
+	}
+	if filter.Currency != "" {
+		add("p.currency = $%d", filter.Currency)
+	}
+	if !filter.CreatedFrom.IsZero() {
+		add("o.date_created >= $%d", filter.CreatedFrom)
+	}
+	if !filter.CreatedTo.IsZero() {
+		add("o.date_created < $%d", filter.CreatedTo)
+	}
+
+	if cursor != nil {
+		args = append(args, cursor.DateCreated, cursor.OrderUID)
+		conditions = append(conditions, fmt.Sprintf("(o.date_created, o.order_uid) < ($%d, $%d)", len(args)-1, len(args)))
+	}
+
+	return conditions, args
+}
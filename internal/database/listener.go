@@ -0,0 +1,167 @@
+package database
+
+import (
+	"L0_project/internal/metrics"
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	listenerMinReconnectInterval = time.Second
+	listenerMaxReconnectInterval = time.Minute
+	// listenerPingInterval - период проверки соединения, если уведомлений нет.
+	// Без проверки обрыв соединения мог бы долго оставаться незамеченным.
+	listenerPingInterval = 30 * time.Second
+)
+
+// notificationListener - часть pq.Listener, используемая OrderChangeListener.
+type notificationListener interface {
+	Listen(channel string) error
+	NotificationChannel() <-chan *pq.Notification
+	Ping() error
+	Close() error
+}
+
+// OrderChangeListener подписывается на уведомления об изменении заказов
+// (LISTEN в OrderChangesChannel) и сообщает об изменениях, сделанных другими
+// экземплярами сервиса. Собственные изменения пропускаются: экземпляр сам
+// обновляет свой кэш при записи.
+//
+// Соединение восстанавливается автоматически. Уведомления, отправленные, пока
+// соединения не было, теряются, поэтому после переподключения вызывается
+// onReconnect - например, чтобы сбросить кэш целиком.
+type OrderChangeListener struct {
+	listener     notificationListener
+	onChange     func(ctx context.Context, orderUID string)
+	onReconnect  func(ctx context.Context)
+	pingInterval time.Duration
+	subscribed   chan struct{} // Закрывается после подписки на канал
+}
+
+// NewOrderChangeListener создает подписку на уведомления об изменении заказов.
+// Подключение к БД выполняется в фоне; Run начинает получать уведомления.
+func NewOrderChangeListener(dbURL string, onChange func(ctx context.Context, orderUID string), onReconnect func(ctx context.Context)) *OrderChangeListener {
+	listener := pq.NewListener(dbURL, listenerMinReconnectInterval, listenerMaxReconnectInterval, logListenerEvent)
+	return newOrderChangeListener(listener, onChange, onReconnect)
+}
+
+func newOrderChangeListener(listener notificationListener, onChange func(ctx context.Context, orderUID string), onReconnect func(ctx context.Context)) *OrderChangeListener {
+	return &OrderChangeListener{
+		listener:     listener,
+		onChange:     onChange,
+		onReconnect:  onReconnect,
+		pingInterval: listenerPingInterval,
+		subscribed:   make(chan struct{}),
+	}
+}
+
+// Subscribed возвращает канал, который закрывается после подписки на
+// уведомления: изменения, сделанные после этого, не будут пропущены.
+func (l *OrderChangeListener) Subscribed() <-chan struct{} {
+	return l.subscribed
+}
+
+// Run получает уведомления до отмены ctx, после чего закрывает подписку.
+func (l *OrderChangeListener) Run(ctx context.Context) {
+	defer func() {
+		if err := l.listener.Close(); err != nil {
+			log.Printf("Ошибка закрытия подписки на изменения заказов: %v", err)
+		}
+	}()
+
+	if err := l.listener.Listen(OrderChangesChannel); err != nil {
+		log.Printf("Ошибка подписки на изменения заказов: %v", err)
+		return
+	}
+	log.Printf("Подписка на изменения заказов (канал %s) запущена.", OrderChangesChannel)
+	close(l.subscribed)
+
+	// Проверки выполняет отдельная горутина: Ping может блокироваться до
+	// таймаута TCP, поэтому одновременно идет не больше одной проверки.
+	pings := make(chan struct{}, 1)
+	stop := make(chan struct{})
+	defer close(stop)
+	go l.pinger(pings, stop)
+
+	ticker := time.NewTicker(l.pingInterval)
+	defer ticker.Stop()
+
+	notifications := l.listener.NotificationChannel()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Подписка на изменения заказов остановлена.")
+			return
+		case n, ok := <-notifications:
+			if !ok {
+				return
+			}
+			if n == nil {
+				// pq отправляет nil после восстановления соединения
+				metrics.CacheInvalidationReconnects.Inc()
+				log.Println("Подписка на изменения заказов восстановлена, уведомления за время разрыва могли быть потеряны.")
+				l.onReconnect(ctx)
+				continue
+			}
+			l.handle(ctx, n)
+		case <-ticker.C:
+			select {
+			case pings <- struct{}{}:
+			default:
+				// Предыдущая проверка еще идет
+			}
+		}
+	}
+}
+
+// pinger проверяет соединение по сигналам из pings, пока не закрыт stop.
+// Ошибка проверки не требует действий: pq сам переподключится и сообщит об
+// этом через nil в канале уведомлений.
+func (l *OrderChangeListener) pinger(pings <-chan struct{}, stop <-chan struct{}) {
+	for {
+		select {
+		case <-stop:
+			return
+		case <-pings:
+		}
+
+		// Сигнал мог прийти, пока шла предыдущая проверка, а подписка уже остановлена
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		_ = l.listener.Ping()
+	}
+}
+
+// handle разбирает уведомление и вызывает onChange для чужих изменений.
+func (l *OrderChangeListener) handle(ctx context.Context, n *pq.Notification) {
+	var change OrderChange
+	if err := json.Unmarshal([]byte(n.Extra), &change); err != nil || change.OrderUID == "" {
+		log.Printf("Некорректное уведомление об изменении заказа: %q", n.Extra)
+		metrics.CacheInvalidations.WithLabelValues("invalid").Inc()
+		return
+	}
+	if change.Source == instanceID {
+		metrics.CacheInvalidations.WithLabelValues("own").Inc()
+		return
+	}
+
+	l.onChange(ctx, change.OrderUID)
+	metrics.CacheInvalidations.WithLabelValues("applied").Inc()
+}
+
+// logListenerEvent логирует изменения состояния соединения подписки.
+func logListenerEvent(event pq.ListenerEventType, err error) {
+	switch event {
+	case pq.ListenerEventDisconnected:
+		log.Printf("Подписка на изменения заказов: соединение потеряно: %v", err)
+	case pq.ListenerEventConnectionAttemptFailed:
+		log.Printf("Подписка на изменения заказов: не удалось подключиться: %v", err)
+	}
+}
@@ -0,0 +1,14 @@
+-- Индексы для постраничного списка заказов (GET /api/orders).
+-- Keyset-пагинация идет по (date_created, order_uid) в порядке убывания.
+CREATE INDEX IF NOT EXISTS idx_orders_date_created_uid ON orders (date_created DESC, order_uid DESC);
+
+CREATE INDEX IF NOT EXISTS idx_orders_customer_id ON orders (customer_id, date_created DESC, order_uid DESC);
+CREATE INDEX IF NOT EXISTS idx_orders_delivery_service ON orders (delivery_service, date_created DESC, order_uid DESC);
+CREATE INDEX IF NOT EXISTS idx_orders_locale ON orders (locale, date_created DESC, order_uid DESC);
+
+CREATE INDEX IF NOT EXISTS idx_payments_provider ON payments (provider);
+CREATE INDEX IF NOT EXISTS idx_payments_bank ON payments (bank);
+CREATE INDEX IF NOT EXISTS idx_payments_currency ON payments (currency);
+
+-- Товары выбираются по order_uid (GetOrderByUID, ListOrders).
+CREATE INDEX IF NOT EXISTS idx_items_order_uid ON items (order_uid);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS status_history;
+ALTER TABLE orders DROP CONSTRAINT IF EXISTS orders_status_check;
+ALTER TABLE orders DROP COLUMN IF EXISTS status_version;
+ALTER TABLE orders DROP COLUMN IF EXISTS status;
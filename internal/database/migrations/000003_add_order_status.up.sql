@@ -0,0 +1,20 @@
+-- Статус заказа и его версия для оптимистичной блокировки.
+-- Каждый переход статуса увеличивает status_version на 1.
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'created',
+    ADD COLUMN IF NOT EXISTS status_version INT NOT NULL DEFAULT 1;
+
+ALTER TABLE orders ADD CONSTRAINT orders_status_check
+    CHECK (status IN ('created', 'paid', 'assembling', 'shipped', 'delivered', 'cancelled', 'returned'));
+
+-- История переходов статусов (GET /api/order/{uid}/history).
+CREATE TABLE IF NOT EXISTS status_history (
+    id BIGSERIAL PRIMARY KEY,
+    order_uid VARCHAR(255) NOT NULL REFERENCES orders(order_uid) ON DELETE CASCADE,
+    from_status VARCHAR(20) NOT NULL,
+    to_status VARCHAR(20) NOT NULL,
+    version INT NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (order_uid, version)
+);
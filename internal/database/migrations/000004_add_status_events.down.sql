@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_parked_status_events_next_attempt;
+DROP TABLE IF EXISTS parked_status_events;
+DROP TABLE IF EXISTS item_status_changes;
+ALTER TABLE orders DROP COLUMN IF EXISTS status_changed_at;
@@ -0,0 +1,28 @@
+-- Момент последней смены статуса заказа. События статусов из Kafka,
+-- произошедшие раньше, считаются устаревшими и не применяются.
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS status_changed_at TIMESTAMPTZ;
+
+-- Момент последней смены статуса товара. Хранится отдельно от items, так как
+-- товары заказа заменяются целиком при его обновлении.
+CREATE TABLE IF NOT EXISTS item_status_changes (
+    order_uid VARCHAR(255) NOT NULL REFERENCES orders(order_uid) ON DELETE CASCADE,
+    rid VARCHAR(255) NOT NULL,
+    changed_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (order_uid, rid)
+);
+
+-- События статусов для заказов и товаров, которые еще не сохранены.
+-- Применяются повторно, когда наступает next_attempt_at.
+CREATE TABLE IF NOT EXISTS parked_status_events (
+    id BIGSERIAL PRIMARY KEY,
+    order_uid VARCHAR(255) NOT NULL,
+    rid VARCHAR(255) NOT NULL DEFAULT '',
+    new_status VARCHAR(20) NOT NULL,
+    occurred_at TIMESTAMPTZ NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    parked_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    next_attempt_at TIMESTAMPTZ NOT NULL,
+    UNIQUE (order_uid, rid, new_status, occurred_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_parked_status_events_next_attempt ON parked_status_events (next_attempt_at);
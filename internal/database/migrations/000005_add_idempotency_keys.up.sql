@@ -0,0 +1,10 @@
+-- Ключи идемпотентности запросов приема заказов через HTTP API (заголовок
+-- Idempotency-Key). Пока запрос выполняется, status_code и response пусты;
+-- после завершения повторный запрос с тем же ключом получает сохраненный ответ.
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    key VARCHAR(255) PRIMARY KEY,
+    request_hash VARCHAR(64) NOT NULL,
+    status_code INT,
+    response BYTEA,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
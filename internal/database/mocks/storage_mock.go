@@ -10,9 +10,11 @@
 package mocks
 
 import (
+	database "L0_project/internal/database"
 	model "L0_project/internal/model"
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	gomock "go.uber.org/mock/gomock"
 )
@@ -41,6 +43,36 @@ func (m *MockStorage) EXPECT() *MockStorageMockRecorder {
 	return m.recorder
 }
 
+// ApplyStatusEvent mocks base method.
+func (m *MockStorage) ApplyStatusEvent(ctx context.Context, event model.StatusEvent) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApplyStatusEvent", ctx, event)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ApplyStatusEvent indicates an expected call of ApplyStatusEvent.
+func (mr *MockStorageMockRecorder) ApplyStatusEvent(ctx, event any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApplyStatusEvent", reflect.TypeOf((*MockStorage)(nil).ApplyStatusEvent), ctx, event)
+}
+
+// ClaimParkedStatusEvents mocks base method.
+func (m *MockStorage) ClaimParkedStatusEvents(ctx context.Context, limit int, retryAfter time.Duration) ([]model.ParkedStatusEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimParkedStatusEvents", ctx, limit, retryAfter)
+	ret0, _ := ret[0].([]model.ParkedStatusEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimParkedStatusEvents indicates an expected call of ClaimParkedStatusEvents.
+func (mr *MockStorageMockRecorder) ClaimParkedStatusEvents(ctx, limit, retryAfter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimParkedStatusEvents", reflect.TypeOf((*MockStorage)(nil).ClaimParkedStatusEvents), ctx, limit, retryAfter)
+}
+
 // Close mocks base method.
 func (m *MockStorage) Close() error {
 	m.ctrl.T.Helper()
@@ -55,19 +87,32 @@ func (mr *MockStorageMockRecorder) Close() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockStorage)(nil).Close))
 }
 
-// GetAllOrders mocks base method.
-func (m *MockStorage) GetAllOrders(ctx context.Context) ([]model.Order, error) {
+// CompleteIdempotencyKey mocks base method.
+func (m *MockStorage) CompleteIdempotencyKey(ctx context.Context, key string, statusCode int, response []byte) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAllOrders", ctx)
-	ret0, _ := ret[0].([]model.Order)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret := m.ctrl.Call(m, "CompleteIdempotencyKey", ctx, key, statusCode, response)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CompleteIdempotencyKey indicates an expected call of CompleteIdempotencyKey.
+func (mr *MockStorageMockRecorder) CompleteIdempotencyKey(ctx, key, statusCode, response any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompleteIdempotencyKey", reflect.TypeOf((*MockStorage)(nil).CompleteIdempotencyKey), ctx, key, statusCode, response)
 }
 
-// GetAllOrders indicates an expected call of GetAllOrders.
-func (mr *MockStorageMockRecorder) GetAllOrders(ctx any) *gomock.Call {
+// DeleteParkedStatusEvent mocks base method.
+func (m *MockStorage) DeleteParkedStatusEvent(ctx context.Context, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteParkedStatusEvent", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteParkedStatusEvent indicates an expected call of DeleteParkedStatusEvent.
+func (mr *MockStorageMockRecorder) DeleteParkedStatusEvent(ctx, id any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllOrders", reflect.TypeOf((*MockStorage)(nil).GetAllOrders), ctx)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteParkedStatusEvent", reflect.TypeOf((*MockStorage)(nil).DeleteParkedStatusEvent), ctx, id)
 }
 
 // GetOrderByUID mocks base method.
@@ -85,6 +130,79 @@ func (mr *MockStorageMockRecorder) GetOrderByUID(ctx, orderUID any) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrderByUID", reflect.TypeOf((*MockStorage)(nil).GetOrderByUID), ctx, orderUID)
 }
 
+// GetStatusHistory mocks base method.
+func (m *MockStorage) GetStatusHistory(ctx context.Context, orderUID string) (*model.StatusHistory, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStatusHistory", ctx, orderUID)
+	ret0, _ := ret[0].(*model.StatusHistory)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetStatusHistory indicates an expected call of GetStatusHistory.
+func (mr *MockStorageMockRecorder) GetStatusHistory(ctx, orderUID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStatusHistory", reflect.TypeOf((*MockStorage)(nil).GetStatusHistory), ctx, orderUID)
+}
+
+// ListOrders mocks base method.
+func (m *MockStorage) ListOrders(ctx context.Context, filter database.OrderFilter, cursor *database.OrderCursor) (*database.OrderPage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListOrders", ctx, filter, cursor)
+	ret0, _ := ret[0].(*database.OrderPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListOrders indicates an expected call of ListOrders.
+func (mr *MockStorageMockRecorder) ListOrders(ctx, filter, cursor any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListOrders", reflect.TypeOf((*MockStorage)(nil).ListOrders), ctx, filter, cursor)
+}
+
+// ParkStatusEvent mocks base method.
+func (m *MockStorage) ParkStatusEvent(ctx context.Context, event model.StatusEvent, retryAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ParkStatusEvent", ctx, event, retryAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ParkStatusEvent indicates an expected call of ParkStatusEvent.
+func (mr *MockStorageMockRecorder) ParkStatusEvent(ctx, event, retryAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ParkStatusEvent", reflect.TypeOf((*MockStorage)(nil).ParkStatusEvent), ctx, event, retryAt)
+}
+
+// ReleaseIdempotencyKey mocks base method.
+func (m *MockStorage) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseIdempotencyKey", ctx, key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReleaseIdempotencyKey indicates an expected call of ReleaseIdempotencyKey.
+func (mr *MockStorageMockRecorder) ReleaseIdempotencyKey(ctx, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseIdempotencyKey", reflect.TypeOf((*MockStorage)(nil).ReleaseIdempotencyKey), ctx, key)
+}
+
+// ReserveIdempotencyKey mocks base method.
+func (m *MockStorage) ReserveIdempotencyKey(ctx context.Context, key, requestHash string, ttl time.Duration) (*database.IdempotencyRecord, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReserveIdempotencyKey", ctx, key, requestHash, ttl)
+	ret0, _ := ret[0].(*database.IdempotencyRecord)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReserveIdempotencyKey indicates an expected call of ReserveIdempotencyKey.
+func (mr *MockStorageMockRecorder) ReserveIdempotencyKey(ctx, key, requestHash, ttl any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReserveIdempotencyKey", reflect.TypeOf((*MockStorage)(nil).ReserveIdempotencyKey), ctx, key, requestHash, ttl)
+}
+
 // SaveOrder mocks base method.
 func (m *MockStorage) SaveOrder(ctx context.Context, order *model.Order) error {
 	m.ctrl.T.Helper()
@@ -98,3 +216,47 @@ func (mr *MockStorageMockRecorder) SaveOrder(ctx, order any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveOrder", reflect.TypeOf((*MockStorage)(nil).SaveOrder), ctx, order)
 }
+
+// SaveOrders mocks base method.
+func (m *MockStorage) SaveOrders(ctx context.Context, orders []*model.Order) ([]error, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveOrders", ctx, orders)
+	ret0, _ := ret[0].([]error)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveOrders indicates an expected call of SaveOrders.
+func (mr *MockStorageMockRecorder) SaveOrders(ctx, orders any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveOrders", reflect.TypeOf((*MockStorage)(nil).SaveOrders), ctx, orders)
+}
+
+// StreamRecentOrders mocks base method.
+func (m *MockStorage) StreamRecentOrders(ctx context.Context, limit int, fn func(*model.Order) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StreamRecentOrders", ctx, limit, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StreamRecentOrders indicates an expected call of StreamRecentOrders.
+func (mr *MockStorageMockRecorder) StreamRecentOrders(ctx, limit, fn any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamRecentOrders", reflect.TypeOf((*MockStorage)(nil).StreamRecentOrders), ctx, limit, fn)
+}
+
+// TransitionStatus mocks base method.
+func (m *MockStorage) TransitionStatus(ctx context.Context, transition database.StatusTransition) (*model.StatusChange, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TransitionStatus", ctx, transition)
+	ret0, _ := ret[0].(*model.StatusChange)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TransitionStatus indicates an expected call of TransitionStatus.
+func (mr *MockStorageMockRecorder) TransitionStatus(ctx, transition any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TransitionStatus", reflect.TypeOf((*MockStorage)(nil).TransitionStatus), ctx, transition)
+}
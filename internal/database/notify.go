@@ -0,0 +1,60 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// OrderChangesChannel - канал LISTEN/NOTIFY, в который публикуются изменения заказов.
+const OrderChangesChannel = "order_changes"
+
+// OrderChange - уведомление об изменении заказа.
+type OrderChange struct {
+	OrderUID string `json:"order_uid"`
+	Source   string `json:"source"` // InstanceID экземпляра, изменившего заказ
+}
+
+// instanceID идентифицирует процесс в уведомлениях об изменениях, чтобы
+// экземпляр мог отличить собственные изменения от изменений других реплик.
+var instanceID = newInstanceID()
+
+// InstanceID возвращает идентификатор текущего процесса в уведомлениях об изменениях.
+func InstanceID() string {
+	return instanceID
+}
+
+func newInstanceID() string {
+	buf := make([]byte, 8)
+	_, _ = rand.Read(buf) // crypto/rand.Read не возвращает ошибок
+	return hex.EncodeToString(buf)
+}
+
+// notifyOrdersChanged публикует уведомления об изменении заказов в рамках транзакции.
+// Postgres доставляет их подписчикам только после фиксации транзакции и не
+// доставляет вовсе при ее откате.
+func notifyOrdersChanged(ctx context.Context, tx *sqlx.Tx, orderUIDs ...string) error {
+	if len(orderUIDs) == 0 {
+		return nil
+	}
+
+	payloads := make([]string, len(orderUIDs))
+	for i, uid := range orderUIDs {
+		payload, err := json.Marshal(OrderChange{OrderUID: uid, Source: instanceID})
+		if err != nil {
+			return fmt.Errorf("ошибка сериализации уведомления об изменении заказа: %w", err)
+		}
+		payloads[i] = string(payload)
+	}
+
+	query := `SELECT pg_notify($1, payload) FROM unnest($2::text[]) AS payload`
+	if _, err := tx.ExecContext(ctx, query, OrderChangesChannel, pq.Array(payloads)); err != nil {
+		return fmt.Errorf("ошибка публикации уведомления об изменении заказа: %w", err)
+	}
+	return nil
+}
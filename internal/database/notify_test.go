@@ -0,0 +1,195 @@
+package database
+
+import (
+	"L0_project/internal/metrics"
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// orderChangesArg проверяет, что pg_notify получает уведомления о заданных заказах
+// от текущего экземпляра.
+type orderChangesArg []string
+
+func (a orderChangesArg) Match(v driver.Value) bool {
+	var payloads pq.StringArray
+	if err := payloads.Scan(v); err != nil {
+		return false
+	}
+
+	uids := make([]string, 0, len(payloads))
+	for _, payload := range payloads {
+		var change OrderChange
+		if err := json.Unmarshal([]byte(payload), &change); err != nil || change.Source != InstanceID() {
+			return false
+		}
+		uids = append(uids, change.OrderUID)
+	}
+	return slices.Equal(uids, a)
+}
+
+// expectNotify ожидает публикацию уведомлений об изменении заказов
+func expectNotify(mock sqlmock.Sqlmock, uids ...string) {
+	mock.ExpectExec(`SELECT pg_notify\(\$1, payload\) FROM unnest\(\$2::text\[\]\)`).
+		WithArgs(OrderChangesChannel, orderChangesArg(uids)).
+		WillReturnResult(sqlmock.NewResult(0, int64(len(uids))))
+}
+
+// fakeListener - управляемая из теста замена pq.Listener
+type fakeListener struct {
+	notifications chan *pq.Notification
+	mu            sync.Mutex
+	channels      []string
+	closed        bool
+}
+
+func newFakeListener() *fakeListener {
+	return &fakeListener{notifications: make(chan *pq.Notification)}
+}
+
+func (f *fakeListener) Listen(channel string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.channels = append(f.channels, channel)
+	return nil
+}
+
+func (f *fakeListener) NotificationChannel() <-chan *pq.Notification { return f.notifications }
+func (f *fakeListener) Ping() error                                  { return nil }
+
+func (f *fakeListener) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	return nil
+}
+
+func changeNotification(t *testing.T, uid, source string) *pq.Notification {
+	t.Helper()
+	payload, err := json.Marshal(OrderChange{OrderUID: uid, Source: source})
+	require.NoError(t, err)
+	return &pq.Notification{Channel: OrderChangesChannel, Extra: string(payload)}
+}
+
+func TestOrderChangeListener_Run(t *testing.T) {
+	fake := newFakeListener()
+	changed := make(chan string, 10)
+	reconnects := make(chan struct{}, 10)
+	listener := newOrderChangeListener(fake,
+		func(_ context.Context, uid string) { changed <- uid },
+		func(context.Context) { reconnects <- struct{}{} },
+	)
+
+	applied := testutil.ToFloat64(metrics.CacheInvalidations.WithLabelValues("applied"))
+	own := testutil.ToFloat64(metrics.CacheInvalidations.WithLabelValues("own"))
+	invalid := testutil.ToFloat64(metrics.CacheInvalidations.WithLabelValues("invalid"))
+	reconnected := testutil.ToFloat64(metrics.CacheInvalidationReconnects)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		listener.Run(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-listener.Subscribed():
+	case <-time.After(time.Second):
+		t.Fatal("подписка не сообщила о готовности")
+	}
+
+	// Небуферизованный канал: каждая отправка дожидается приема предыдущего уведомления
+	fake.notifications <- changeNotification(t, "own-uid", InstanceID())
+	fake.notifications <- &pq.Notification{Channel: OrderChangesChannel, Extra: "not json"}
+	fake.notifications <- changeNotification(t, "uid-1", "other-instance")
+	fake.notifications <- nil // Переподключение
+
+	assert.Equal(t, "uid-1", <-changed)
+	<-reconnects
+	cancel()
+	<-done
+
+	assert.Empty(t, changed, "собственные и некорректные уведомления не применяются")
+	assert.Equal(t, []string{OrderChangesChannel}, fake.channels)
+	assert.True(t, fake.closed)
+	assert.Equal(t, applied+1, testutil.ToFloat64(metrics.CacheInvalidations.WithLabelValues("applied")))
+	assert.Equal(t, own+1, testutil.ToFloat64(metrics.CacheInvalidations.WithLabelValues("own")))
+	assert.Equal(t, invalid+1, testutil.ToFloat64(metrics.CacheInvalidations.WithLabelValues("invalid")))
+	assert.Equal(t, reconnected+1, testutil.ToFloat64(metrics.CacheInvalidationReconnects))
+}
+
+func TestOrderChangeListener_PingsIdleConnection(t *testing.T) {
+	fake := &pingCountingListener{fakeListener: newFakeListener(), pinged: make(chan struct{}, 1)}
+	listener := newOrderChangeListener(fake, func(context.Context, string) {}, func(context.Context) {})
+	listener.pingInterval = 10 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go listener.Run(ctx)
+
+	select {
+	case <-fake.pinged:
+	case <-time.After(time.Second):
+		t.Fatal("соединение не проверяется при отсутствии уведомлений")
+	}
+}
+
+func TestOrderChangeListener_OnePingInFlight(t *testing.T) {
+	fake := &blockingPingListener{fakeListener: newFakeListener(), release: make(chan struct{})}
+	listener := newOrderChangeListener(fake, func(context.Context, string) {}, func(context.Context) {})
+	listener.pingInterval = time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		listener.Run(ctx)
+		close(done)
+	}()
+
+	// Проверка зависла (сетевой разрыв): новые проверки не запускаются
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(1), fake.calls.Load())
+
+	cancel()
+	<-done
+	close(fake.release)
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, int32(1), fake.calls.Load(), "после остановки проверки не выполняются")
+}
+
+// blockingPingListener - подписка, у которой Ping зависает до release
+type blockingPingListener struct {
+	*fakeListener
+	calls   atomic.Int32
+	release chan struct{}
+}
+
+func (p *blockingPingListener) Ping() error {
+	p.calls.Add(1)
+	<-p.release
+	return nil
+}
+
+type pingCountingListener struct {
+	*fakeListener
+	pinged chan struct{}
+}
+
+func (p *pingCountingListener) Ping() error {
+	select {
+	case p.pinged <- struct{}{}:
+	default:
+	}
+	return nil
+}
@@ -8,12 +8,14 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"strings"
+	"time"
 
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	"github.com/jmoiron/sqlx"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/trace"
 )
@@ -23,8 +25,19 @@ import (
 // Storage определяет интерфейс для работы с хранилищем заказов.
 type Storage interface {
 	SaveOrder(ctx context.Context, order *model.Order) error
+	SaveOrders(ctx context.Context, orders []*model.Order) ([]error, error)
 	GetOrderByUID(ctx context.Context, orderUID string) (*model.Order, error)
-	GetAllOrders(ctx context.Context) ([]model.Order, error)
+	StreamRecentOrders(ctx context.Context, limit int, fn func(order *model.Order) error) error
+	ListOrders(ctx context.Context, filter OrderFilter, cursor *OrderCursor) (*OrderPage, error)
+	TransitionStatus(ctx context.Context, transition StatusTransition) (*model.StatusChange, error)
+	GetStatusHistory(ctx context.Context, orderUID string) (*model.StatusHistory, error)
+	ApplyStatusEvent(ctx context.Context, event model.StatusEvent) (bool, error)
+	ParkStatusEvent(ctx context.Context, event model.StatusEvent, retryAt time.Time) error
+	ClaimParkedStatusEvents(ctx context.Context, limit int, retryAfter time.Duration) ([]model.ParkedStatusEvent, error)
+	DeleteParkedStatusEvent(ctx context.Context, id int64) error
+	ReserveIdempotencyKey(ctx context.Context, key, requestHash string, ttl time.Duration) (*IdempotencyRecord, error)
+	CompleteIdempotencyKey(ctx context.Context, key string, statusCode int, response []byte) error
+	ReleaseIdempotencyKey(ctx context.Context, key string) error
 	Close() error
 }
 
@@ -83,11 +96,30 @@ func runMigrations(dbURL, migrationsPath string) error {
 }
 
 // SaveOrder сохраняет заказ и все связанные с ним данные в одной транзакции.
-func (s *postgresStorage) SaveOrder(ctx context.Context, order *model.Order) (err error) {
+// Операция идемпотентна: если заказ с таким UID уже сохранен и данные совпадают,
+// возвращается ErrOrderUnchanged; если данные отличаются — заказ обновляется.
+// Если уникальное поле (транзакция платежа, трек-номер) принадлежит другому
+// заказу, возвращается *OrderConflictError. Об изменении заказа публикуется
+// уведомление в OrderChangesChannel (доставляется после фиксации транзакции).
+//
+// Две одновременные первые вставки одного заказа (например, из Kafka и HTTP API)
+// сталкиваются на уникальном ограничении: проигравшая транзакция повторяется
+// один раз и находит заказ, сохраненный параллельно, как существующий. Конфликт
+// с другим заказом не повторяется (см. isInsertRace).
+func (s *postgresStorage) SaveOrder(ctx context.Context, order *model.Order) error {
 	// Создаем span для трассировки
 	ctx, span := s.tracer.Start(ctx, "DB.SaveOrder")
 	defer span.End()
 
+	err := s.saveOrderOnce(ctx, order)
+	if isInsertRace(ctx, s.db, order, err) {
+		err = s.saveOrderOnce(ctx, order)
+	}
+	return err
+}
+
+// saveOrderOnce сохраняет заказ в отдельной транзакции.
+func (s *postgresStorage) saveOrderOnce(ctx context.Context, order *model.Order) (err error) {
 	var tx *sqlx.Tx
 	tx, err = s.db.BeginTxx(ctx, nil)
 
@@ -110,34 +142,145 @@ func (s *postgresStorage) SaveOrder(ctx context.Context, order *model.Order) (er
 		}
 	}()
 
+	if err = saveOrderTx(ctx, tx, order); err != nil {
+		return err
+	}
+	if err = notifyOrdersChanged(ctx, tx, order.OrderUID); err != nil {
+		return err
+	}
+
+	// Если все успешно, коммитим. Ошибка (nil или реальная) будет возвращена.
+	err = tx.Commit()
+	return err
+}
+
+// paymentsTransactionKey - имя ограничения уникальности транзакции платежа.
+const paymentsTransactionKey = "payments_transaction_key"
+
+// isInsertRace сообщает, что err - нарушение уникальности из-за параллельной
+// вставки того же заказа, а не конфликт с другим заказом: нарушен первичный
+// ключ orders или ключ транзакции платежа, которая уже принадлежит этому заказу
+// (платеж вставляется раньше заказа, поэтому гонка обычно проявляется на нем).
+// Повторная попытка в этом случае найдет заказ как существующий.
+//
+// Принадлежность транзакции проверяется отдельным запросом через q; в
+// транзакции q ее нужно предварительно откатить к точке сохранения.
+func isInsertRace(ctx context.Context, q sqlx.QueryerContext, order *model.Order, err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code != pgUniqueViolation {
+		return false
+	}
+
+	switch pqErr.Constraint {
+	case ordersPrimaryKey:
+		return true
+	case paymentsTransactionKey:
+		var owner string
+		query := `SELECT o.order_uid FROM orders o JOIN payments p ON p.id = o.payment_id WHERE p.transaction = $1`
+		if err := sqlx.GetContext(ctx, q, &owner, query, order.Payment.Transaction); err != nil {
+			return false
+		}
+		return owner == order.OrderUID
+	}
+	return false
+}
+
+// saveOrderTx добавляет новый заказ или обновляет существующий в рамках транзакции.
+func saveOrderTx(ctx context.Context, tx *sqlx.Tx, order *model.Order) error {
+	// Блокируем существующую запись (если есть), чтобы параллельная повторная
+	// доставка того же сообщения не обновляла заказ одновременно с нами.
+	var existing struct {
+		DeliveryID int `db:"delivery_id"`
+		PaymentID  int `db:"payment_id"`
+	}
+	err := tx.GetContext(ctx, &existing, `SELECT delivery_id, payment_id FROM orders WHERE order_uid = $1 FOR UPDATE`, order.OrderUID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		err = insertOrder(ctx, tx, order)
+	case err != nil:
+		return fmt.Errorf("ошибка проверки существующего заказа: %w", err)
+	default:
+		err = updateOrder(ctx, tx, order, existing.DeliveryID, existing.PaymentID)
+	}
+	return asConflict(order.OrderUID, err)
+}
+
+// insertOrder добавляет новый заказ со всеми связанными данными в рамках транзакции.
+func insertOrder(ctx context.Context, tx *sqlx.Tx, order *model.Order) error {
 	deliveryQuery := `INSERT INTO deliveries (name, phone, zip, city, address, region, email) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`
 	var deliveryID int
-	// Присваиваем ошибку именованной err
-	if err = tx.GetContext(ctx, &deliveryID, deliveryQuery, order.Delivery.Name, order.Delivery.Phone, order.Delivery.Zip, order.Delivery.City, order.Delivery.Address, order.Delivery.Region, order.Delivery.Email); err != nil {
+	if err := tx.GetContext(ctx, &deliveryID, deliveryQuery, order.Delivery.Name, order.Delivery.Phone, order.Delivery.Zip, order.Delivery.City, order.Delivery.Address, order.Delivery.Region, order.Delivery.Email); err != nil {
 		return fmt.Errorf("ошибка сохранения доставки: %w", err)
 	}
 
 	paymentQuery := `INSERT INTO payments (transaction, request_id, currency, provider, amount, payment_dt, bank, delivery_cost, goods_total, custom_fee) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id`
 	var paymentID int
-	if err = tx.GetContext(ctx, &paymentID, paymentQuery, order.Payment.Transaction, order.Payment.RequestID, order.Payment.Currency, order.Payment.Provider, order.Payment.Amount, order.Payment.PaymentDt, order.Payment.Bank, order.Payment.DeliveryCost, order.Payment.GoodsTotal, order.Payment.CustomFee); err != nil {
+	if err := tx.GetContext(ctx, &paymentID, paymentQuery, order.Payment.Transaction, order.Payment.RequestID, order.Payment.Currency, order.Payment.Provider, order.Payment.Amount, order.Payment.PaymentDt, order.Payment.Bank, order.Payment.DeliveryCost, order.Payment.GoodsTotal, order.Payment.CustomFee); err != nil {
 		return fmt.Errorf("ошибка сохранения платежа: %w", err)
 	}
 
 	orderQuery := `INSERT INTO orders (order_uid, track_number, entry, delivery_id, payment_id, locale, internal_signature, customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`
-	if _, err = tx.ExecContext(ctx, orderQuery, order.OrderUID, order.TrackNumber, order.Entry, deliveryID, paymentID, order.Locale, order.InternalSignature, order.CustomerID, order.DeliveryService, order.Shardkey, order.SmID, order.DateCreated, order.OofShard); err != nil {
+	if _, err := tx.ExecContext(ctx, orderQuery, order.OrderUID, order.TrackNumber, order.Entry, deliveryID, paymentID, order.Locale, order.InternalSignature, order.CustomerID, order.DeliveryService, order.Shardkey, order.SmID, order.DateCreated, order.OofShard); err != nil {
 		return fmt.Errorf("ошибка сохранения заказа: %w", err)
 	}
 
+	return insertItems(ctx, tx, order, nil)
+}
+
+// updateOrder сравнивает входящий заказ с сохраненным и, если данные отличаются,
+// заменяет доставку, платеж, поля заказа и товары. Для идентичного заказа
+// возвращает ErrOrderUnchanged, ничего не изменяя. Статусы товаров, которые
+// уже есть в заказе, ведут события статуса, поэтому они сохраняются.
+func updateOrder(ctx context.Context, tx *sqlx.Tx, order *model.Order, deliveryID, paymentID int) error {
+	stored, err := loadOrder(ctx, tx, order.OrderUID)
+	if err != nil {
+		return err
+	}
+	if sameOrder(stored, order) {
+		return ErrOrderUnchanged
+	}
+
+	deliveryQuery := `UPDATE deliveries SET name = $1, phone = $2, zip = $3, city = $4, address = $5, region = $6, email = $7 WHERE id = $8`
+	if _, err := tx.ExecContext(ctx, deliveryQuery, order.Delivery.Name, order.Delivery.Phone, order.Delivery.Zip, order.Delivery.City, order.Delivery.Address, order.Delivery.Region, order.Delivery.Email, deliveryID); err != nil {
+		return fmt.Errorf("ошибка обновления доставки: %w", err)
+	}
+
+	paymentQuery := `UPDATE payments SET transaction = $1, request_id = $2, currency = $3, provider = $4, amount = $5, payment_dt = $6, bank = $7, delivery_cost = $8, goods_total = $9, custom_fee = $10 WHERE id = $11`
+	if _, err := tx.ExecContext(ctx, paymentQuery, order.Payment.Transaction, order.Payment.RequestID, order.Payment.Currency, order.Payment.Provider, order.Payment.Amount, order.Payment.PaymentDt, order.Payment.Bank, order.Payment.DeliveryCost, order.Payment.GoodsTotal, order.Payment.CustomFee, paymentID); err != nil {
+		return fmt.Errorf("ошибка обновления платежа: %w", err)
+	}
+
+	orderQuery := `UPDATE orders SET track_number = $2, entry = $3, locale = $4, internal_signature = $5, customer_id = $6, delivery_service = $7, shardkey = $8, sm_id = $9, date_created = $10, oof_shard = $11 WHERE order_uid = $1`
+	if _, err := tx.ExecContext(ctx, orderQuery, order.OrderUID, order.TrackNumber, order.Entry, order.Locale, order.InternalSignature, order.CustomerID, order.DeliveryService, order.Shardkey, order.SmID, order.DateCreated, order.OofShard); err != nil {
+		return fmt.Errorf("ошибка обновления заказа: %w", err)
+	}
+
+	// Состав заказа заменяется целиком, статусы существующих товаров сохраняются.
+	statuses := make(map[string]int, len(stored.Items))
+	for _, item := range stored.Items {
+		statuses[item.Rid] = item.Status
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM items WHERE order_uid = $1`, order.OrderUID); err != nil {
+		return fmt.Errorf("ошибка удаления товаров: %w", err)
+	}
+
+	return insertItems(ctx, tx, order, statuses)
+}
+
+// insertItems добавляет товары заказа в рамках транзакции. Для товаров из
+// statuses (по rid) записывается статус оттуда, а не из заказа.
+func insertItems(ctx context.Context, tx *sqlx.Tx, order *model.Order, statuses map[string]int) error {
 	for _, item := range order.Items {
+		status := item.Status
+		if stored, ok := statuses[item.Rid]; ok {
+			status = stored
+		}
 		itemQuery := `INSERT INTO items (order_uid, chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
-		if _, err = tx.ExecContext(ctx, itemQuery, order.OrderUID, item.ChrtID, item.TrackNumber, item.Price, item.Rid, item.Name, item.Sale, item.Size, item.TotalPrice, item.NmID, item.Brand, item.Status); err != nil {
+		if _, err := tx.ExecContext(ctx, itemQuery, order.OrderUID, item.ChrtID, item.TrackNumber, item.Price, item.Rid, item.Name, item.Sale, item.Size, item.TotalPrice, item.NmID, item.Brand, status); err != nil {
 			return fmt.Errorf("ошибка сохранения товара: %w", err)
 		}
 	}
-
-	// Если все успешно, коммитим. Ошибка (nil или реальная) будет возвращена.
-	err = tx.Commit()
-	return err
+	return nil
 }
 
 // GetOrderByUID извлекает полный объект заказа по его UID.
@@ -146,11 +289,17 @@ func (s *postgresStorage) GetOrderByUID(ctx context.Context, orderUID string) (*
 	ctx, span := s.tracer.Start(ctx, "DB.GetOrderByUID")
 	defer span.End()
 
+	return loadOrder(ctx, s.db, orderUID)
+}
+
+// loadOrder читает заказ с доставкой, платежом и товарами через переданный
+// исполнитель запросов (подключение или транзакцию).
+func loadOrder(ctx context.Context, q sqlx.QueryerContext, orderUID string) (*model.Order, error) {
 	var order model.Order
 	query := `
         SELECT
             o.order_uid, o.track_number, o.entry, o.locale, o.internal_signature, o.customer_id, o.delivery_service,
-            o.shardkey, o.sm_id, o.date_created, o.oof_shard,
+            o.shardkey, o.sm_id, o.date_created, o.oof_shard, o.status, o.status_version,
             d.name "delivery.name", d.phone "delivery.phone", d.zip "delivery.zip", d.city "delivery.city",
             d.address "delivery.address", d.region "delivery.region", d.email "delivery.email",
             p.transaction "payment.transaction", p.request_id "payment.request_id", p.currency "payment.currency",
@@ -161,12 +310,14 @@ func (s *postgresStorage) GetOrderByUID(ctx context.Context, orderUID string) (*
         JOIN payments p ON o.payment_id = p.id
         WHERE o.order_uid = $1`
 
-	if err := s.db.GetContext(ctx, &order, query, orderUID); err != nil {
-		metrics.DBErrors.WithLabelValues("get_order").Inc() // Метрика ошибки
+	if err := sqlx.GetContext(ctx, q, &order, query, orderUID); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			metrics.DBErrors.WithLabelValues("get_order").Inc() // Метрика ошибки (отсутствие заказа ошибкой не считается)
+		}
 		return nil, fmt.Errorf("не удалось получить заказ: %w", err)
 	}
 
-	if err := s.db.SelectContext(ctx, &order.Items, `SELECT * FROM items WHERE order_uid = $1`, orderUID); err != nil {
+	if err := sqlx.SelectContext(ctx, q, &order.Items, `SELECT * FROM items WHERE order_uid = $1 ORDER BY id`, orderUID); err != nil {
 		metrics.DBErrors.WithLabelValues("get_items").Inc() // Метрика ошибки
 		return nil, fmt.Errorf("не удалось получить товары для заказа: %w", err)
 	}
@@ -174,66 +325,163 @@ func (s *postgresStorage) GetOrderByUID(ctx context.Context, orderUID string) (*
 	return &order, nil
 }
 
-// GetAllOrders извлекает все заказы из БД для прогрева кэша.
-func (s *postgresStorage) GetAllOrders(ctx context.Context) ([]model.Order, error) {
+// StreamRecentOrders передает в fn до limit самых свежих заказов (по date_created)
+// вместе с товарами. Строки читаются курсором по мере обработки, поэтому в памяти
+// одновременно находится только один заказ. Заказы передаются от более старых к
+// более свежим, чтобы при вставке в кэш самые свежие оказались наиболее ценными.
+// Ошибка fn прерывает чтение и возвращается без изменений.
+func (s *postgresStorage) StreamRecentOrders(ctx context.Context, limit int, fn func(order *model.Order) error) error {
 	// Создаем span для трассировки
-	ctx, span := s.tracer.Start(ctx, "DB.GetAllOrders")
+	ctx, span := s.tracer.Start(ctx, "DB.StreamRecentOrders")
 	defer span.End()
 
-	// Этот запрос получает все данные одним махом, избегая проблемы N+1.
+	// Товары присоединяются к заказам в том же запросе, строки одного заказа идут подряд.
+	// У заказа без товаров поля товара пустые (COALESCE), такой товар пропускается.
 	query := `
+        WITH recent AS (
+            SELECT order_uid FROM orders
+            ORDER BY date_created DESC, order_uid DESC
+            LIMIT $1
+        )
         SELECT
-            o.order_uid, o.track_number, o.entry, o.locale, o.internal_signature, o.customer_id, 
-            o.delivery_service, o.shardkey, o.sm_id, o.date_created, o.oof_shard,
-
-            d.id "delivery.id", d.name "delivery.name", d.phone "delivery.phone", d.zip "delivery.zip", d.city "delivery.city", d.address "delivery.address", d.region "delivery.region", d.email "delivery.email",
-            p.id "payment.id", p.transaction "payment.transaction", p.request_id "payment.request_id", p.currency "payment.currency", p.provider "payment.provider", p.amount "payment.amount", p.payment_dt "payment.payment_dt", p.bank "payment.bank", p.delivery_cost "payment.delivery_cost", p.goods_total "payment.goods_total", p.custom_fee "payment.custom_fee",
-            i.id "items.id", i.chrt_id "items.chrt_id", i.track_number "items.track_number", i.price "items.price", i.rid "items.rid", i.name "items.name", i.sale "items.sale", i.size "items.size", i.total_price "items.total_price", i.nm_id "items.nm_id", i.brand "items.brand", i.status "items.status",
-            
-            i.order_uid "items.order_uid"
-
-		FROM orders o
-        LEFT JOIN deliveries d ON o.delivery_id = d.id
-        LEFT JOIN payments p ON o.payment_id = p.id
-        LEFT JOIN items i ON o.order_uid = i.order_uid
-        ORDER BY o.date_created DESC`
-
-	type fullOrderRow struct {
-		model.Order
-		model.Delivery `db:"delivery"`
-		model.Payment  `db:"payment"`
-		model.Item     `db:"items"`
+            o.order_uid, o.track_number, o.entry, o.locale, o.internal_signature, o.customer_id, o.delivery_service,
+            o.shardkey, o.sm_id, o.date_created, o.oof_shard, o.status, o.status_version,
+            d.name "delivery.name", d.phone "delivery.phone", d.zip "delivery.zip", d.city "delivery.city",
+            d.address "delivery.address", d.region "delivery.region", d.email "delivery.email",
+            p.transaction "payment.transaction", p.request_id "payment.request_id", p.currency "payment.currency",
+            p.provider "payment.provider", p.amount "payment.amount", p.payment_dt "payment.payment_dt", p.bank "payment.bank",
+            p.delivery_cost "payment.delivery_cost", p.goods_total "payment.goods_total", p.custom_fee "payment.custom_fee",
+            COALESCE(i.id, 0) "item.id", COALESCE(i.chrt_id, 0) "item.chrt_id", COALESCE(i.track_number, '') "item.track_number",
+            COALESCE(i.price, 0) "item.price", COALESCE(i.rid, '') "item.rid", COALESCE(i.name, '') "item.name",
+            COALESCE(i.sale, 0) "item.sale", COALESCE(i.size, '') "item.size", COALESCE(i.total_price, 0) "item.total_price",
+            COALESCE(i.nm_id, 0) "item.nm_id", COALESCE(i.brand, '') "item.brand", COALESCE(i.status, 0) "item.status"
+        FROM recent r
+        JOIN orders o ON o.order_uid = r.order_uid
+        JOIN deliveries d ON o.delivery_id = d.id
+        JOIN payments p ON o.payment_id = p.id
+        LEFT JOIN items i ON i.order_uid = o.order_uid
+        ORDER BY o.date_created, o.order_uid, i.id`
+
+	rows, err := s.db.QueryxContext(ctx, query, limit)
+	if err != nil {
+		metrics.DBErrors.WithLabelValues("stream_recent_orders").Inc() // Метрика ошибки
+		return fmt.Errorf("ошибка получения последних заказов: %w", err)
 	}
+	defer rows.Close()
 
-	var rows []fullOrderRow
-	if err := s.db.SelectContext(ctx, &rows, query); err != nil {
-		metrics.DBErrors.WithLabelValues("get_all_orders").Inc() // Метрика ошибки
-		return nil, fmt.Errorf("ошибка получения всех заказов: %w", err)
+	type orderItemRow struct {
+		model.Order
+		Item model.Item `db:"item"`
 	}
 
-	// Группируем товары по заказам.
-	ordersMap := make(map[string]*model.Order)
-	for _, row := range rows {
-		// Используем row.Order.OrderUID, чтобы явно указать поле из model.Order
-		if _, exists := ordersMap[row.Order.OrderUID]; !exists {
+	var current *model.Order
+	for rows.Next() {
+		var row orderItemRow
+		if err := rows.StructScan(&row); err != nil {
+			metrics.DBErrors.WithLabelValues("stream_recent_orders").Inc() // Метрика ошибки
+			return fmt.Errorf("ошибка чтения строки заказа: %w", err)
+		}
+
+		if current == nil || current.OrderUID != row.Order.OrderUID {
+			if current != nil {
+				if err := fn(current); err != nil {
+					return err
+				}
+			}
 			order := row.Order
-			order.Delivery = row.Delivery
-			order.Payment = row.Payment
 			order.Items = []model.Item{}
-			ordersMap[order.OrderUID] = &order
+			current = &order
 		}
 		if row.Item.ID > 0 { // Проверяем, что товар существует
-			order := ordersMap[row.Order.OrderUID]
-			order.Items = append(order.Items, row.Item)
+			row.Item.OrderUID = current.OrderUID
+			current.Items = append(current.Items, row.Item)
 		}
 	}
+	if err := rows.Err(); err != nil {
+		metrics.DBErrors.WithLabelValues("stream_recent_orders").Inc() // Метрика ошибки
+		return fmt.Errorf("ошибка получения последних заказов: %w", err)
+	}
+
+	if current != nil {
+		return fn(current)
+	}
+	return nil
+}
+
+// ListOrders возвращает страницу заказов, отсортированных по (date_created, order_uid)
+// в порядке убывания, с учетом фильтров. Пагинация keyset-based: следующая страница
+// запрашивается с курсором, возвращенным в OrderPage.NextCursor.
+func (s *postgresStorage) ListOrders(ctx context.Context, filter OrderFilter, cursor *OrderCursor) (*OrderPage, error) {
+	// Создаем span для трассировки
+	ctx, span := s.tracer.Start(ctx, "DB.ListOrders")
+	defer span.End()
+
+	limit := filter.normalizedLimit()
+	conditions, args := buildListConditions(filter, cursor)
 
-	orders := make([]model.Order, 0, len(ordersMap))
-	for _, order := range ordersMap {
-		orders = append(orders, *order)
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	// Запрашиваем на одну запись больше, чтобы понять, есть ли следующая страница.
+	args = append(args, limit+1)
+	query := fmt.Sprintf(`
+        SELECT
+            o.order_uid, o.track_number, o.entry, o.locale, o.internal_signature, o.customer_id, o.delivery_service,
+            o.shardkey, o.sm_id, o.date_created, o.oof_shard, o.status, o.status_version,
+            d.name "delivery.name", d.phone "delivery.phone", d.zip "delivery.zip", d.city "delivery.city",
+            d.address "delivery.address", d.region "delivery.region", d.email "delivery.email",
+            p.transaction "payment.transaction", p.request_id "payment.request_id", p.currency "payment.currency",
+            p.provider "payment.provider", p.amount "payment.amount", p.payment_dt "payment.payment_dt", p.bank "payment.bank",
+            p.delivery_cost "payment.delivery_cost", p.goods_total "payment.goods_total", p.custom_fee "payment.custom_fee"
+        FROM orders o
+        JOIN deliveries d ON o.delivery_id = d.id
+        JOIN payments p ON o.payment_id = p.id
+        %s
+        ORDER BY o.date_created DESC, o.order_uid DESC
+        LIMIT $%d`, where, len(args))
+
+	var orders []model.Order
+	if err := s.db.SelectContext(ctx, &orders, query, args...); err != nil {
+		metrics.DBErrors.WithLabelValues("list_orders").Inc() // Метрика ошибки
+		return nil, fmt.Errorf("не удалось получить список заказов: %w", err)
+	}
+
+	page := &OrderPage{Orders: orders}
+	if len(orders) > limit {
+		page.Orders = orders[:limit]
+		last := page.Orders[limit-1]
+		page.NextCursor = &OrderCursor{DateCreated: last.DateCreated, OrderUID: last.OrderUID}
+	}
+
+	if len(page.Orders) == 0 {
+		page.Orders = []model.Order{}
+		return page, nil
+	}
+
+	// Загружаем товары для всей страницы одним запросом.
+	uids := make([]string, len(page.Orders))
+	byUID := make(map[string]*model.Order, len(page.Orders))
+	for i := range page.Orders {
+		page.Orders[i].Items = []model.Item{}
+		uids[i] = page.Orders[i].OrderUID
+		byUID[uids[i]] = &page.Orders[i]
+	}
+
+	var items []model.Item
+	if err := s.db.SelectContext(ctx, &items, `SELECT * FROM items WHERE order_uid = ANY($1) ORDER BY id`, pq.Array(uids)); err != nil {
+		metrics.DBErrors.WithLabelValues("get_items").Inc() // Метрика ошибки
+		return nil, fmt.Errorf("не удалось получить товары для списка заказов: %w", err)
+	}
+
+	for _, item := range items {
+		if order, ok := byUID[item.OrderUID]; ok {
+			order.Items = append(order.Items, item)
+		}
 	}
 
-	return orders, nil
+	return page, nil
 }
 
 // Close закрывает соединение с БД.
@@ -10,6 +10,7 @@ import (
 
 	sqlmock "github.com/DATA-DOG/go-sqlmock"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 	"go.opentelemetry.io/otel"
 )
@@ -46,6 +47,36 @@ func setupStorageWithMock(t *testing.T) (Storage, sqlmock.Sqlmock) {
 	return storage, mock
 }
 
+// expectNoExistingOrder ожидает проверку существования заказа, которая ничего не находит
+func expectNoExistingOrder(mock sqlmock.Sqlmock, uid string) {
+	mock.ExpectQuery(`SELECT delivery_id, payment_id FROM orders WHERE order_uid = \$1 FOR UPDATE`).
+		WithArgs(uid).
+		WillReturnRows(sqlmock.NewRows([]string{"delivery_id", "payment_id"}))
+}
+
+// expectStoredOrder ожидает проверку существования и чтение сохраненного заказа
+func expectStoredOrder(mock sqlmock.Sqlmock, order *model.Order) {
+	mock.ExpectQuery(`SELECT delivery_id, payment_id FROM orders WHERE order_uid = \$1 FOR UPDATE`).
+		WithArgs(order.OrderUID).
+		WillReturnRows(sqlmock.NewRows([]string{"delivery_id", "payment_id"}).AddRow(7, 8))
+
+	mock.ExpectQuery(`SELECT o.order_uid, o.track_number, o.entry`).WithArgs(order.OrderUID).WillReturnRows(sqlmock.NewRows([]string{
+		"order_uid", "track_number", "entry", "locale", "internal_signature", "customer_id", "delivery_service", "shardkey", "sm_id", "date_created", "oof_shard",
+		"delivery.name", "delivery.phone", "delivery.zip", "delivery.city", "delivery.address", "delivery.region", "delivery.email",
+		"payment.transaction", "payment.request_id", "payment.currency", "payment.provider", "payment.amount", "payment.payment_dt", "payment.bank", "payment.delivery_cost", "payment.goods_total", "payment.custom_fee",
+	}).AddRow(
+		order.OrderUID, order.TrackNumber, order.Entry, order.Locale, order.InternalSignature, order.CustomerID, order.DeliveryService, order.Shardkey, order.SmID, order.DateCreated, order.OofShard,
+		order.Delivery.Name, order.Delivery.Phone, order.Delivery.Zip, order.Delivery.City, order.Delivery.Address, order.Delivery.Region, order.Delivery.Email,
+		order.Payment.Transaction, order.Payment.RequestID, order.Payment.Currency, order.Payment.Provider, order.Payment.Amount, order.Payment.PaymentDt, order.Payment.Bank, order.Payment.DeliveryCost, order.Payment.GoodsTotal, order.Payment.CustomFee,
+	))
+
+	itemRows := sqlmock.NewRows([]string{"id", "chrt_id", "track_number", "price", "rid", "name", "sale", "size", "total_price", "nm_id", "brand", "status", "order_uid"})
+	for i, item := range order.Items {
+		itemRows.AddRow(i+1, item.ChrtID, item.TrackNumber, item.Price, item.Rid, item.Name, item.Sale, item.Size, item.TotalPrice, item.NmID, item.Brand, item.Status, order.OrderUID)
+	}
+	mock.ExpectQuery(`SELECT \* FROM items WHERE order_uid`).WithArgs(order.OrderUID).WillReturnRows(itemRows)
+}
+
 func TestPostgresStorage_Close(t *testing.T) {
 	storage, mock := setupStorageWithMock(t)
 
@@ -74,6 +105,7 @@ func TestPostgresStorage_SaveOrder_Success(t *testing.T) {
 	order := helperTestOrder
 
 	mock.ExpectBegin()
+	expectNoExistingOrder(mock, order.OrderUID)
 
 	mock.ExpectQuery(`INSERT INTO deliveries`).
 		WithArgs(order.Delivery.Name, order.Delivery.Phone, order.Delivery.Zip, order.Delivery.City, order.Delivery.Address, order.Delivery.Region, order.Delivery.Email).
@@ -92,6 +124,7 @@ func TestPostgresStorage_SaveOrder_Success(t *testing.T) {
 		WithArgs(order.OrderUID, item.ChrtID, item.TrackNumber, item.Price, item.Rid, item.Name, item.Sale, item.Size, item.TotalPrice, item.NmID, item.Brand, item.Status).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
+	expectNotify(mock, order.OrderUID)
 	mock.ExpectCommit()
 
 	err := storage.SaveOrder(ctx, order)
@@ -118,6 +151,7 @@ func TestPostgresStorage_SaveOrder_DeliveryError_Rollback(t *testing.T) {
 	mockErr := errors.New("delivery insert error")
 
 	mock.ExpectBegin()
+	expectNoExistingOrder(mock, helperTestOrder.OrderUID)
 	mock.ExpectQuery(`INSERT INTO deliveries`).WillReturnError(mockErr)
 	mock.ExpectRollback()
 
@@ -134,11 +168,13 @@ func TestPostgresStorage_SaveOrder_CommitError(t *testing.T) {
 	mockErr := errors.New("commit error")
 
 	mock.ExpectBegin()
+	expectNoExistingOrder(mock, order.OrderUID)
 	mock.ExpectQuery(`INSERT INTO deliveries`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 	mock.ExpectQuery(`INSERT INTO payments`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 	mock.ExpectExec(`INSERT INTO orders`).WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectExec(`INSERT INTO items`).WillReturnResult(sqlmock.NewResult(1, 1))
 
+	expectNotify(mock, order.OrderUID)
 	mock.ExpectCommit().WillReturnError(mockErr)
 	err := storage.SaveOrder(ctx, order)
 	assert.Error(t, err)
@@ -203,3 +239,296 @@ func TestPostgresStorage_GetOrderByUID_OrderNotFound(t *testing.T) {
 	assert.Contains(t, err.Error(), "не удалось получить заказ")
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
+
+func TestPostgresStorage_ListOrders_NextPage(t *testing.T) {
+	storage, mock := setupStorageWithMock(t)
+	ctx := context.Background()
+	order := helperTestOrder
+	older := order.DateCreated.Add(-time.Hour)
+
+	columns := []string{
+		"order_uid", "track_number", "entry", "locale", "internal_signature", "customer_id", "delivery_service", "shardkey", "sm_id", "date_created", "oof_shard",
+		"delivery.name", "payment.transaction",
+	}
+	orderRows := sqlmock.NewRows(columns).
+		AddRow(order.OrderUID, order.TrackNumber, order.Entry, order.Locale, "", order.CustomerID, order.DeliveryService, order.Shardkey, order.SmID, order.DateCreated, order.OofShard, order.Delivery.Name, order.Payment.Transaction).
+		AddRow("older-uid", "track-older", order.Entry, order.Locale, "", order.CustomerID, order.DeliveryService, order.Shardkey, order.SmID, older, order.OofShard, order.Delivery.Name, "older-uid")
+
+	cursor := &OrderCursor{DateCreated: order.DateCreated.Add(time.Hour), OrderUID: "zzz"}
+
+	// Лимит 1 -> запрашиваем 2 строки, чтобы определить наличие следующей страницы
+	mock.ExpectQuery(`SELECT .+ FROM orders o .+ WHERE o.customer_id = \$1 AND p.currency = \$2 AND \(o.date_created, o.order_uid\) < \(\$3, \$4\) ORDER BY o.date_created DESC, o.order_uid DESC LIMIT \$5`).
+		WithArgs(order.CustomerID, "USD", cursor.DateCreated, cursor.OrderUID, 2).
+		WillReturnRows(orderRows)
+
+	item := order.Items[0]
+	itemRows := sqlmock.NewRows([]string{"id", "chrt_id", "name", "order_uid"}).
+		AddRow(1, item.ChrtID, item.Name, order.OrderUID)
+	mock.ExpectQuery(`SELECT \* FROM items WHERE order_uid = ANY\(\$1\)`).WillReturnRows(itemRows)
+
+	page, err := storage.ListOrders(ctx, OrderFilter{CustomerID: order.CustomerID, Currency: "USD", Limit: 1}, cursor)
+	assert.NoError(t, err)
+	assert.Len(t, page.Orders, 1)
+	assert.Equal(t, order.OrderUID, page.Orders[0].OrderUID)
+	assert.Len(t, page.Orders[0].Items, 1)
+	if assert.NotNil(t, page.NextCursor) {
+		assert.Equal(t, order.OrderUID, page.NextCursor.OrderUID)
+		assert.True(t, order.DateCreated.Equal(page.NextCursor.DateCreated))
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStorage_ListOrders_Empty(t *testing.T) {
+	storage, mock := setupStorageWithMock(t)
+	ctx := context.Background()
+
+	mock.ExpectQuery(`SELECT .+ FROM orders o .+ ORDER BY o.date_created DESC, o.order_uid DESC LIMIT \$1`).
+		WithArgs(DefaultListLimit + 1).
+		WillReturnRows(sqlmock.NewRows([]string{"order_uid"}))
+
+	page, err := storage.ListOrders(ctx, OrderFilter{}, nil)
+	assert.NoError(t, err)
+	assert.NotNil(t, page.Orders)
+	assert.Empty(t, page.Orders)
+	assert.Nil(t, page.NextCursor)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStorage_StreamRecentOrders_GroupsItems(t *testing.T) {
+	storage, mock := setupStorageWithMock(t)
+	ctx := context.Background()
+	order := helperTestOrder
+
+	columns := []string{
+		"order_uid", "track_number", "date_created", "delivery.name", "payment.transaction",
+		"item.id", "item.chrt_id", "item.name",
+	}
+	rows := sqlmock.NewRows(columns).
+		AddRow("older-uid", "track-older", order.DateCreated.Add(-time.Hour), order.Delivery.Name, "older-uid", 0, 0, ""). // Заказ без товаров
+		AddRow(order.OrderUID, order.TrackNumber, order.DateCreated, order.Delivery.Name, order.Payment.Transaction, 1, 11, "Item 1").
+		AddRow(order.OrderUID, order.TrackNumber, order.DateCreated, order.Delivery.Name, order.Payment.Transaction, 2, 12, "Item 2")
+
+	mock.ExpectQuery(`WITH recent AS .+ ORDER BY date_created DESC, order_uid DESC LIMIT \$1 .+ LEFT JOIN items i`).
+		WithArgs(2).
+		WillReturnRows(rows)
+
+	var streamed []*model.Order
+	err := storage.StreamRecentOrders(ctx, 2, func(o *model.Order) error {
+		streamed = append(streamed, o)
+		return nil
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, streamed, 2) {
+		assert.Equal(t, "older-uid", streamed[0].OrderUID)
+		assert.NotNil(t, streamed[0].Items)
+		assert.Empty(t, streamed[0].Items)
+
+		assert.Equal(t, order.OrderUID, streamed[1].OrderUID)
+		assert.Equal(t, order.Delivery.Name, streamed[1].Delivery.Name)
+		if assert.Len(t, streamed[1].Items, 2) {
+			assert.Equal(t, "Item 1", streamed[1].Items[0].Name)
+			assert.Equal(t, order.OrderUID, streamed[1].Items[1].OrderUID)
+		}
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStorage_StreamRecentOrders_CallbackErrorStops(t *testing.T) {
+	storage, mock := setupStorageWithMock(t)
+	ctx := context.Background()
+	stop := errors.New("stop")
+
+	rows := sqlmock.NewRows([]string{"order_uid", "item.id"}).
+		AddRow("uid-1", 1).
+		AddRow("uid-2", 2).
+		AddRow("uid-3", 3)
+	mock.ExpectQuery(`WITH recent AS`).WithArgs(10).WillReturnRows(rows)
+
+	calls := 0
+	err := storage.StreamRecentOrders(ctx, 10, func(*model.Order) error {
+		calls++
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 1, calls, "после ошибки обработчика чтение должно прекратиться")
+}
+
+func TestOrderCursor_EncodeDecode(t *testing.T) {
+	cursor := OrderCursor{DateCreated: time.Date(2021, 11, 26, 6, 22, 19, 123, time.UTC), OrderUID: "test-uid-123"}
+
+	decoded, err := DecodeOrderCursor(cursor.Encode())
+	assert.NoError(t, err)
+	assert.Equal(t, cursor.OrderUID, decoded.OrderUID)
+	assert.True(t, cursor.DateCreated.Equal(decoded.DateCreated))
+
+	_, err = DecodeOrderCursor("not a cursor!")
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
+
+func TestPostgresStorage_SaveOrder_DuplicateUnchanged(t *testing.T) {
+	storage, mock := setupStorageWithMock(t)
+	ctx := context.Background()
+
+	mock.ExpectBegin()
+	expectStoredOrder(mock, helperTestOrder)
+	// Данные совпадают - никаких записей, только откат
+	mock.ExpectRollback()
+
+	err := storage.SaveOrder(ctx, helperTestOrder)
+	assert.ErrorIs(t, err, ErrOrderUnchanged)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStorage_SaveOrder_DuplicateChanged_Updates(t *testing.T) {
+	storage, mock := setupStorageWithMock(t)
+	ctx := context.Background()
+
+	changed := *helperTestOrder
+	changed.Delivery.City = "New City"
+
+	mock.ExpectBegin()
+	expectStoredOrder(mock, helperTestOrder)
+	mock.ExpectExec(`UPDATE deliveries SET`).
+		WithArgs(changed.Delivery.Name, changed.Delivery.Phone, changed.Delivery.Zip, "New City", changed.Delivery.Address, changed.Delivery.Region, changed.Delivery.Email, 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE payments SET`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE orders SET`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM items WHERE order_uid = \$1`).WithArgs(changed.OrderUID).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO items`).WillReturnResult(sqlmock.NewResult(1, 1))
+	expectNotify(mock, changed.OrderUID)
+	mock.ExpectCommit()
+
+	err := storage.SaveOrder(ctx, &changed)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStorage_SaveOrder_UniqueConflict(t *testing.T) {
+	storage, mock := setupStorageWithMock(t)
+	ctx := context.Background()
+	pqErr := &pq.Error{Code: "23505", Constraint: "payments_transaction_key"}
+
+	// Транзакция платежа занята другим заказом: это конфликт, а не гонка вставок, и сохранение не повторяется
+	mock.ExpectBegin()
+	expectNoExistingOrder(mock, helperTestOrder.OrderUID)
+	mock.ExpectQuery(`INSERT INTO deliveries`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery(`INSERT INTO payments`).WillReturnError(pqErr)
+	mock.ExpectRollback()
+	expectTransactionOwner(mock, helperTestOrder.Payment.Transaction, "other-order")
+
+	err := storage.SaveOrder(ctx, helperTestOrder)
+
+	var conflict *OrderConflictError
+	if assert.ErrorAs(t, err, &conflict) {
+		assert.Equal(t, "payments_transaction_key", conflict.Constraint)
+		assert.Equal(t, helperTestOrder.OrderUID, conflict.OrderUID)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// expectTransactionOwner ожидает проверку, какому заказу принадлежит транзакция платежа.
+func expectTransactionOwner(mock sqlmock.Sqlmock, transaction, orderUID string) {
+	mock.ExpectQuery(`SELECT o.order_uid FROM orders o JOIN payments p ON p.id = o.payment_id WHERE p.transaction = \$1`).
+		WithArgs(transaction).
+		WillReturnRows(sqlmock.NewRows([]string{"order_uid"}).AddRow(orderUID))
+}
+
+func TestPostgresStorage_SaveOrder_PrimaryKeyRaceIsNotConflict(t *testing.T) {
+	storage, mock := setupStorageWithMock(t)
+	ctx := context.Background()
+	pqErr := &pq.Error{Code: "23505", Constraint: "orders_pkey"}
+
+	mock.ExpectBegin()
+	expectNoExistingOrder(mock, helperTestOrder.OrderUID)
+	mock.ExpectQuery(`INSERT INTO deliveries`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery(`INSERT INTO payments`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec(`INSERT INTO orders`).WillReturnError(pqErr)
+	mock.ExpectRollback()
+
+	// Повторная попытка находит заказ, сохраненный параллельно
+	mock.ExpectBegin()
+	expectStoredOrder(mock, helperTestOrder)
+	mock.ExpectRollback()
+
+	err := storage.SaveOrder(ctx, helperTestOrder)
+
+	var conflict *OrderConflictError
+	assert.ErrorIs(t, err, ErrOrderUnchanged)
+	assert.False(t, errors.As(err, &conflict))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStorage_SaveOrder_ConcurrentFirstInsert(t *testing.T) {
+	storage, mock := setupStorageWithMock(t)
+	ctx := context.Background()
+
+	// Параллельная транзакция вставила тот же заказ между проверкой и вставкой:
+	// платеж сталкивается на уникальной транзакции раньше, чем orders_pkey
+	mock.ExpectBegin()
+	expectNoExistingOrder(mock, helperTestOrder.OrderUID)
+	mock.ExpectQuery(`INSERT INTO deliveries`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery(`INSERT INTO payments`).WillReturnError(&pq.Error{Code: "23505", Constraint: "payments_transaction_key"})
+	mock.ExpectRollback()
+	expectTransactionOwner(mock, helperTestOrder.Payment.Transaction, helperTestOrder.OrderUID)
+
+	// Повтор видит зафиксированный заказ с измененными данными и обновляет его
+	stored := *helperTestOrder
+	stored.Delivery.City = "Old City"
+	mock.ExpectBegin()
+	expectStoredOrder(mock, &stored)
+	mock.ExpectExec(`UPDATE deliveries SET`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE payments SET`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE orders SET`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM items WHERE order_uid = \$1`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO items`).WillReturnResult(sqlmock.NewResult(1, 1))
+	expectNotify(mock, helperTestOrder.OrderUID)
+	mock.ExpectCommit()
+
+	err := storage.SaveOrder(ctx, helperTestOrder)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStorage_SaveOrder_PreservesItemStatus(t *testing.T) {
+	// Событие статуса уже перевело товар в 300, а повторно доставленный заказ несет прежний 202
+	stored := *helperTestOrder
+	stored.Items = append([]model.Item{}, helperTestOrder.Items...)
+	stored.Items[0].Status = 300
+
+	t.Run("повторная доставка", func(t *testing.T) {
+		storage, mock := setupStorageWithMock(t)
+
+		mock.ExpectBegin()
+		expectStoredOrder(mock, &stored)
+		// Статус товара не сравнивается - заказ не изменился
+		mock.ExpectRollback()
+
+		err := storage.SaveOrder(context.Background(), helperTestOrder)
+		assert.ErrorIs(t, err, ErrOrderUnchanged)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("обновление заказа", func(t *testing.T) {
+		storage, mock := setupStorageWithMock(t)
+		changed := *helperTestOrder
+		changed.Delivery.City = "New City"
+		item := changed.Items[0]
+
+		mock.ExpectBegin()
+		expectStoredOrder(mock, &stored)
+		mock.ExpectExec(`UPDATE deliveries SET`).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`UPDATE payments SET`).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`UPDATE orders SET`).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`DELETE FROM items WHERE order_uid = \$1`).WillReturnResult(sqlmock.NewResult(0, 1))
+		// Товар перезаписывается со статусом из БД, а не из сообщения
+		mock.ExpectExec(`INSERT INTO items`).
+			WithArgs(changed.OrderUID, item.ChrtID, item.TrackNumber, item.Price, item.Rid, item.Name, item.Sale, item.Size, item.TotalPrice, item.NmID, item.Brand, 300).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		expectNotify(mock, changed.OrderUID)
+		mock.ExpectCommit()
+
+		err := storage.SaveOrder(context.Background(), &changed)
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,128 @@
+package database
+
+import (
+	"L0_project/internal/metrics"
+	"L0_project/internal/model"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// StatusTransition - запрос на смену статуса заказа.
+type StatusTransition struct {
+	OrderUID string
+	To       model.OrderStatus
+	// ExpectedVersion - версия статуса, которую видел клиент (0 - не проверять).
+	// Если статус с тех пор изменился, переход отклоняется с ErrStatusVersionConflict.
+	ExpectedVersion int
+	Reason          string
+}
+
+// TransitionStatus переводит заказ в новый статус и записывает переход в историю.
+//
+// Используется оптимистичная блокировка: статус читается без блокировки строки,
+// а обновляется только при неизменной версии. Если статус успели изменить
+// (параллельно или после того, как его прочитал клиент), возвращается ошибка,
+// оборачивающая ErrStatusVersionConflict. Запрещенный жизненным циклом переход
+// отклоняется с model.ErrIllegalTransition, отсутствующий заказ - с sql.ErrNoRows.
+// Об изменении публикуется уведомление в OrderChangesChannel.
+func (s *postgresStorage) TransitionStatus(ctx context.Context, transition StatusTransition) (change *model.StatusChange, err error) {
+	// Создаем span для трассировки
+	ctx, span := s.tracer.Start(ctx, "DB.TransitionStatus")
+	defer span.End()
+
+	var tx *sqlx.Tx
+	tx, err = s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+				log.Printf("Ошибка отката транзакции (после ошибки: %v): %v", err, rbErr)
+			}
+		}
+	}()
+
+	var current struct {
+		Status  model.OrderStatus `db:"status"`
+		Version int               `db:"status_version"`
+	}
+	err = tx.GetContext(ctx, &current, `SELECT status, status_version FROM orders WHERE order_uid = $1`, transition.OrderUID)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			metrics.DBErrors.WithLabelValues("transition_status").Inc() // Метрика ошибки
+		}
+		return nil, fmt.Errorf("не удалось получить статус заказа: %w", err)
+	}
+
+	if transition.ExpectedVersion > 0 && transition.ExpectedVersion != current.Version {
+		return nil, fmt.Errorf("%w: ожидалась версия %d, текущая %d", ErrStatusVersionConflict, transition.ExpectedVersion, current.Version)
+	}
+	if err = model.ValidateTransition(current.Status, transition.To); err != nil {
+		return nil, err
+	}
+
+	result, err := tx.ExecContext(ctx, `UPDATE orders SET status = $1, status_version = status_version + 1, status_changed_at = now() WHERE order_uid = $2 AND status_version = $3`,
+		transition.To, transition.OrderUID, current.Version)
+	if err != nil {
+		metrics.DBErrors.WithLabelValues("transition_status").Inc() // Метрика ошибки
+		return nil, fmt.Errorf("ошибка обновления статуса заказа: %w", err)
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка обновления статуса заказа: %w", err)
+	}
+	if updated == 0 {
+		return nil, fmt.Errorf("%w: версия %d устарела", ErrStatusVersionConflict, current.Version)
+	}
+
+	change = &model.StatusChange{
+		OrderUID: transition.OrderUID,
+		From:     current.Status,
+		To:       transition.To,
+		Version:  current.Version + 1,
+		Reason:   transition.Reason,
+	}
+	historyQuery := `INSERT INTO status_history (order_uid, from_status, to_status, version, reason) VALUES ($1, $2, $3, $4, $5) RETURNING changed_at`
+	if err = tx.GetContext(ctx, &change.ChangedAt, historyQuery, change.OrderUID, change.From, change.To, change.Version, change.Reason); err != nil {
+		metrics.DBErrors.WithLabelValues("transition_status").Inc() // Метрика ошибки
+		return nil, fmt.Errorf("ошибка записи истории статусов: %w", err)
+	}
+
+	if err = notifyOrdersChanged(ctx, tx, transition.OrderUID); err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+// GetStatusHistory возвращает текущий статус заказа и историю его переходов.
+// Для отсутствующего заказа возвращается ошибка, оборачивающая sql.ErrNoRows.
+func (s *postgresStorage) GetStatusHistory(ctx context.Context, orderUID string) (*model.StatusHistory, error) {
+	// Создаем span для трассировки
+	ctx, span := s.tracer.Start(ctx, "DB.GetStatusHistory")
+	defer span.End()
+
+	history := &model.StatusHistory{OrderUID: orderUID, Changes: []model.StatusChange{}}
+	row := s.db.QueryRowxContext(ctx, `SELECT status, status_version FROM orders WHERE order_uid = $1`, orderUID)
+	if err := row.Scan(&history.Status, &history.Version); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			metrics.DBErrors.WithLabelValues("get_status_history").Inc() // Метрика ошибки
+		}
+		return nil, fmt.Errorf("не удалось получить статус заказа: %w", err)
+	}
+
+	query := `SELECT order_uid, from_status, to_status, version, reason, changed_at FROM status_history WHERE order_uid = $1 ORDER BY version`
+	if err := s.db.SelectContext(ctx, &history.Changes, query, orderUID); err != nil {
+		metrics.DBErrors.WithLabelValues("get_status_history").Inc() // Метрика ошибки
+		return nil, fmt.Errorf("не удалось получить историю статусов: %w", err)
+	}
+	return history, nil
+}
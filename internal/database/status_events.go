@@ -0,0 +1,224 @@
+package database
+
+import (
+	"L0_project/internal/metrics"
+	"L0_project/internal/model"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// statusEventReason - причина перехода в истории статусов для событий из Kafka.
+const statusEventReason = "событие статуса"
+
+// ErrStatusEventTargetNotFound возвращается ApplyStatusEvent, если заказа или
+// товара из события еще нет в БД. Такое событие откладывается и применяется позже.
+var ErrStatusEventTargetNotFound = errors.New("заказ или товар события статуса не найден")
+
+// ApplyStatusEvent применяет событие статуса заказа или товара. Возвращает false,
+// если событие устарело: статус уже менялся позже event.OccurredAt (или совпадает
+// с текущим). Так события, пришедшие не по порядку, не откатывают более новый статус.
+//
+// Событие о заказе может перевести его через несколько шагов жизненного цикла
+// сразу (например, created -> shipped, если промежуточные события еще не пришли);
+// недостижимый статус отклоняется с model.ErrIllegalTransition. Переход
+// записывается в историю статусов со временем события. Если заказа или товара
+// нет, возвращается ErrStatusEventTargetNotFound.
+func (s *postgresStorage) ApplyStatusEvent(ctx context.Context, event model.StatusEvent) (applied bool, err error) {
+	// Создаем span для трассировки
+	ctx, span := s.tracer.Start(ctx, "DB.ApplyStatusEvent")
+	defer span.End()
+
+	var tx *sqlx.Tx
+	tx, err = s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer func() {
+		if err != nil || !applied {
+			if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+				log.Printf("Ошибка отката транзакции (после ошибки: %v): %v", err, rbErr)
+			}
+		}
+	}()
+
+	if event.ItemEvent() {
+		applied, err = applyItemStatusEvent(ctx, tx, event)
+	} else {
+		applied, err = applyOrderStatusEvent(ctx, tx, event)
+	}
+	if err != nil && !errors.Is(err, ErrStatusEventTargetNotFound) && !errors.Is(err, model.ErrIllegalTransition) {
+		metrics.DBErrors.WithLabelValues("apply_status_event").Inc() // Метрика ошибки
+	}
+	if err != nil || !applied {
+		return false, err
+	}
+
+	if err = notifyOrdersChanged(ctx, tx, event.OrderUID); err != nil {
+		return false, err
+	}
+	if err = tx.Commit(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// applyOrderStatusEvent меняет статус заказа, если событие новее последней смены статуса.
+func applyOrderStatusEvent(ctx context.Context, tx *sqlx.Tx, event model.StatusEvent) (bool, error) {
+	var current struct {
+		Status    model.OrderStatus `db:"status"`
+		Version   int               `db:"status_version"`
+		ChangedAt sql.NullTime      `db:"status_changed_at"`
+	}
+	err := tx.GetContext(ctx, &current, `SELECT status, status_version, status_changed_at FROM orders WHERE order_uid = $1 FOR UPDATE`, event.OrderUID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, fmt.Errorf("%w: заказ %s", ErrStatusEventTargetNotFound, event.OrderUID)
+	}
+	if err != nil {
+		return false, fmt.Errorf("не удалось получить статус заказа: %w", err)
+	}
+
+	to := event.OrderStatus()
+	if current.ChangedAt.Valid && !event.OccurredAt.After(current.ChangedAt.Time) || current.Status == to {
+		return false, nil
+	}
+	if !current.Status.CanReach(to) {
+		return false, fmt.Errorf("%w: %s -> %s", model.ErrIllegalTransition, current.Status, to)
+	}
+
+	updateQuery := `UPDATE orders SET status = $1, status_version = status_version + 1, status_changed_at = $2 WHERE order_uid = $3`
+	if _, err := tx.ExecContext(ctx, updateQuery, to, event.OccurredAt, event.OrderUID); err != nil {
+		return false, fmt.Errorf("ошибка обновления статуса заказа: %w", err)
+	}
+
+	historyQuery := `INSERT INTO status_history (order_uid, from_status, to_status, version, reason, changed_at) VALUES ($1, $2, $3, $4, $5, $6)`
+	if _, err := tx.ExecContext(ctx, historyQuery, event.OrderUID, current.Status, to, current.Version+1, statusEventReason, event.OccurredAt); err != nil {
+		return false, fmt.Errorf("ошибка записи истории статусов: %w", err)
+	}
+	return true, nil
+}
+
+// applyItemStatusEvent меняет статус товара, если событие новее последней смены
+// его статуса. Время смены хранится в item_status_changes.
+func applyItemStatusEvent(ctx context.Context, tx *sqlx.Tx, event model.StatusEvent) (bool, error) {
+	status, err := event.ItemStatus()
+	if err != nil {
+		return false, err
+	}
+
+	// Блокируем строки товара, чтобы параллельное обновление заказа не заменило их до нас
+	var rids []string
+	err = tx.SelectContext(ctx, &rids, `SELECT rid FROM items WHERE order_uid = $1 AND rid = $2 FOR UPDATE`, event.OrderUID, event.Rid)
+	if err != nil {
+		return false, fmt.Errorf("ошибка проверки товара: %w", err)
+	}
+	if len(rids) == 0 {
+		return false, fmt.Errorf("%w: товар %s заказа %s", ErrStatusEventTargetNotFound, event.Rid, event.OrderUID)
+	}
+
+	// Запись о смене статуса вставляется или сдвигается только вперед по времени
+	guardQuery := `
+        INSERT INTO item_status_changes (order_uid, rid, changed_at) VALUES ($1, $2, $3)
+        ON CONFLICT (order_uid, rid) DO UPDATE SET changed_at = EXCLUDED.changed_at
+        WHERE item_status_changes.changed_at < EXCLUDED.changed_at`
+	result, err := tx.ExecContext(ctx, guardQuery, event.OrderUID, event.Rid, event.OccurredAt)
+	if err != nil {
+		return false, fmt.Errorf("ошибка записи времени смены статуса товара: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return false, err // Событие устарело
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE items SET status = $1 WHERE order_uid = $2 AND rid = $3`, status, event.OrderUID, event.Rid); err != nil {
+		return false, fmt.Errorf("ошибка обновления статуса товара: %w", err)
+	}
+	return true, nil
+}
+
+// ParkStatusEvent откладывает событие, заказ или товар которого еще не сохранен,
+// до retryAt. Повторная отправка того же события не создает дубликат.
+func (s *postgresStorage) ParkStatusEvent(ctx context.Context, event model.StatusEvent, retryAt time.Time) error {
+	// Создаем span для трассировки
+	ctx, span := s.tracer.Start(ctx, "DB.ParkStatusEvent")
+	defer span.End()
+
+	query := `
+        INSERT INTO parked_status_events (order_uid, rid, new_status, occurred_at, next_attempt_at) VALUES ($1, $2, $3, $4, $5)
+        ON CONFLICT (order_uid, rid, new_status, occurred_at) DO NOTHING`
+	if _, err := s.db.ExecContext(ctx, query, event.OrderUID, event.Rid, event.NewStatus, event.OccurredAt, retryAt); err != nil {
+		metrics.DBErrors.WithLabelValues("park_status_event").Inc() // Метрика ошибки
+		return fmt.Errorf("ошибка сохранения отложенного события статуса: %w", err)
+	}
+	return nil
+}
+
+// ClaimParkedStatusEvents выбирает до limit отложенных событий, срок повтора
+// которых наступил, и откладывает их еще на retryAfter, увеличивая счетчик
+// попыток. Так одно событие не обрабатывают одновременно несколько экземпляров,
+// а событие, обработка которого прервалась, будет выбрано снова. События
+// возвращаются в порядке occurred_at.
+func (s *postgresStorage) ClaimParkedStatusEvents(ctx context.Context, limit int, retryAfter time.Duration) ([]model.ParkedStatusEvent, error) {
+	// Создаем span для трассировки
+	ctx, span := s.tracer.Start(ctx, "DB.ClaimParkedStatusEvents")
+	defer span.End()
+
+	query := `
+        UPDATE parked_status_events SET attempts = attempts + 1, next_attempt_at = now() + $2 * interval '1 millisecond'
+        WHERE id IN (
+            SELECT id FROM parked_status_events
+            WHERE next_attempt_at <= now()
+            ORDER BY occurred_at
+            LIMIT $1
+            FOR UPDATE SKIP LOCKED
+        )
+        RETURNING id, order_uid, rid, new_status, occurred_at, attempts, parked_at`
+
+	var rows []struct {
+		ID         int64             `db:"id"`
+		OrderUID   string            `db:"order_uid"`
+		Rid        string            `db:"rid"`
+		NewStatus  model.StatusValue `db:"new_status"`
+		OccurredAt time.Time         `db:"occurred_at"`
+		Attempts   int               `db:"attempts"`
+		ParkedAt   time.Time         `db:"parked_at"`
+	}
+	if err := s.db.SelectContext(ctx, &rows, query, limit, retryAfter.Milliseconds()); err != nil {
+		metrics.DBErrors.WithLabelValues("claim_parked_status_events").Inc() // Метрика ошибки
+		return nil, fmt.Errorf("ошибка выборки отложенных событий статуса: %w", err)
+	}
+
+	events := make([]model.ParkedStatusEvent, len(rows))
+	for i, row := range rows {
+		events[i] = model.ParkedStatusEvent{
+			ID:       row.ID,
+			Event:    model.StatusEvent{OrderUID: row.OrderUID, Rid: row.Rid, NewStatus: row.NewStatus, OccurredAt: row.OccurredAt},
+			Attempts: row.Attempts,
+			ParkedAt: row.ParkedAt,
+		}
+	}
+	// RETURNING не сохраняет порядок подзапроса
+	slices.SortStableFunc(events, func(a, b model.ParkedStatusEvent) int {
+		return a.Event.OccurredAt.Compare(b.Event.OccurredAt)
+	})
+	return events, nil
+}
+
+// DeleteParkedStatusEvent удаляет отложенное событие после его применения или
+// отправки в DLQ.
+func (s *postgresStorage) DeleteParkedStatusEvent(ctx context.Context, id int64) error {
+	// Создаем span для трассировки
+	ctx, span := s.tracer.Start(ctx, "DB.DeleteParkedStatusEvent")
+	defer span.End()
+
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM parked_status_events WHERE id = $1`, id); err != nil {
+		metrics.DBErrors.WithLabelValues("delete_parked_status_event").Inc() // Метрика ошибки
+		return fmt.Errorf("ошибка удаления отложенного события статуса: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package database
+
+import (
+	"L0_project/internal/model"
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// expectLockedStatus ожидает чтение статуса заказа с блокировкой строки
+func expectLockedStatus(mock sqlmock.Sqlmock, uid string, status model.OrderStatus, version int, changedAt any) {
+	mock.ExpectQuery(`SELECT status, status_version, status_changed_at FROM orders WHERE order_uid = \$1 FOR UPDATE`).
+		WithArgs(uid).
+		WillReturnRows(sqlmock.NewRows([]string{"status", "status_version", "status_changed_at"}).AddRow(status, version, changedAt))
+}
+
+func TestPostgresStorage_ApplyStatusEvent_Order(t *testing.T) {
+	storage, mock := setupStorageWithMock(t)
+	occurredAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	expectLockedStatus(mock, "uid-1", model.StatusCreated, 1, nil)
+	// Промежуточные события еще не пришли: переходим сразу в shipped
+	mock.ExpectExec(`UPDATE orders SET status = \$1, status_version = status_version \+ 1, status_changed_at = \$2 WHERE order_uid = \$3`).
+		WithArgs(model.StatusShipped, occurredAt, "uid-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO status_history`).
+		WithArgs("uid-1", model.StatusCreated, model.StatusShipped, 2, statusEventReason, occurredAt).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	expectNotify(mock, "uid-1")
+	mock.ExpectCommit()
+
+	applied, err := storage.ApplyStatusEvent(context.Background(), model.StatusEvent{
+		OrderUID: "uid-1", NewStatus: "shipped", OccurredAt: occurredAt,
+	})
+	require.NoError(t, err)
+	assert.True(t, applied)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStorage_ApplyStatusEvent_OrderNotApplied(t *testing.T) {
+	changedAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name    string
+		status  model.OrderStatus
+		event   model.StatusEvent
+		wantErr error
+	}{
+		{
+			name:   "событие старше последней смены статуса",
+			status: model.StatusShipped,
+			event:  model.StatusEvent{OrderUID: "uid-1", NewStatus: "paid", OccurredAt: changedAt.Add(-time.Hour)},
+		},
+		{
+			name:   "повторная доставка",
+			status: model.StatusPaid,
+			event:  model.StatusEvent{OrderUID: "uid-1", NewStatus: "paid", OccurredAt: changedAt},
+		},
+		{
+			name:    "статус недостижим",
+			status:  model.StatusCancelled,
+			event:   model.StatusEvent{OrderUID: "uid-1", NewStatus: "paid", OccurredAt: changedAt.Add(time.Hour)},
+			wantErr: model.ErrIllegalTransition,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			storage, mock := setupStorageWithMock(t)
+
+			mock.ExpectBegin()
+			expectLockedStatus(mock, "uid-1", tc.status, 3, changedAt)
+			mock.ExpectRollback()
+
+			applied, err := storage.ApplyStatusEvent(context.Background(), tc.event)
+			assert.False(t, applied)
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPostgresStorage_ApplyStatusEvent_OrderNotFound(t *testing.T) {
+	storage, mock := setupStorageWithMock(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT status, status_version, status_changed_at FROM orders`).WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+
+	_, err := storage.ApplyStatusEvent(context.Background(), model.StatusEvent{
+		OrderUID: "absent", NewStatus: "paid", OccurredAt: time.Now(),
+	})
+	assert.ErrorIs(t, err, ErrStatusEventTargetNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStorage_ApplyStatusEvent_Item(t *testing.T) {
+	occurredAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	event := model.StatusEvent{OrderUID: "uid-1", Rid: "rid-1", NewStatus: "202", OccurredAt: occurredAt}
+
+	t.Run("применено", func(t *testing.T) {
+		storage, mock := setupStorageWithMock(t)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT rid FROM items WHERE order_uid = \$1 AND rid = \$2 FOR UPDATE`).
+			WithArgs("uid-1", "rid-1").
+			WillReturnRows(sqlmock.NewRows([]string{"rid"}).AddRow("rid-1"))
+		mock.ExpectExec(`INSERT INTO item_status_changes`).
+			WithArgs("uid-1", "rid-1", occurredAt).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`UPDATE items SET status = \$1 WHERE order_uid = \$2 AND rid = \$3`).
+			WithArgs(202, "uid-1", "rid-1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		expectNotify(mock, "uid-1")
+		mock.ExpectCommit()
+
+		applied, err := storage.ApplyStatusEvent(context.Background(), event)
+		require.NoError(t, err)
+		assert.True(t, applied)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("устарело", func(t *testing.T) {
+		storage, mock := setupStorageWithMock(t)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT rid FROM items`).WillReturnRows(sqlmock.NewRows([]string{"rid"}).AddRow("rid-1"))
+		// Статус товара уже менялся позже события
+		mock.ExpectExec(`INSERT INTO item_status_changes`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		applied, err := storage.ApplyStatusEvent(context.Background(), event)
+		require.NoError(t, err)
+		assert.False(t, applied)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("товар не найден", func(t *testing.T) {
+		storage, mock := setupStorageWithMock(t)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT rid FROM items`).WillReturnRows(sqlmock.NewRows([]string{"rid"}))
+		mock.ExpectRollback()
+
+		_, err := storage.ApplyStatusEvent(context.Background(), event)
+		assert.ErrorIs(t, err, ErrStatusEventTargetNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestPostgresStorage_ParkAndClaimStatusEvents(t *testing.T) {
+	storage, mock := setupStorageWithMock(t)
+	occurredAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	retryAt := occurredAt.Add(time.Minute)
+	event := model.StatusEvent{OrderUID: "uid-1", NewStatus: "paid", OccurredAt: occurredAt}
+
+	mock.ExpectExec(`INSERT INTO parked_status_events .* ON CONFLICT \(order_uid, rid, new_status, occurred_at\) DO NOTHING`).
+		WithArgs("uid-1", "", model.StatusValue("paid"), occurredAt, retryAt).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	require.NoError(t, storage.ParkStatusEvent(context.Background(), event, retryAt))
+
+	// RETURNING возвращает строки в произвольном порядке
+	mock.ExpectQuery(`UPDATE parked_status_events SET attempts = attempts \+ 1`).
+		WithArgs(100, int64(10000)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "order_uid", "rid", "new_status", "occurred_at", "attempts", "parked_at"}).
+			AddRow(2, "uid-1", "", "shipped", occurredAt.Add(time.Hour), 1, occurredAt).
+			AddRow(1, "uid-1", "", "paid", occurredAt, 1, occurredAt))
+	parked, err := storage.ClaimParkedStatusEvents(context.Background(), 100, 10*time.Second)
+	require.NoError(t, err)
+	require.Len(t, parked, 2)
+	assert.Equal(t, int64(1), parked[0].ID)
+	assert.Equal(t, event, parked[0].Event)
+	assert.Equal(t, model.StatusValue("shipped"), parked[1].Event.NewStatus)
+
+	mock.ExpectExec(`DELETE FROM parked_status_events WHERE id = \$1`).WithArgs(int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, storage.DeleteParkedStatusEvent(context.Background(), 1))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,139 @@
+package database
+
+import (
+	"L0_project/internal/model"
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// expectCurrentStatus ожидает чтение текущего статуса заказа
+func expectCurrentStatus(mock sqlmock.Sqlmock, uid string, status model.OrderStatus, version int) {
+	mock.ExpectQuery(`SELECT status, status_version FROM orders WHERE order_uid = \$1`).
+		WithArgs(uid).
+		WillReturnRows(sqlmock.NewRows([]string{"status", "status_version"}).AddRow(status, version))
+}
+
+func TestPostgresStorage_TransitionStatus_Success(t *testing.T) {
+	storage, mock := setupStorageWithMock(t)
+	changedAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	expectCurrentStatus(mock, "uid-1", model.StatusCreated, 1)
+	mock.ExpectExec(`UPDATE orders SET status = \$1, status_version = status_version \+ 1, status_changed_at = now\(\) WHERE order_uid = \$2 AND status_version = \$3`).
+		WithArgs(model.StatusPaid, "uid-1", 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`INSERT INTO status_history`).
+		WithArgs("uid-1", model.StatusCreated, model.StatusPaid, 2, "оплачен").
+		WillReturnRows(sqlmock.NewRows([]string{"changed_at"}).AddRow(changedAt))
+	expectNotify(mock, "uid-1")
+	mock.ExpectCommit()
+
+	change, err := storage.TransitionStatus(context.Background(), StatusTransition{
+		OrderUID: "uid-1", To: model.StatusPaid, ExpectedVersion: 1, Reason: "оплачен",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &model.StatusChange{
+		OrderUID: "uid-1", From: model.StatusCreated, To: model.StatusPaid, Version: 2, Reason: "оплачен", ChangedAt: changedAt,
+	}, change)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStorage_TransitionStatus_Rejected(t *testing.T) {
+	testCases := []struct {
+		name       string
+		transition StatusTransition
+		wantErr    error
+	}{
+		{
+			name:       "устаревшая версия клиента",
+			transition: StatusTransition{OrderUID: "uid-1", To: model.StatusPaid, ExpectedVersion: 1},
+			wantErr:    ErrStatusVersionConflict,
+		},
+		{
+			name:       "запрещенный переход",
+			transition: StatusTransition{OrderUID: "uid-1", To: model.StatusDelivered},
+			wantErr:    model.ErrIllegalTransition,
+		},
+		{
+			name:       "неизвестный статус",
+			transition: StatusTransition{OrderUID: "uid-1", To: "lost"},
+			wantErr:    model.ErrUnknownStatus,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			storage, mock := setupStorageWithMock(t)
+
+			mock.ExpectBegin()
+			expectCurrentStatus(mock, "uid-1", model.StatusPaid, 2)
+			mock.ExpectRollback()
+
+			_, err := storage.TransitionStatus(context.Background(), tc.transition)
+			assert.ErrorIs(t, err, tc.wantErr)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPostgresStorage_TransitionStatus_ConcurrentChange(t *testing.T) {
+	storage, mock := setupStorageWithMock(t)
+
+	mock.ExpectBegin()
+	expectCurrentStatus(mock, "uid-1", model.StatusCreated, 1)
+	// Статус изменили между чтением и обновлением
+	mock.ExpectExec(`UPDATE orders SET status`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	_, err := storage.TransitionStatus(context.Background(), StatusTransition{OrderUID: "uid-1", To: model.StatusPaid})
+	assert.ErrorIs(t, err, ErrStatusVersionConflict)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStorage_TransitionStatus_NotFound(t *testing.T) {
+	storage, mock := setupStorageWithMock(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT status, status_version FROM orders`).WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+
+	_, err := storage.TransitionStatus(context.Background(), StatusTransition{OrderUID: "absent", To: model.StatusPaid})
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStorage_GetStatusHistory(t *testing.T) {
+	storage, mock := setupStorageWithMock(t)
+	changedAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	expectCurrentStatus(mock, "uid-1", model.StatusAssembling, 3)
+	mock.ExpectQuery(`SELECT order_uid, from_status, to_status, version, reason, changed_at FROM status_history WHERE order_uid = \$1 ORDER BY version`).
+		WithArgs("uid-1").
+		WillReturnRows(sqlmock.NewRows([]string{"order_uid", "from_status", "to_status", "version", "reason", "changed_at"}).
+			AddRow("uid-1", "created", "paid", 2, "", changedAt).
+			AddRow("uid-1", "paid", "assembling", 3, "склад", changedAt.Add(time.Hour)))
+
+	history, err := storage.GetStatusHistory(context.Background(), "uid-1")
+	require.NoError(t, err)
+	assert.Equal(t, model.StatusAssembling, history.Status)
+	assert.Equal(t, 3, history.Version)
+	require.Len(t, history.Changes, 2)
+	assert.Equal(t, model.StatusPaid, history.Changes[0].To)
+	assert.Equal(t, "склад", history.Changes[1].Reason)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStorage_GetStatusHistory_NotFound(t *testing.T) {
+	storage, mock := setupStorageWithMock(t)
+	mock.ExpectQuery(`SELECT status, status_version FROM orders`).WillReturnRows(sqlmock.NewRows([]string{"status", "status_version"}))
+
+	_, err := storage.GetStatusHistory(context.Background(), "absent")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -52,11 +52,11 @@ func NewOrder() model.Order {
 
 	delivery := model.Delivery{
 		Name:    gofakeit.Name(),
-		Phone:   gofakeit.Phone(), // Генерирует в формате +...
-		Zip:     addr.Zip,         // Используем согласованный Zip
-		City:    addr.City,        // Используем согласованный City
-		Address: addr.Address,     // Используем согласованный Address
-		Region:  addr.State,       // Используем согласованный State (Region)
+		Phone:   "+1" + gofakeit.Phone(), // Номер США в формате E.164, как и адрес
+		Zip:     addr.Zip,                // Используем согласованный Zip
+		City:    addr.City,               // Используем согласованный City
+		Address: addr.Address,            // Используем согласованный Address
+		Region:  addr.State,              // Используем согласованный State (Region)
 		Email:   gofakeit.Email(),
 	}
 
@@ -67,7 +67,7 @@ func NewOrder() model.Order {
 	payment := model.Payment{
 		Transaction:  orderUID, // Связываем транзакцию с UID заказа
 		RequestID:    "",
-		Currency:     gofakeit.CurrencyShort(), // "USD", "EUR" и т.д.
+		Currency:     gofakeit.RandomString([]string{"USD", "EUR", "RUB", "KZT"}), // Действующие коды ISO 4217
 		Provider:     gofakeit.RandomString([]string{"wbpay", "click", "paypal"}),
 		Amount:       goodsTotal + deliveryCost,                             // Общая сумма
 		PaymentDt:    time.Now().Unix() - int64(gofakeit.Number(100, 1000)), // Недавнее прошлое
@@ -85,7 +85,7 @@ func NewOrder() model.Order {
 		Delivery:          delivery,
 		Payment:           payment,
 		Items:             items,
-		Locale:            gofakeit.LanguageAbbreviation(), // "en", "ru"
+		Locale:            gofakeit.RandomString([]string{"en", "ru"}),
 		InternalSignature: "",
 		CustomerID:        gofakeit.Username(),
 		DeliveryService:   gofakeit.RandomString([]string{"meest", "dhl", "pony"}),
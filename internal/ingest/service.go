@@ -0,0 +1,226 @@
+// Package ingest содержит прием заказов, общий для Kafka-консюмера и HTTP API:
+// десериализацию, валидацию, сохранение в БД с повторами и запись в кэш.
+package ingest
+
+import (
+	"L0_project/internal/cache"
+	"L0_project/internal/database"
+	"L0_project/internal/metrics"
+	"L0_project/internal/model"
+	"L0_project/internal/retry"
+	"L0_project/internal/validator"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Причины отклонения заказа. Используются в заголовке X-Error-Reason сообщений DLQ.
+const (
+	ReasonJSON         = "json_unmarshal_error"
+	ReasonValidation   = "validation_error"
+	ReasonBusinessRule = "business_rule_violation"
+)
+
+// InvalidOrderError возвращается Decode, если заказ не удалось десериализовать
+// или он не прошел валидацию. Повторять прием такого заказа бессмысленно.
+type InvalidOrderError struct {
+	Reason   string // ReasonJSON, ReasonValidation или ReasonBusinessRule
+	OrderUID string // Пусто, если JSON не разобран
+	Err      error
+}
+
+func (e *InvalidOrderError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *InvalidOrderError) Unwrap() error {
+	return e.Err
+}
+
+// Result - итог успешного приема заказа.
+type Result int
+
+const (
+	// ResultSaved - заказ сохранен (новый или с измененными данными).
+	ResultSaved Result = iota
+	// ResultUnchanged - заказ уже сохранен с теми же данными (повторная доставка).
+	ResultUnchanged
+)
+
+// String возвращает название результата для ответов API.
+func (r Result) String() string {
+	if r == ResultUnchanged {
+		return "unchanged"
+	}
+	return "saved"
+}
+
+// Outcome - результат приема одного заказа пакета.
+type Outcome struct {
+	Result Result
+	Err    error
+}
+
+// Service принимает заказы: Kafka-консюмер и HTTP API обрабатывают их одинаково.
+type Service struct {
+	storage database.Storage
+	loader  *cache.Loader[string, *model.Order] // Кэш заказов: сохраненные заказы записываются в него
+	tracer  trace.Tracer                        // Для трассировки
+	retry   retry.Policy                        // Повторы сохранения в БД
+}
+
+// NewService создает сервис приема заказов. loader должен читать через тот же
+// кэш, из которого отдаются заказы.
+func NewService(storage database.Storage, loader *cache.Loader[string, *model.Order], policy retry.Policy) *Service {
+	return &Service{
+		storage: storage,
+		loader:  loader,
+		tracer:  otel.Tracer("ingest-service"),
+		retry:   policy,
+	}
+}
+
+// Decode десериализует и валидирует заказ: сначала по тегам полей, затем
+// бизнес-правилами. Для некорректного заказа возвращает *InvalidOrderError.
+// Нарушения правил с уровнем warn не мешают приему: они записываются в лог
+// и метрики и возвращаются в warnings.
+func (s *Service) Decode(data []byte) (order *model.Order, warnings []validator.Violation, err error) {
+	order = &model.Order{}
+	if err := json.Unmarshal(data, order); err != nil {
+		return nil, nil, &InvalidOrderError{Reason: ReasonJSON, Err: err}
+	}
+
+	// Валидация данных
+	if err := validator.ValidateStruct(order); err != nil {
+		return nil, nil, &InvalidOrderError{Reason: ReasonValidation, OrderUID: order.OrderUID, Err: err}
+	}
+
+	// Проверка бизнес-правил
+	warnings, err = validator.ValidateRules(order)
+	for _, warning := range warnings {
+		metrics.ValidationRuleViolations.WithLabelValues(warning.Code, string(warning.Severity)).Inc()
+		log.Printf("Предупреждение валидации заказа %s: %s: %s (%s)", order.OrderUID, warning.Path, warning.Message, warning.Code)
+	}
+	var rulesErr *validator.RuleViolationError
+	if errors.As(err, &rulesErr) {
+		for _, violation := range rulesErr.Violations {
+			metrics.ValidationRuleViolations.WithLabelValues(violation.Code, string(violation.Severity)).Inc()
+		}
+		return nil, warnings, &InvalidOrderError{Reason: ReasonBusinessRule, OrderUID: order.OrderUID, Err: err}
+	}
+	return order, warnings, nil
+}
+
+// Save сохраняет заказ и записывает его в кэш.
+//
+// Временные ошибки БД повторяются, пока не будет отменен ctx: в этом случае
+// возвращается ошибка, оборачивающая ctx.Err(). Конфликт уникальности
+// (*database.OrderConflictError) и постоянные ошибки не повторяются,
+// нераспознанные - не более MaxRetries раз. Повторная доставка уже
+// сохраненного заказа считается успехом (ResultUnchanged).
+func (s *Service) Save(ctx context.Context, order *model.Order) (Result, error) {
+	// Создаем span для трассировки
+	ctx, span := s.tracer.Start(ctx, "Ingest.Save")
+	defer span.End()
+
+	return s.complete(ctx, order, s.storage.SaveOrder(ctx, order))
+}
+
+// SaveBatch сохраняет заказы одним вызовом SaveOrders. Результат каждого
+// заказа обрабатывается так же, как в Save, поэтому ошибка одного заказа не
+// влияет на остальные. Если пакет не сохранен целиком, заказы сохраняются по одному.
+func (s *Service) SaveBatch(ctx context.Context, orders []*model.Order) []Outcome {
+	outcomes := make([]Outcome, len(orders))
+	if len(orders) == 0 {
+		return outcomes
+	}
+	if len(orders) == 1 {
+		outcomes[0].Result, outcomes[0].Err = s.Save(ctx, orders[0])
+		return outcomes
+	}
+
+	// Создаем span для трассировки
+	ctx, span := s.tracer.Start(ctx, "Ingest.SaveBatch")
+	defer span.End()
+
+	results, err := s.storage.SaveOrders(ctx, orders)
+	if err != nil {
+		// Пакет не сохранен целиком: сохраняем заказы по одному с ретраями.
+		metrics.DBErrors.WithLabelValues("save_orders").Inc()
+		log.Printf("Ошибка пакетного сохранения %d заказов, сохраняем по одному: %v", len(orders), err)
+		for i, order := range orders {
+			outcomes[i].Result, outcomes[i].Err = s.complete(ctx, order, s.storage.SaveOrder(ctx, order))
+		}
+		return outcomes
+	}
+
+	for i, order := range orders {
+		outcomes[i].Result, outcomes[i].Err = s.complete(ctx, order, results[i])
+	}
+	return outcomes
+}
+
+// complete обрабатывает результат первой попытки сохранения заказа (dbErr):
+// при необходимости повторяет сохранение и записывает сохраненный заказ в кэш.
+//
+// В кэш записывается заказ, перечитанный из БД после сохранения: статус и
+// версию статуса ведет БД, а в принятом заказе их нет.
+func (s *Service) complete(ctx context.Context, order *model.Order, dbErr error) (Result, error) {
+	first := true
+	err := s.retry.Do(ctx, func() error {
+		if !first {
+			dbErr = s.storage.SaveOrder(ctx, order)
+		}
+		first = false
+
+		var conflict *database.OrderConflictError
+		if dbErr == nil || errors.Is(dbErr, database.ErrOrderUnchanged) || errors.As(dbErr, &conflict) {
+			return nil // Успешно, либо повтор не поможет
+		}
+		metrics.DBErrors.WithLabelValues("save_order").Inc()
+		return dbErr
+	})
+	switch {
+	case err != nil && ctx.Err() != nil:
+		return ResultSaved, fmt.Errorf("сохранение заказа %s прервано: %w", order.OrderUID, ctx.Err())
+	case err != nil:
+		return ResultSaved, err
+	}
+
+	var conflict *database.OrderConflictError
+	if errors.As(dbErr, &conflict) {
+		return ResultSaved, dbErr
+	}
+
+	if errors.Is(dbErr, database.ErrOrderUnchanged) {
+		// Повторная доставка того же заказа: данные в БД не изменились, кэш актуален
+		log.Printf("Заказ %s уже сохранен с теми же данными (повторная доставка).", order.OrderUID)
+		return ResultUnchanged, nil
+	}
+
+	log.Printf("Заказ %s успешно сохранен в БД.", order.OrderUID)
+
+	// Записываем сохраненный заказ во все уровни кэша. Ошибка чтения не отменяет
+	// сохранения: заказ удален из кэша и будет загружен при следующем запросе.
+	if err := s.loader.Refresh(ctx, order.OrderUID, s.loadOrder(order.OrderUID)); err != nil {
+		log.Printf("Не удалось записать заказ %s в кэш: %v", order.OrderUID, err)
+	}
+	return ResultSaved, nil
+}
+
+// loadOrder возвращает функцию загрузки заказа из БД для кэша.
+func (s *Service) loadOrder(orderUID string) cache.LoadFunc[*model.Order] {
+	return func(ctx context.Context) (*model.Order, error) {
+		order, err := s.storage.GetOrderByUID(ctx, orderUID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, cache.ErrNotFound
+		}
+		return order, err
+	}
+}
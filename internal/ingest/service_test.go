@@ -0,0 +1,183 @@
+package ingest
+
+import (
+	"L0_project/internal/cache"
+	"L0_project/internal/cache/mocks"
+	"L0_project/internal/database"
+	db_mocks "L0_project/internal/database/mocks"
+	"L0_project/internal/model"
+	"L0_project/internal/retry"
+	"L0_project/internal/validator"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+// setupService - хелпер для создания сервиса приема с моками
+func setupService(t *testing.T) (*Service, *mocks.MockCache[string, *model.Order], *db_mocks.MockStorage) {
+	ctrl := gomock.NewController(t)
+	mockCache := mocks.NewMockCache[string, *model.Order](ctrl)
+	mockStorage := db_mocks.NewMockStorage(ctrl)
+	service := NewService(mockStorage, cache.NewLoader(mockCache), retry.Policy{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond, IsTransient: database.IsTransient, IsPermanent: database.IsPermanent})
+	return service, mockCache, mockStorage
+}
+
+// validOrderJSON - валидный заказ для тестов
+const validOrderJSON = `{
+	"order_uid": "b563feb7-b2b8-4b6f-807c-9b63a11e81b9", "track_number": "WBILMTESTTRACK", "entry": "WBIL",
+	"delivery": {"name": "Test Testov", "phone": "+9720000000", "zip": "2639809", "city": "Kiryat Mozkin",
+		"address": "Ploshad Mira 15", "region": "Kraiot", "email": "test@gmail.com"},
+	"payment": {"transaction": "b563feb7-b2b8-4b6f-807c-9b63a11e81b9", "currency": "USD", "provider": "wbpay", "amount": 1817,
+		"payment_dt": 1637907727, "bank": "alpha", "delivery_cost": 1500, "goods_total": 317},
+	"items": [{"chrt_id": 9934930, "track_number": "WBILMTESTTRACK", "price": 453, "rid": "ab4219087a764ae0btest",
+		"name": "Mascaras", "sale": 30, "size": "0", "total_price": 317, "nm_id": 2389212, "brand": "Vivienne Sabo", "status": 202}],
+	"locale": "en", "customer_id": "test", "delivery_service": "meest", "shardkey": "9", "sm_id": 99,
+	"date_created": "2021-11-26T06:22:19Z", "oof_shard": "1"
+}`
+
+func TestService_Decode(t *testing.T) {
+	service, _, _ := setupService(t)
+
+	order, warnings, err := service.Decode([]byte(validOrderJSON))
+	require.NoError(t, err)
+	assert.Empty(t, warnings)
+	assert.Equal(t, "b563feb7-b2b8-4b6f-807c-9b63a11e81b9", order.OrderUID)
+
+	var invalid *InvalidOrderError
+	_, _, err = service.Decode([]byte("not json"))
+	require.ErrorAs(t, err, &invalid)
+	assert.Equal(t, ReasonJSON, invalid.Reason)
+
+	_, _, err = service.Decode([]byte(`{"order_uid": "not-a-uuid"}`))
+	require.ErrorAs(t, err, &invalid)
+	assert.Equal(t, ReasonValidation, invalid.Reason)
+	assert.Equal(t, "not-a-uuid", invalid.OrderUID)
+}
+
+func TestService_Decode_BusinessRules(t *testing.T) {
+	service, _, _ := setupService(t)
+
+	// Сумма платежа не сходится: заказ отклоняется
+	_, _, err := service.Decode([]byte(strings.Replace(validOrderJSON, `"amount": 1817`, `"amount": 1000`, 1)))
+	var invalid *InvalidOrderError
+	require.ErrorAs(t, err, &invalid)
+	assert.Equal(t, ReasonBusinessRule, invalid.Reason)
+	var rulesErr *validator.RuleViolationError
+	require.ErrorAs(t, err, &rulesErr)
+	assert.Equal(t, validator.RuleAmount, rulesErr.Violations[0].Code)
+
+	// Транзакция не совпадает с UID: заказ принимается с предупреждением
+	order, warnings, err := service.Decode([]byte(strings.Replace(validOrderJSON, `"transaction": "b563feb7-b2b8-4b6f-807c-9b63a11e81b9"`, `"transaction": "other"`, 1)))
+	require.NoError(t, err)
+	assert.NotNil(t, order)
+	require.Len(t, warnings, 1)
+	assert.Equal(t, validator.RuleTransaction, warnings[0].Code)
+}
+
+func TestService_Save(t *testing.T) {
+	testCases := []struct {
+		name       string
+		saveErr    error
+		wantErr    bool
+		wantResult Result
+		wantCached bool
+	}{
+		{name: "новый заказ", wantResult: ResultSaved, wantCached: true},
+		{name: "повторный прием", saveErr: database.ErrOrderUnchanged, wantResult: ResultUnchanged},
+		{name: "конфликт", saveErr: &database.OrderConflictError{Err: errors.New("duplicate key")}, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			service, mockCache, mockStorage := setupService(t)
+			order := &model.Order{OrderUID: "uid-1"}
+
+			mockStorage.EXPECT().SaveOrder(gomock.Any(), order).Return(tc.saveErr)
+			if tc.wantCached {
+				// В кэш записывается заказ из БД: статус ведет она
+				stored := &model.Order{OrderUID: "uid-1", Status: model.StatusPaid, StatusVersion: 2}
+				mockStorage.EXPECT().GetOrderByUID(gomock.Any(), "uid-1").Return(stored, nil)
+				mockCache.EXPECT().Set(gomock.Any(), "uid-1", stored)
+			}
+
+			result, err := service.Save(context.Background(), order)
+			if tc.wantErr {
+				assert.ErrorIs(t, err, tc.saveErr)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tc.wantResult, result)
+			}
+		})
+	}
+}
+
+func TestService_SaveBatch_FallbackToSingleSaves(t *testing.T) {
+	service, mockCache, mockStorage := setupService(t)
+	orders := []*model.Order{{OrderUID: "uid-1"}, {OrderUID: "uid-2"}}
+
+	// Пакет целиком не сохранился - заказы сохраняются по одному
+	mockStorage.EXPECT().SaveOrders(gomock.Any(), orders).Return(nil, errors.New("commit failed"))
+	mockStorage.EXPECT().SaveOrder(gomock.Any(), orders[0]).Return(nil)
+	mockStorage.EXPECT().SaveOrder(gomock.Any(), orders[1]).Return(database.ErrOrderUnchanged)
+	mockStorage.EXPECT().GetOrderByUID(gomock.Any(), "uid-1").Return(orders[0], nil)
+	mockCache.EXPECT().Set(gomock.Any(), "uid-1", orders[0])
+
+	outcomes := service.SaveBatch(context.Background(), orders)
+	assert.Equal(t, []Outcome{{Result: ResultSaved}, {Result: ResultUnchanged}}, outcomes)
+}
+
+func TestService_Save_ReloadFailureEvictsOrder(t *testing.T) {
+	service, mockCache, mockStorage := setupService(t)
+	order := &model.Order{OrderUID: "uid-1"}
+
+	// Заказ сохранен, но перечитать его не удалось: прежнее значение удаляется из кэша
+	mockStorage.EXPECT().SaveOrder(gomock.Any(), order).Return(nil)
+	mockStorage.EXPECT().GetOrderByUID(gomock.Any(), "uid-1").Return(nil, errors.New("connection reset"))
+	mockCache.EXPECT().Delete(gomock.Any(), "uid-1")
+
+	result, err := service.Save(context.Background(), order)
+	require.NoError(t, err)
+	assert.Equal(t, ResultSaved, result)
+}
+
+func TestService_Save_WritesThroughBothTiers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockStorage := db_mocks.NewMockStorage(ctrl)
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	local := cache.NewLRUCache[string, *model.Order](10)
+	tiered := cache.NewTieredCache(local, client, cache.WithRemoteKeyPrefix("order:"))
+	service := NewService(mockStorage, cache.NewLoader(tiered), retry.Policy{})
+
+	order := &model.Order{OrderUID: "uid-1", TrackNumber: "TRACK"}
+	stored := &model.Order{OrderUID: "uid-1", TrackNumber: "TRACK", Status: model.StatusPaid, StatusVersion: 2}
+	mockStorage.EXPECT().SaveOrder(gomock.Any(), order).Return(nil)
+	mockStorage.EXPECT().GetOrderByUID(gomock.Any(), "uid-1").Return(stored, nil)
+
+	_, err := service.Save(context.Background(), order)
+	require.NoError(t, err)
+
+	// Локальный уровень хранит заказ из БД, со статусом
+	entry, found := local.Peek("uid-1")
+	require.True(t, found)
+	assert.Equal(t, model.StatusPaid, entry.Value.Status)
+
+	// Общий уровень тоже
+	data, err := server.Get("order:uid-1")
+	require.NoError(t, err)
+	var remote model.Order
+	require.NoError(t, json.Unmarshal([]byte(data), &remote))
+	assert.Equal(t, model.StatusPaid, remote.Status)
+	assert.Equal(t, 2, remote.StatusVersion)
+}
@@ -4,13 +4,16 @@ import (
 	"L0_project/internal/cache"
 	"L0_project/internal/config"
 	"L0_project/internal/database"
+	"L0_project/internal/ingest"
 	"L0_project/internal/metrics"
 	"L0_project/internal/model"
+	"L0_project/internal/retry"
 	"L0_project/internal/validator"
 	"context"
 	"encoding/json"
+	"errors"
 	"log"
-	"time"
+	"sync"
 
 	"github.com/segmentio/kafka-go"
 	"go.opentelemetry.io/otel"
@@ -26,16 +29,35 @@ type KafkaMessageReader interface {
 
 // Consumer читает и обрабатывает сообщения из Kafka.
 type Consumer struct {
-	reader     KafkaMessageReader // Используем интерфейс
-	dlqWriter  *kafka.Writer      // Продюсер для отправки "битых" сообщений в DLQ
-	storage    database.Storage
-	cache      cache.Cache
-	tracer     trace.Tracer // Для трассировки
-	maxRetries int          // Количество попыток для временных ошибок БД
+	reader    KafkaMessageReader // Используем интерфейс
+	dlqWriter *kafka.Writer      // Продюсер для отправки "битых" сообщений в DLQ
+	ingest    *ingest.Service    // Прием заказов, общий с HTTP API
+	storage   database.Storage
+	loader    *cache.Loader[string, *model.Order] // Чтение заказов через кэш: заказы со сменой статуса из него удаляются
+	tracer    trace.Tracer                        // Для трассировки
+	retry     retry.Policy                        // Повторы операций с БД для событий статуса
+
+	pool poolOptions // Параметры пула обработчиков и пакетной обработки
+
+	// Ридер топика событий статуса; nil, если их обработка отключена
+	statusReader KafkaMessageReader
+	status       statusOptions
+}
+
+// RetryPolicy возвращает политику повторов операций с БД из настроек Kafka.
+func RetryPolicy(cfg config.KafkaConfig) retry.Policy {
+	return retry.Policy{
+		MaxRetries:  cfg.MaxRetries,
+		BaseDelay:   cfg.RetryBaseDelay,
+		MaxDelay:    cfg.RetryMaxDelay,
+		IsTransient: database.IsTransient,
+		IsPermanent: database.IsPermanent,
+	}
 }
 
-// NewConsumer создает новый экземпляр Consumer.
-func NewConsumer(cfg config.KafkaConfig, storage database.Storage, cache cache.Cache) *Consumer {
+// NewConsumer создает новый экземпляр Consumer. Заказы принимаются через
+// service, тот же, что использует HTTP API; loader должен читать через тот же кэш.
+func NewConsumer(cfg config.KafkaConfig, service *ingest.Service, storage database.Storage, loader *cache.Loader[string, *model.Order]) *Consumer {
 	reader := kafka.NewReader(kafka.ReaderConfig{
 		Brokers:  cfg.Brokers,
 		GroupID:  cfg.GroupID,
@@ -52,19 +74,55 @@ func NewConsumer(cfg config.KafkaConfig, storage database.Storage, cache cache.C
 		Balancer: &kafka.LeastBytes{},
 	}
 
+	var statusReader KafkaMessageReader
+	if cfg.StatusParkRetryInterval <= 0 {
+		cfg.StatusParkRetryInterval = defaultParkRetryInterval
+	}
+	if cfg.StatusTopic != "" {
+		statusReader = kafka.NewReader(kafka.ReaderConfig{
+			Brokers:  cfg.Brokers,
+			GroupID:  cfg.StatusGroupID,
+			Topic:    cfg.StatusTopic,
+			MinBytes: 1,    // События статуса небольшие, не ждем накопления
+			MaxBytes: 10e6, // 10MB
+		})
+	}
+
 	return &Consumer{
-		reader:     reader, // *kafka.Reader реализует интерфейс KafkaMessageReader
-		dlqWriter:  dlqWriter,
-		storage:    storage,
-		cache:      cache,
-		tracer:     otel.Tracer("kafka-consumer"),
-		maxRetries: 3, // 3 попытки на сохранение в БД
+		reader:    reader, // *kafka.Reader реализует интерфейс KafkaMessageReader
+		dlqWriter: dlqWriter,
+		ingest:    service,
+		storage:   storage,
+		loader:    loader,
+		tracer:    otel.Tracer("kafka-consumer"),
+		retry:     RetryPolicy(cfg),
+
+		pool: poolOptions{
+			workers:     cfg.Workers,
+			queueSize:   cfg.PartitionQueueSize,
+			maxInFlight: cfg.MaxInFlight,
+			batchSize:   cfg.BatchSize,
+			batchLinger: cfg.BatchLinger,
+		},
+
+		statusReader: statusReader,
+		status: statusOptions{
+			topic:         cfg.StatusTopic,
+			retryInterval: cfg.StatusParkRetryInterval,
+			maxAttempts:   cfg.StatusParkMaxAttempts,
+		},
 	}
 }
 
 // Run запускает цикл чтения сообщений из Kafka.
+// Сообщения обрабатываются пулом обработчиков параллельно: сообщения одного
+// заказа (ключ order_uid) обрабатываются последовательно в порядке партиции,
+// а коммит смещений продвигается только по непрерывно завершенным сообщениям.
+//
+// Если задан топик событий статуса, события читаются отдельным ридером и
+// пулом, а отложенные события периодически применяются повторно.
 func (c *Consumer) Run(ctx context.Context) {
-	log.Println("Kafka-консюмер запущен...")
+	log.Printf("Kafka-консюмер запущен (обработчиков: %d, размер пакета: %d)...", c.pool.workers, c.pool.batchSize)
 	defer func() {
 		if err := c.reader.Close(); err != nil {
 			log.Printf("Ошибка закрытия Kafka-ридера: %v", err)
@@ -74,91 +132,190 @@ func (c *Consumer) Run(ctx context.Context) {
 		}
 	}()
 
-	for {
-		select {
-		case <-ctx.Done():
-			log.Println("Kafka-консюмер останавливается.")
-			return
-		default:
-			// FetchMessage используется для ручного контроля коммитов
-			msg, err := c.reader.FetchMessage(ctx)
-			if err != nil {
-				log.Printf("Ошибка чтения сообщения из Kafka: %v", err)
-				continue
+	var wg sync.WaitGroup
+	if c.statusReader != nil {
+		defer func() {
+			if err := c.statusReader.Close(); err != nil {
+				log.Printf("Ошибка закрытия Kafka-ридера событий статуса: %v", err)
 			}
+		}()
+
+		// События применяются по одному: пакетное сохранение к ним не относится
+		statusPool := c.pool
+		statusPool.batchSize = 1
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			log.Printf("Чтение событий статуса из топика %s запущено.", c.status.topic)
+			c.fetchLoop(ctx, c.statusReader, newDispatcher(statusPool, c.processStatusBatch, c.statusReader.CommitMessages))
+		}()
+		go func() {
+			defer wg.Done()
+			c.runParkedRetrier(ctx)
+		}()
+	}
+	// Ридеры и DLQ закрываются только после остановки всех циклов
+	defer wg.Wait()
+
+	c.fetchLoop(ctx, c.reader, newDispatcher(c.pool, c.processBatch, c.reader.CommitMessages))
+	log.Println("Kafka-консюмер останавливается.")
+}
+
+// fetchLoop читает сообщения из reader и передает их в пул до отмены ctx.
+// Перед возвратом дожидается уже принятых сообщений и коммитит их.
+func (c *Consumer) fetchLoop(ctx context.Context, reader KafkaMessageReader, pool *dispatcher) {
+	pool.start(ctx)
+	defer pool.stop()
 
-			// Обрабатываем сообщение
-			procErr := c.processMessage(ctx, msg)
-
-			if procErr != nil {
-				// Ошибка = нужна повторная обработка.
-				// Мы НЕ коммитим сообщение, Kafka доставит его повторно.
-				log.Printf("Ошибка обработки сообщения (UID: %s): %v. Не коммитим, ждем retry.", string(msg.Key), procErr)
-			} else {
-				// nil = обработка успешна (в т.ч. уход в DLQ).
-				// Коммитим, чтобы Kafka не присылала его снова.
-				if err := c.reader.CommitMessages(ctx, msg); err != nil {
-					log.Printf("Ошибка коммита сообщения: %v", err)
-				}
+	for ctx.Err() == nil {
+		// FetchMessage используется для ручного контроля коммитов
+		msg, err := reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Ошибка чтения сообщения из Kafka: %v", err)
 			}
+			continue
+		}
+
+		// Передаем сообщение в пул. Блокируется при достижении лимита сообщений в обработке.
+		if err := pool.dispatch(ctx, msg); err != nil {
+			log.Printf("Сообщение %s не передано в обработку: %v", string(msg.Key), err)
 		}
 	}
 }
 
 // processMessage выполняет десериализацию, валидацию, сохранение и кэширование заказа.
-// Возвращает error, если нужен Kafka-retry (например, БД временно недоступна).
+// Возвращает error, если нужен Kafka-retry (например, обработку прервала остановка сервиса).
 // Возвращает nil, если обработка успешна или сообщение ушло в DLQ (не нужно ретраить).
+// Повторная доставка уже сохраненного заказа считается успехом.
 func (c *Consumer) processMessage(ctx context.Context, msg kafka.Message) error {
 	ctx, span := c.tracer.Start(ctx, "Consumer.processMessage")
 	defer span.End()
 
-	var order model.Order
-	if err := json.Unmarshal(msg.Value, &order); err != nil {
-		log.Printf("Невалидное JSON-сообщение, отправка в DLQ: %v", err)
-		c.sendToDLQ(ctx, msg, "json_unmarshal_error", err)
-		metrics.KafkaMessagesProcessed.WithLabelValues("dlq_validation").Inc()
-		return nil // Коммитим (не ретраим "битый" JSON)
+	order, ok := c.decodeOrder(ctx, msg)
+	if !ok {
+		return nil // Коммитим (сообщение ушло в DLQ)
 	}
 
-	// Валидация данных
-	if err := validator.ValidateStruct(&order); err != nil {
-		log.Printf("Ошибка валидации для UID %s, отправка в DLQ: %v", order.OrderUID, err)
-		c.sendToDLQ(ctx, msg, "validation_error", err)
-		metrics.KafkaMessagesProcessed.WithLabelValues("dlq_validation").Inc()
-		return nil // Коммитим (не ретраим невалидные данные)
+	result, err := c.ingest.Save(ctx, order)
+	return c.completeSave(ctx, msg, order, result, err)
+}
+
+// processBatch обрабатывает пакет сообщений: невалидные уходят в DLQ по отдельности,
+// а валидные заказы сохраняются одним пакетом. Результат для каждого сообщения
+// обрабатывается так же, как в processMessage, поэтому ошибка одного заказа не
+// влияет на остальные. Возвращает ошибку для каждого сообщения пакета.
+func (c *Consumer) processBatch(ctx context.Context, msgs []kafka.Message) []error {
+	errs := make([]error, len(msgs))
+	if len(msgs) == 1 {
+		errs[0] = c.processMessage(ctx, msgs[0])
+		return errs
+	}
+
+	ctx, span := c.tracer.Start(ctx, "Consumer.processBatch")
+	defer span.End()
+
+	var (
+		orders  []*model.Order
+		indexes []int
+	)
+	for i, msg := range msgs {
+		if order, ok := c.decodeOrder(ctx, msg); ok {
+			orders = append(orders, order)
+			indexes = append(indexes, i)
+		}
 	}
 
-	// Сохранение в БД с внутренним Retry-циклом
-	var dbErr error
-	for i := 0; i < c.maxRetries; i++ {
-		dbErr = c.storage.SaveOrder(ctx, &order)
-		if dbErr == nil {
-			break // Успешно
+	for j, outcome := range c.ingest.SaveBatch(ctx, orders) {
+		i := indexes[j]
+		errs[i] = c.completeSave(ctx, msgs[i], orders[j], outcome.Result, outcome.Err)
+	}
+	return errs
+}
+
+// decodeOrder десериализует и валидирует заказ. Если сообщение некорректно,
+// отправляет его в DLQ и возвращает false.
+func (c *Consumer) decodeOrder(ctx context.Context, msg kafka.Message) (*model.Order, bool) {
+	// Предупреждения бизнес-правил записывает сервис приема; в DLQ такие заказы не уходят
+	order, _, err := c.ingest.Decode(msg.Value)
+	var invalid *ingest.InvalidOrderError
+	if errors.As(err, &invalid) {
+		if invalid.Reason == ingest.ReasonJSON {
+			log.Printf("Невалидное JSON-сообщение, отправка в DLQ: %v", err)
+		} else {
+			log.Printf("Ошибка валидации для UID %s, отправка в DLQ: %v", invalid.OrderUID, err)
 		}
-		metrics.DBErrors.WithLabelValues("save_order").Inc()
-		log.Printf("Ошибка сохранения в БД (попытка %d/%d): %v", i+1, c.maxRetries, dbErr)
-		time.Sleep(time.Second * time.Duration(i+1)) // Простой backoff
+		c.sendToDLQ(ctx, msg, invalid.Reason, err)
+		metrics.KafkaMessagesProcessed.WithLabelValues("dlq_validation").Inc()
+		return nil, false // Не ретраим невалидные данные
 	}
+	return order, true
+}
+
+// completeSave обрабатывает итог приема заказа: отправляет сообщение в DLQ,
+// если заказ не сохранен и повтор не поможет.
+//
+// Временные ошибки БД сервис приема повторяет, пока не будет отменен ctx:
+// отправка в DLQ при сбое БД нарушила бы порядок заказов. Поэтому ошибка,
+// вызванная остановкой сервиса, возвращается, и сообщение не коммитится.
+func (c *Consumer) completeSave(ctx context.Context, msg kafka.Message, order *model.Order, result ingest.Result, err error) error {
+	var conflict *database.OrderConflictError
+	switch {
+	case err != nil && ctx.Err() != nil:
+		return err // Остановка сервиса: сообщение будет доставлено повторно
 
-	// Если после всех попыток ошибка осталась
-	if dbErr != nil {
-		log.Printf("Не удалось сохранить заказ %s после %d попыток, отправка в DLQ.", order.OrderUID, c.maxRetries)
-		c.sendToDLQ(ctx, msg, "db_save_error", dbErr)
+	case errors.As(err, &conflict):
+		log.Printf("Конфликт уникальности для заказа %s, отправка в DLQ: %v", order.OrderUID, err)
+		c.sendToDLQ(ctx, msg, "order_conflict", err)
+		metrics.KafkaMessagesProcessed.WithLabelValues("dlq_conflict").Inc()
+		return nil // Коммитим (данные противоречат уже сохраненному заказу)
+
+	case database.IsPermanent(err):
+		log.Printf("Постоянная ошибка сохранения заказа %s, отправка в DLQ: %v", order.OrderUID, err)
+		c.sendToDLQ(ctx, msg, "db_permanent_error", err)
+		metrics.KafkaMessagesProcessed.WithLabelValues("dlq_db_error").Inc()
+		return nil // Коммитим (повтор не поможет)
+
+	case err != nil:
+		// Если после всех попыток ошибка осталась
+		log.Printf("Не удалось сохранить заказ %s после %d попыток, отправка в DLQ.", order.OrderUID, c.retry.MaxRetries)
+		c.sendToDLQ(ctx, msg, "db_save_error", err)
 		metrics.KafkaMessagesProcessed.WithLabelValues("dlq_db_error").Inc()
 		return nil // Коммитим (не ретраим, т.к. исчерпали попытки)
-	}
 
-	log.Printf("Заказ %s успешно сохранен в БД.", order.OrderUID)
+	case result == ingest.ResultUnchanged:
+		metrics.KafkaMessagesProcessed.WithLabelValues("duplicate").Inc()
+		return nil // Коммитим
+	}
 
-	// Кэшируем указатель на копию
-	orderCopy := order
-	c.cache.Set(ctx, order.OrderUID, &orderCopy) // Передаем контекст
-	log.Printf("Заказ %s успешно сохранен в кэш.", order.OrderUID)
 	metrics.KafkaMessagesProcessed.WithLabelValues("success").Inc()
-
 	return nil
 }
 
+// errorDetails возвращает значение заголовка X-Error-Details: для ошибок
+// валидации - JSON-список ошибок полей (validator.ErrorSchema), для
+// нарушений бизнес-правил - JSON-список нарушений, для остальных - текст ошибки.
+func errorDetails(procErr error) []byte {
+	var (
+		validationErr *validator.ValidationError
+		rulesErr      *validator.RuleViolationError
+		details       []byte
+		err           error
+	)
+	switch {
+	case errors.As(procErr, &validationErr):
+		details, err = json.Marshal(validationErr.Fields)
+	case errors.As(procErr, &rulesErr):
+		details, err = json.Marshal(rulesErr.Violations)
+	default:
+		return []byte(procErr.Error())
+	}
+	if err != nil {
+		return []byte(procErr.Error())
+	}
+	return details
+}
+
 // sendToDLQ отправляет "битое" сообщение в DLQ топик.
 func (c *Consumer) sendToDLQ(ctx context.Context, originalMsg kafka.Message, reason string, procErr error) {
 	_, span := c.tracer.Start(ctx, "Consumer.sendToDLQ")
@@ -171,7 +328,7 @@ func (c *Consumer) sendToDLQ(ctx context.Context, originalMsg kafka.Message, rea
 		Headers: []kafka.Header{
 			{Key: "X-Original-Topic", Value: []byte(originalMsg.Topic)},
 			{Key: "X-Error-Reason", Value: []byte(reason)},
-			{Key: "X-Error-Details", Value: []byte(procErr.Error())},
+			{Key: "X-Error-Details", Value: errorDetails(procErr)},
 		},
 	})
 
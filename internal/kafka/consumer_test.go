@@ -1,9 +1,14 @@
 package kafka
 
 import (
+	"L0_project/internal/cache"
 	"L0_project/internal/cache/mocks"
+	"L0_project/internal/database"
 	db_mocks "L0_project/internal/database/mocks"
+	"L0_project/internal/ingest"
 	"L0_project/internal/model"
+	"L0_project/internal/retry"
+	"L0_project/internal/validator"
 	"context"
 	"encoding/json"
 	"errors"
@@ -12,6 +17,7 @@ import (
 
 	"go.opentelemetry.io/otel"
 
+	"github.com/lib/pq"
 	"github.com/segmentio/kafka-go"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/mock/gomock"
@@ -28,19 +34,24 @@ func (r *NoOpReader) CommitMessages(context.Context, ...kafka.Message) error {
 func (r *NoOpReader) Close() error { return nil }
 
 // setupConsumerAndMocks - хелпер для инициализации консюмера и моков
-func setupConsumerAndMocks(t *testing.T) (*gomock.Controller, *Consumer, *mocks.MockCache, *db_mocks.MockStorage) {
+func setupConsumerAndMocks(t *testing.T) (*gomock.Controller, *Consumer, *mocks.MockCache[string, *model.Order], *db_mocks.MockStorage) {
 	ctrl := gomock.NewController(t)
-	mockCache := mocks.NewMockCache(ctrl)
+	mockCache := mocks.NewMockCache[string, *model.Order](ctrl)
 	mockStorage := db_mocks.NewMockStorage(ctrl)
+	loader := cache.NewLoader(mockCache)
+
+	// Короткие задержки, чтобы тесты ретраев выполнялись быстро
+	policy := retry.Policy{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond, IsTransient: database.IsTransient, IsPermanent: database.IsPermanent}
 
 	// Используем NoOpReader
 	consumer := &Consumer{
-		reader:     &NoOpReader{},
-		storage:    mockStorage,
-		cache:      mockCache,
-		dlqWriter:  &kafka.Writer{}, // Инициализируем, чтобы избежать nil panic в тестах на DLQ
-		maxRetries: 3,               // Устанавливаем значение, как в NewConsumer
-		tracer:     otel.Tracer("test-tracer"),
+		reader:    &NoOpReader{},
+		ingest:    ingest.NewService(mockStorage, loader, policy),
+		storage:   mockStorage,
+		loader:    loader,
+		dlqWriter: &kafka.Writer{}, // Инициализируем, чтобы избежать nil panic в тестах на DLQ
+		tracer:    otel.Tracer("test-tracer"),
+		retry:     policy,
 	}
 
 	return ctrl, consumer, mockCache, mockStorage
@@ -105,7 +116,8 @@ func TestConsumer_ProcessMessage_Success(t *testing.T) {
 
 	// 1. Ожидаем сохранение в БД
 	mockStorage.EXPECT().SaveOrder(gomock.Any(), gomock.Any()).Return(nil)
-	// 2. Ожидаем сохранение в кэш
+	// 2. Ожидаем запись в кэш заказа, перечитанного из БД
+	mockStorage.EXPECT().GetOrderByUID(gomock.Any(), helperTestOrder.OrderUID).Return(&helperTestOrder, nil)
 	mockCache.EXPECT().Set(gomock.Any(), helperTestOrder.OrderUID, gomock.Any()).Times(1)
 
 	err := consumer.processMessage(context.Background(), msg)
@@ -120,9 +132,7 @@ func TestConsumer_ProcessMessage_DBError(t *testing.T) {
 	msg := kafka.Message{Value: orderBytes}
 	dbErr := errors.New("database connection failed")
 
-	consumer.maxRetries = 3
-
-	mockStorage.EXPECT().SaveOrder(gomock.Any(), gomock.Any()).Return(dbErr).Times(consumer.maxRetries)
+	mockStorage.EXPECT().SaveOrder(gomock.Any(), gomock.Any()).Return(dbErr).Times(consumer.retry.MaxRetries)
 	mockCache.EXPECT().Set(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
 
 	err := consumer.processMessage(context.Background(), msg)
@@ -139,13 +149,12 @@ func TestConsumer_ProcessMessage_DBError_RetryLogic(t *testing.T) {
 	msg := kafka.Message{Value: orderBytes}
 	dbErr := errors.New("temp db error")
 
-	consumer.maxRetries = 3
-
 	// 1. Ожидаем 2 неудачных вызова
 	mockStorage.EXPECT().SaveOrder(gomock.Any(), gomock.Any()).Return(dbErr).Times(2)
 	// 2. Ожидаем 1 удачный вызов
 	mockStorage.EXPECT().SaveOrder(gomock.Any(), gomock.Any()).Return(nil).Times(1)
-	// 3. Ожидаем Set в кэш
+	// 3. Ожидаем запись в кэш
+	mockStorage.EXPECT().GetOrderByUID(gomock.Any(), helperTestOrder.OrderUID).Return(&helperTestOrder, nil)
 	mockCache.EXPECT().Set(gomock.Any(), helperTestOrder.OrderUID, gomock.Any()).Times(1)
 
 	err := consumer.processMessage(context.Background(), msg)
@@ -191,3 +200,150 @@ func TestConsumer_ProcessMessage_ValidationError(t *testing.T) {
 	// Ошибка не должна быть возвращена, т.к. это "poison pill"
 	assert.NoError(t, err)
 }
+
+func TestErrorDetails(t *testing.T) {
+	validationErr := &validator.ValidationError{Fields: []validator.FieldError{
+		{Path: "items[2].price", Rule: "gt", Param: "0", Value: 0, Message: "должно быть больше 0"},
+	}}
+	wrapped := &ingest.InvalidOrderError{Reason: ingest.ReasonValidation, Err: validationErr}
+
+	// Ошибки валидации передаются в DLQ списком полей, остальные - текстом
+	assert.JSONEq(t, `[{"path":"items[2].price","rule":"gt","param":"0","value":0,"message":"должно быть больше 0"}]`, string(errorDetails(wrapped)))
+	assert.Equal(t, "boom", string(errorDetails(errors.New("boom"))))
+}
+
+func TestConsumer_ProcessMessage_DuplicateUnchanged(t *testing.T) {
+	ctrl, consumer, mockCache, mockStorage := setupConsumerAndMocks(t)
+	defer ctrl.Finish()
+
+	orderBytes, _ := json.Marshal(helperTestOrder)
+	msg := kafka.Message{Value: orderBytes}
+
+	// Повторная доставка: без ретраев, кэш не трогается, сообщение коммитится
+	mockStorage.EXPECT().SaveOrder(gomock.Any(), gomock.Any()).Return(database.ErrOrderUnchanged).Times(1)
+	mockCache.EXPECT().Set(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	err := consumer.processMessage(context.Background(), msg)
+	assert.NoError(t, err)
+}
+
+func TestConsumer_ProcessMessage_ConflictNotRetried(t *testing.T) {
+	ctrl, consumer, mockCache, mockStorage := setupConsumerAndMocks(t)
+	defer ctrl.Finish()
+
+	orderBytes, _ := json.Marshal(helperTestOrder)
+	msg := kafka.Message{Value: orderBytes}
+	conflictErr := &database.OrderConflictError{OrderUID: helperTestOrder.OrderUID, Constraint: "payments_transaction_key", Err: errors.New("duplicate key")}
+
+	// Конфликт уникальности: одна попытка, затем DLQ
+	mockStorage.EXPECT().SaveOrder(gomock.Any(), gomock.Any()).Return(conflictErr).Times(1)
+	mockCache.EXPECT().Set(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	err := consumer.processMessage(context.Background(), msg)
+	assert.NoError(t, err)
+}
+
+func TestConsumer_ProcessMessage_TransientErrorRetriedBeyondLimit(t *testing.T) {
+	ctrl, consumer, mockCache, mockStorage := setupConsumerAndMocks(t)
+	defer ctrl.Finish()
+
+	orderBytes, _ := json.Marshal(helperTestOrder)
+	msg := kafka.Message{Value: orderBytes}
+	transientErr := &pq.Error{Code: "08006"} // connection_failure
+
+	// Временная ошибка не ограничена maxRetries: 5 неудач, затем успех
+	gomock.InOrder(
+		mockStorage.EXPECT().SaveOrder(gomock.Any(), gomock.Any()).Return(transientErr).Times(5),
+		mockStorage.EXPECT().SaveOrder(gomock.Any(), gomock.Any()).Return(nil).Times(1),
+	)
+	mockStorage.EXPECT().GetOrderByUID(gomock.Any(), helperTestOrder.OrderUID).Return(&helperTestOrder, nil)
+	mockCache.EXPECT().Set(gomock.Any(), helperTestOrder.OrderUID, gomock.Any()).Times(1)
+
+	err := consumer.processMessage(context.Background(), msg)
+	assert.NoError(t, err)
+}
+
+func TestConsumer_ProcessMessage_PermanentErrorNotRetried(t *testing.T) {
+	ctrl, consumer, mockCache, mockStorage := setupConsumerAndMocks(t)
+	defer ctrl.Finish()
+
+	orderBytes, _ := json.Marshal(helperTestOrder)
+	msg := kafka.Message{Value: orderBytes}
+	permanentErr := &pq.Error{Code: "23503"} // foreign_key_violation
+
+	mockStorage.EXPECT().SaveOrder(gomock.Any(), gomock.Any()).Return(permanentErr).Times(1)
+	mockCache.EXPECT().Set(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	err := consumer.processMessage(context.Background(), msg)
+
+	// Сообщение ушло в DLQ и будет закоммичено
+	assert.NoError(t, err)
+}
+
+func TestConsumer_ProcessMessage_BackoffInterruptedByContext(t *testing.T) {
+	ctrl, consumer, mockCache, mockStorage := setupConsumerAndMocks(t)
+	defer ctrl.Finish()
+
+	orderBytes, _ := json.Marshal(helperTestOrder)
+	msg := kafka.Message{Value: orderBytes}
+	consumer.ingest = ingest.NewService(mockStorage, cache.NewLoader(mockCache), retry.Policy{MaxRetries: 3, BaseDelay: time.Hour, MaxDelay: time.Hour, IsTransient: database.IsTransient, IsPermanent: database.IsPermanent})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	mockStorage.EXPECT().SaveOrder(gomock.Any(), gomock.Any()).DoAndReturn(func(context.Context, *model.Order) error {
+		time.AfterFunc(10*time.Millisecond, cancel)
+		return &pq.Error{Code: "40P01"} // deadlock_detected
+	}).Times(1)
+	mockCache.EXPECT().Set(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	start := time.Now()
+	err := consumer.processMessage(ctx, msg)
+
+	// Ожидание прервано отменой, сообщение не коммитится
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestConsumer_ProcessBatch_PerOrderResults(t *testing.T) {
+	ctrl, consumer, mockCache, mockStorage := setupConsumerAndMocks(t)
+	defer ctrl.Finish()
+
+	second := helperTestOrder
+	second.OrderUID = "5f0b5e3a-7b8e-4a4f-9d63-1c2b3a4d5e6f"
+	firstBytes, _ := json.Marshal(helperTestOrder)
+	secondBytes, _ := json.Marshal(second)
+	msgs := []kafka.Message{
+		{Value: firstBytes},
+		{Value: []byte("this is not json")}, // уходит в DLQ, в пакет не попадает
+		{Value: secondBytes},
+	}
+	conflictErr := &database.OrderConflictError{OrderUID: second.OrderUID, Err: errors.New("duplicate key")}
+
+	mockStorage.EXPECT().SaveOrders(gomock.Any(), gomock.Len(2)).Return([]error{nil, conflictErr}, nil)
+	mockStorage.EXPECT().SaveOrder(gomock.Any(), gomock.Any()).Times(0)
+	// В кэш записывается только успешно сохраненный заказ
+	mockStorage.EXPECT().GetOrderByUID(gomock.Any(), helperTestOrder.OrderUID).Return(&helperTestOrder, nil)
+	mockCache.EXPECT().Set(gomock.Any(), helperTestOrder.OrderUID, gomock.Any()).Times(1)
+
+	errs := consumer.processBatch(context.Background(), msgs)
+	assert.Equal(t, []error{nil, nil, nil}, errs)
+}
+
+func TestConsumer_ProcessBatch_FallbackToSingleSaves(t *testing.T) {
+	ctrl, consumer, mockCache, mockStorage := setupConsumerAndMocks(t)
+	defer ctrl.Finish()
+
+	second := helperTestOrder
+	second.OrderUID = "5f0b5e3a-7b8e-4a4f-9d63-1c2b3a4d5e6f"
+	firstBytes, _ := json.Marshal(helperTestOrder)
+	secondBytes, _ := json.Marshal(second)
+	msgs := []kafka.Message{{Value: firstBytes}, {Value: secondBytes}}
+
+	// Пакет целиком не сохранился - заказы сохраняются по одному
+	mockStorage.EXPECT().SaveOrders(gomock.Any(), gomock.Any()).Return(nil, errors.New("commit failed"))
+	mockStorage.EXPECT().SaveOrder(gomock.Any(), gomock.Any()).Return(nil).Times(2)
+	mockStorage.EXPECT().GetOrderByUID(gomock.Any(), gomock.Any()).Return(&helperTestOrder, nil).Times(2)
+	mockCache.EXPECT().Set(gomock.Any(), gomock.Any(), gomock.Any()).Times(2)
+
+	errs := consumer.processBatch(context.Background(), msgs)
+	assert.Equal(t, []error{nil, nil}, errs)
+}
@@ -0,0 +1,349 @@
+package kafka
+
+import (
+	"context"
+	"hash/fnv"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// commitTimeout ограничивает финальный коммит смещений при остановке,
+// когда основной контекст уже отменен.
+const commitTimeout = 5 * time.Second
+
+// offsetTracker отслеживает смещения одной партиции, отданные в обработку.
+// Коммитить можно только непрерывный префикс завершенных сообщений: если
+// сообщение 5 еще обрабатывается, а 6 и 7 готовы, коммит остается на 4.
+//
+// После ошибки обработки коммит партиции не продвинется дальше сообщения с
+// ошибкой, пока Kafka не доставит его повторно (после перезапуска или
+// ребалансировки), поэтому следующие сообщения партиции до тех пор не
+// отслеживаются, и pending не растет.
+type offsetTracker struct {
+	pending  []trackedMessage // В порядке получения (смещения возрастают)
+	failed   bool             // Было сообщение с ошибкой: коммит партиции остановлен
+	failedAt int64            // Смещение сообщения с ошибкой
+}
+
+type trackedMessage struct {
+	msg  kafka.Message
+	done bool
+}
+
+// add регистрирует сообщение, переданное в обработку.
+func (t *offsetTracker) add(msg kafka.Message) {
+	if t.failed {
+		if msg.Offset > t.failedAt {
+			return
+		}
+		// Повторная доставка с закоммиченного смещения: отслеживание начинается заново
+		t.failed = false
+		t.pending = nil
+	}
+	t.pending = append(t.pending, trackedMessage{msg: msg})
+}
+
+// fail отмечает сообщение необработанным: коммит партиции останавливается на
+// нем, а сообщения после него перестают отслеживаться. Завершенный префикс
+// до сообщения по-прежнему можно закоммитить. Возвращает false, если коммит
+// партиции уже был остановлен.
+func (t *offsetTracker) fail(offset int64) bool {
+	if t.failed {
+		return false
+	}
+	t.failed = true
+	t.failedAt = offset
+	for i := range t.pending {
+		if t.pending[i].msg.Offset == offset {
+			t.pending = t.pending[:i+1]
+			break
+		}
+	}
+	return true
+}
+
+// complete отмечает сообщение обработанным и возвращает последнее сообщение
+// непрерывного завершенного префикса (его можно коммитить), если префикс сдвинулся.
+func (t *offsetTracker) complete(offset int64) (kafka.Message, bool) {
+	for i := range t.pending {
+		if t.pending[i].msg.Offset == offset {
+			t.pending[i].done = true
+			break
+		}
+	}
+
+	var (
+		last     kafka.Message
+		advanced bool
+	)
+	for len(t.pending) > 0 && t.pending[0].done {
+		last = t.pending[0].msg
+		advanced = true
+		t.pending = t.pending[1:]
+	}
+	return last, advanced
+}
+
+// dispatcher распределяет сообщения по пулу обработчиков и коммитит
+// смещения по мере их непрерывного завершения.
+//
+// Сообщения с одинаковым ключом (order_uid) всегда попадают к одному и тому же
+// обработчику и обрабатываются строго в порядке партиции. Сообщения без ключа
+// распределяются по номеру партиции, что сохраняет порядок внутри нее.
+type dispatcher struct {
+	process func(ctx context.Context, msgs []kafka.Message) []error
+	commit  func(ctx context.Context, msgs ...kafka.Message) error
+
+	batchSize   int
+	batchLinger time.Duration
+
+	lanes    []chan kafka.Message
+	inFlight chan struct{} // Семафор: ограничивает число сообщений в обработке
+
+	mu         sync.Mutex
+	partitions map[int]*offsetTracker
+	committed  map[int]kafka.Message // Готовые к коммиту сообщения по партициям
+	notify     chan struct{}
+
+	workersWG   sync.WaitGroup
+	committerWG sync.WaitGroup
+	stopCommit  chan struct{}
+}
+
+// poolOptions - параметры пула обработчиков.
+type poolOptions struct {
+	workers     int           // Количество обработчиков
+	queueSize   int           // Глубина очереди каждого обработчика
+	maxInFlight int           // Максимум сообщений в обработке одновременно
+	batchSize   int           // Максимальный размер пакета (1 - без пакетов)
+	batchLinger time.Duration // Сколько ждать добора пакета
+}
+
+// newDispatcher создает пул обработчиков. process получает пакет сообщений
+// одного обработчика и возвращает ошибку для каждого из них.
+func newDispatcher(opts poolOptions,
+	process func(ctx context.Context, msgs []kafka.Message) []error,
+	commit func(ctx context.Context, msgs ...kafka.Message) error,
+) *dispatcher {
+	if opts.workers <= 0 {
+		opts.workers = 1
+	}
+	if opts.queueSize < 0 {
+		opts.queueSize = 0
+	}
+	if opts.batchSize <= 0 {
+		opts.batchSize = 1
+	}
+	if opts.maxInFlight < opts.workers*opts.batchSize {
+		// Иначе обработчики не смогут набрать полный пакет
+		opts.maxInFlight = opts.workers * opts.batchSize
+	}
+
+	d := &dispatcher{
+		process:     process,
+		commit:      commit,
+		batchSize:   opts.batchSize,
+		batchLinger: opts.batchLinger,
+		lanes:       make([]chan kafka.Message, opts.workers),
+		inFlight:    make(chan struct{}, opts.maxInFlight),
+		partitions:  make(map[int]*offsetTracker),
+		committed:   make(map[int]kafka.Message),
+		notify:      make(chan struct{}, 1),
+		stopCommit:  make(chan struct{}),
+	}
+	for i := range d.lanes {
+		d.lanes[i] = make(chan kafka.Message, opts.queueSize)
+	}
+	return d
+}
+
+// start запускает обработчиков и горутину коммитов.
+func (d *dispatcher) start(ctx context.Context) {
+	for _, lane := range d.lanes {
+		d.workersWG.Add(1)
+		go d.worker(ctx, lane)
+	}
+
+	d.committerWG.Add(1)
+	go d.committer(ctx)
+}
+
+// dispatch передает сообщение обработчику. Блокируется, если достигнут лимит
+// сообщений в обработке или очередь обработчика заполнена.
+func (d *dispatcher) dispatch(ctx context.Context, msg kafka.Message) error {
+	select {
+	case d.inFlight <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	d.mu.Lock()
+	tracker, ok := d.partitions[msg.Partition]
+	if !ok {
+		tracker = &offsetTracker{}
+		d.partitions[msg.Partition] = tracker
+	}
+	tracker.add(msg)
+	d.mu.Unlock()
+
+	select {
+	case d.lanes[d.laneFor(msg)] <- msg:
+		return nil
+	case <-ctx.Done():
+		// Сообщение остается незавершенным в трекере: коммит не продвинется за него.
+		<-d.inFlight
+		return ctx.Err()
+	}
+}
+
+// laneFor выбирает обработчика по ключу сообщения (или по партиции, если ключа нет).
+func (d *dispatcher) laneFor(msg kafka.Message) int {
+	h := fnv.New32a()
+	if len(msg.Key) > 0 {
+		_, _ = h.Write(msg.Key)
+	} else {
+		_, _ = h.Write([]byte(strconv.Itoa(msg.Partition)))
+	}
+	return int(h.Sum32() % uint32(len(d.lanes)))
+}
+
+// worker последовательно обрабатывает сообщения своей очереди пакетами.
+func (d *dispatcher) worker(ctx context.Context, lane <-chan kafka.Message) {
+	defer d.workersWG.Done()
+
+	for msg := range lane {
+		batch := d.collect(lane, msg)
+
+		errs := d.process(ctx, batch)
+		for i, m := range batch {
+			if errs[i] != nil {
+				// Ошибка = нужна повторная обработка.
+				// Смещение не отмечается завершенным, поэтому коммит по этой партиции
+				// не продвинется дальше, и Kafka доставит сообщение повторно.
+				log.Printf("Ошибка обработки сообщения (UID: %s): %v. Не коммитим, ждем retry.", string(m.Key), errs[i])
+				d.markFailed(m)
+			} else {
+				d.markDone(m)
+			}
+			<-d.inFlight
+		}
+	}
+}
+
+// collect добирает пакет к первому сообщению, пока он не заполнится,
+// не истечет batchLinger или очередь не будет закрыта.
+func (d *dispatcher) collect(lane <-chan kafka.Message, first kafka.Message) []kafka.Message {
+	batch := []kafka.Message{first}
+	if d.batchSize <= 1 {
+		return batch
+	}
+
+	timer := time.NewTimer(d.batchLinger)
+	defer timer.Stop()
+
+	for len(batch) < d.batchSize {
+		select {
+		case msg, ok := <-lane:
+			if !ok {
+				return batch
+			}
+			batch = append(batch, msg)
+		case <-timer.C:
+			return batch
+		}
+	}
+	return batch
+}
+
+// markDone отмечает сообщение обработанным и будит горутину коммитов,
+// если непрерывный префикс партиции сдвинулся.
+func (d *dispatcher) markDone(msg kafka.Message) {
+	d.mu.Lock()
+	last, advanced := d.partitions[msg.Partition].complete(msg.Offset)
+	if advanced {
+		d.committed[msg.Partition] = last
+	}
+	d.mu.Unlock()
+
+	if advanced {
+		select {
+		case d.notify <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// markFailed останавливает коммит партиции на сообщении с ошибкой.
+func (d *dispatcher) markFailed(msg kafka.Message) {
+	d.mu.Lock()
+	stalled := d.partitions[msg.Partition].fail(msg.Offset)
+	d.mu.Unlock()
+
+	if stalled {
+		log.Printf("Коммит партиции %d остановлен на смещении %d до перезапуска или ребалансировки.", msg.Partition, msg.Offset)
+	}
+}
+
+// committer коммитит накопленные смещения. Работает в одной горутине, поэтому
+// коммиты по каждой партиции только возрастают.
+func (d *dispatcher) committer(ctx context.Context) {
+	defer d.committerWG.Done()
+
+	for {
+		select {
+		case <-d.notify:
+			d.flush(ctx)
+		case <-d.stopCommit:
+			// Финальный коммит: основной контекст к этому моменту может быть отменен.
+			flushCtx, cancel := context.WithTimeout(context.Background(), commitTimeout)
+			d.flush(flushCtx)
+			cancel()
+			return
+		}
+	}
+}
+
+// flush коммитит все готовые смещения одним вызовом.
+func (d *dispatcher) flush(ctx context.Context) {
+	d.mu.Lock()
+	if len(d.committed) == 0 {
+		d.mu.Unlock()
+		return
+	}
+	msgs := make([]kafka.Message, 0, len(d.committed))
+	for partition, msg := range d.committed {
+		msgs = append(msgs, msg)
+		delete(d.committed, partition)
+	}
+	d.mu.Unlock()
+
+	if err := d.commit(ctx, msgs...); err != nil {
+		log.Printf("Ошибка коммита сообщений: %v", err)
+
+		// Возвращаем смещения, если за это время по партиции не появилось более новых,
+		// чтобы следующий flush (в том числе финальный) повторил коммит.
+		d.mu.Lock()
+		for _, msg := range msgs {
+			if _, ok := d.committed[msg.Partition]; !ok {
+				d.committed[msg.Partition] = msg
+			}
+		}
+		d.mu.Unlock()
+	}
+}
+
+// stop дожидается обработки уже принятых сообщений и выполняет финальный коммит.
+// Вызывается после того, как dispatch больше не будет вызываться.
+func (d *dispatcher) stop() {
+	for _, lane := range d.lanes {
+		close(lane)
+	}
+	d.workersWG.Wait()
+
+	close(d.stopCommit)
+	d.committerWG.Wait()
+}
@@ -0,0 +1,203 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+)
+
+// perMessage адаптирует обработчик одного сообщения к пакетному интерфейсу dispatcher
+func perMessage(process func(context.Context, kafka.Message) error) func(context.Context, []kafka.Message) []error {
+	return func(ctx context.Context, msgs []kafka.Message) []error {
+		errs := make([]error, len(msgs))
+		for i, msg := range msgs {
+			errs[i] = process(ctx, msg)
+		}
+		return errs
+	}
+}
+
+func TestOffsetTracker_CommitsOnlyContiguousPrefix(t *testing.T) {
+	tracker := &offsetTracker{}
+	for offset := int64(1); offset <= 4; offset++ {
+		tracker.add(kafka.Message{Offset: offset})
+	}
+
+	// 2 и 3 готовы, но 1 еще в обработке - коммит не двигается
+	_, advanced := tracker.complete(2)
+	assert.False(t, advanced)
+	_, advanced = tracker.complete(3)
+	assert.False(t, advanced)
+
+	// 1 завершен - префикс сдвигается сразу до 3
+	last, advanced := tracker.complete(1)
+	assert.True(t, advanced)
+	assert.Equal(t, int64(3), last.Offset)
+
+	last, advanced = tracker.complete(4)
+	assert.True(t, advanced)
+	assert.Equal(t, int64(4), last.Offset)
+	assert.Empty(t, tracker.pending)
+}
+
+func TestOffsetTracker_FailureStopsTracking(t *testing.T) {
+	tracker := &offsetTracker{}
+	for offset := int64(1); offset <= 3; offset++ {
+		tracker.add(kafka.Message{Offset: offset})
+	}
+
+	// 2 завершился с ошибкой: 3 и все следующие сообщения больше не отслеживаются
+	assert.True(t, tracker.fail(2))
+	for offset := int64(4); offset <= 100; offset++ {
+		tracker.add(kafka.Message{Offset: offset})
+	}
+	assert.Len(t, tracker.pending, 2)
+
+	// Префикс до сообщения с ошибкой по-прежнему коммитится
+	last, advanced := tracker.complete(1)
+	assert.True(t, advanced)
+	assert.Equal(t, int64(1), last.Offset)
+	_, advanced = tracker.complete(3)
+	assert.False(t, advanced)
+
+	// Повторная доставка сообщения с ошибкой возобновляет отслеживание
+	tracker.add(kafka.Message{Offset: 2})
+	last, advanced = tracker.complete(2)
+	assert.True(t, advanced)
+	assert.Equal(t, int64(2), last.Offset)
+}
+
+func TestDispatcher_PreservesPerKeyOrderAndCommitsMonotonically(t *testing.T) {
+	const (
+		partitions = 3
+		keys       = 10
+		perKey     = 20
+	)
+
+	var (
+		mu        sync.Mutex
+		processed = make(map[string][]int64)
+		commits   = make(map[int][]int64)
+	)
+
+	process := func(_ context.Context, msg kafka.Message) error {
+		time.Sleep(time.Duration(rand.IntN(200)) * time.Microsecond)
+		mu.Lock()
+		processed[string(msg.Key)] = append(processed[string(msg.Key)], msg.Offset)
+		mu.Unlock()
+		return nil
+	}
+	commit := func(_ context.Context, msgs ...kafka.Message) error {
+		mu.Lock()
+		for _, msg := range msgs {
+			commits[msg.Partition] = append(commits[msg.Partition], msg.Offset)
+		}
+		mu.Unlock()
+		return nil
+	}
+
+	d := newDispatcher(poolOptions{workers: 4, queueSize: 2, maxInFlight: 8}, perMessage(process), commit)
+	ctx := context.Background()
+	d.start(ctx)
+
+	offsets := make(map[int]int64)
+	for i := 0; i < perKey; i++ {
+		for k := 0; k < keys; k++ {
+			partition := k % partitions
+			msg := kafka.Message{Partition: partition, Offset: offsets[partition], Key: []byte(fmt.Sprintf("order-%d", k))}
+			offsets[partition]++
+			assert.NoError(t, d.dispatch(ctx, msg))
+		}
+	}
+	d.stop()
+
+	// Сообщения каждого заказа обработаны в порядке смещений
+	for key, got := range processed {
+		assert.Len(t, got, perKey, key)
+		assert.IsIncreasing(t, got, key)
+	}
+
+	// Коммиты по партиции только растут, а финальный указывает на последнее сообщение
+	for partition, got := range commits {
+		assert.IsIncreasing(t, got, "partition %d", partition)
+		assert.Equal(t, offsets[partition]-1, got[len(got)-1], "partition %d", partition)
+	}
+}
+
+func TestDispatcher_FailedMessageBlocksCommit(t *testing.T) {
+	var (
+		mu        sync.Mutex
+		committed []int64
+	)
+
+	process := func(_ context.Context, msg kafka.Message) error {
+		if msg.Offset == 1 {
+			return fmt.Errorf("нужен retry")
+		}
+		return nil
+	}
+	commit := func(_ context.Context, msgs ...kafka.Message) error {
+		mu.Lock()
+		for _, msg := range msgs {
+			committed = append(committed, msg.Offset)
+		}
+		mu.Unlock()
+		return nil
+	}
+
+	d := newDispatcher(poolOptions{workers: 2, queueSize: 1, maxInFlight: 4}, perMessage(process), commit)
+	ctx := context.Background()
+	d.start(ctx)
+	for offset := int64(0); offset < 4; offset++ {
+		assert.NoError(t, d.dispatch(ctx, kafka.Message{Offset: offset, Key: []byte(fmt.Sprintf("k%d", offset))}))
+	}
+	d.stop()
+
+	// Коммит не продвигается дальше необработанного сообщения 1
+	assert.Equal(t, []int64{0}, committed)
+}
+
+func TestDispatcher_Batching(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		batches [][]int64
+	)
+
+	process := func(_ context.Context, msgs []kafka.Message) []error {
+		offsets := make([]int64, len(msgs))
+		for i, msg := range msgs {
+			offsets[i] = msg.Offset
+		}
+		mu.Lock()
+		batches = append(batches, offsets)
+		mu.Unlock()
+		return make([]error, len(msgs))
+	}
+	commit := func(context.Context, ...kafka.Message) error { return nil }
+
+	// Один обработчик: пакеты не больше 3, неполный пакет уходит по истечении linger
+	d := newDispatcher(poolOptions{workers: 1, queueSize: 10, batchSize: 3, batchLinger: 20 * time.Millisecond}, process, commit)
+	ctx := context.Background()
+	d.start(ctx)
+	for offset := int64(0); offset < 7; offset++ {
+		assert.NoError(t, d.dispatch(ctx, kafka.Message{Offset: offset, Key: []byte("order")}))
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	mu.Lock()
+	var total int
+	for _, batch := range batches {
+		assert.LessOrEqual(t, len(batch), 3)
+		total += len(batch)
+	}
+	mu.Unlock()
+	assert.Equal(t, 7, total, "неполный пакет должен быть обработан по истечении linger")
+
+	d.stop()
+}
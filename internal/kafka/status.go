@@ -0,0 +1,208 @@
+package kafka
+
+import (
+	"L0_project/internal/database"
+	"L0_project/internal/metrics"
+	"L0_project/internal/model"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+const (
+	// parkedClaimLimit - сколько отложенных событий статуса применяется за один проход.
+	parkedClaimLimit = 100
+	// defaultParkRetryInterval используется, если период повтора не задан.
+	defaultParkRetryInterval = 10 * time.Second
+)
+
+// statusOptions - параметры обработки событий статуса.
+type statusOptions struct {
+	topic         string        // Топик событий статуса (для заголовков DLQ)
+	retryInterval time.Duration // Период повторного применения отложенных событий
+	maxAttempts   int           // После стольких повторов отложенное событие уходит в DLQ
+}
+
+// processStatusBatch применяет события статуса по одному, в порядке сообщений.
+func (c *Consumer) processStatusBatch(ctx context.Context, msgs []kafka.Message) []error {
+	errs := make([]error, len(msgs))
+	for i, msg := range msgs {
+		errs[i] = c.processStatusMessage(ctx, msg)
+	}
+	return errs
+}
+
+// processStatusMessage десериализует и применяет событие статуса. Событие для
+// заказа или товара, которого еще нет в БД, откладывается и будет применено
+// повторно. Возвращает error, только если обработку прервала остановка сервиса.
+func (c *Consumer) processStatusMessage(ctx context.Context, msg kafka.Message) error {
+	ctx, span := c.tracer.Start(ctx, "Consumer.processStatusMessage")
+	defer span.End()
+
+	var event model.StatusEvent
+	if err := json.Unmarshal(msg.Value, &event); err != nil {
+		log.Printf("Невалидное JSON-событие статуса, отправка в DLQ: %v", err)
+		c.sendToDLQ(ctx, msg, "json_unmarshal_error", err)
+		metrics.KafkaMessagesProcessed.WithLabelValues("dlq_validation").Inc()
+		return nil // Не ретраим "битый" JSON
+	}
+	if err := event.Validate(); err != nil {
+		log.Printf("Некорректное событие статуса заказа %s, отправка в DLQ: %v", event.OrderUID, err)
+		c.sendToDLQ(ctx, msg, "status_event_invalid", err)
+		metrics.KafkaMessagesProcessed.WithLabelValues("dlq_validation").Inc()
+		return nil
+	}
+
+	applied, err := c.applyStatusEvent(ctx, event)
+	if errors.Is(err, database.ErrStatusEventTargetNotFound) {
+		// Заказ еще не пришел из основного топика: откладываем событие
+		retryAt := time.Now().Add(c.status.retryInterval)
+		err = c.retry.Do(ctx, func() error {
+			return c.storage.ParkStatusEvent(ctx, event, retryAt)
+		})
+		if err == nil {
+			log.Printf("Заказ %s еще не сохранен, событие статуса %q отложено.", event.OrderUID, event.NewStatus)
+			metrics.KafkaMessagesProcessed.WithLabelValues("status_parked").Inc()
+			return nil
+		}
+	}
+
+	return c.completeStatusEvent(ctx, msg, event, applied, err)
+}
+
+// applyStatusEvent применяет событие с повторами при ошибках БД. Ошибки
+// ErrStatusEventTargetNotFound и model.ErrIllegalTransition возвращаются сразу.
+func (c *Consumer) applyStatusEvent(ctx context.Context, event model.StatusEvent) (bool, error) {
+	var (
+		applied bool
+		outcome error
+	)
+	err := c.retry.Do(ctx, func() error {
+		var err error
+		applied, err = c.storage.ApplyStatusEvent(ctx, event)
+		if errors.Is(err, database.ErrStatusEventTargetNotFound) || errors.Is(err, model.ErrIllegalTransition) {
+			outcome = err
+			return nil // Повтор не поможет
+		}
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	return applied, outcome
+}
+
+// completeStatusEvent обрабатывает результат применения события: удаляет
+// измененный заказ из кэша или отправляет сообщение в DLQ.
+func (c *Consumer) completeStatusEvent(ctx context.Context, msg kafka.Message, event model.StatusEvent, applied bool, err error) error {
+	switch {
+	case err != nil && ctx.Err() != nil:
+		// Остановка сервиса: не коммитим, событие будет доставлено повторно
+		return fmt.Errorf("применение события статуса заказа %s прервано: %w", event.OrderUID, ctx.Err())
+
+	case errors.Is(err, model.ErrIllegalTransition):
+		log.Printf("Событие статуса заказа %s противоречит жизненному циклу, отправка в DLQ: %v", event.OrderUID, err)
+		c.sendToDLQ(ctx, msg, "status_illegal_transition", err)
+		metrics.KafkaMessagesProcessed.WithLabelValues("dlq_status").Inc()
+		return nil
+
+	case err != nil:
+		log.Printf("Не удалось применить событие статуса заказа %s, отправка в DLQ: %v", event.OrderUID, err)
+		c.sendToDLQ(ctx, msg, "status_db_error", err)
+		metrics.KafkaMessagesProcessed.WithLabelValues("dlq_db_error").Inc()
+		return nil
+
+	case !applied:
+		// Статус уже менялся позже: событие пришло не по порядку или повторно
+		log.Printf("Событие статуса %q заказа %s устарело (%s), пропускаем.", event.NewStatus, event.OrderUID, event.OccurredAt.Format(time.RFC3339))
+		metrics.KafkaMessagesProcessed.WithLabelValues("status_stale").Inc()
+		return nil
+	}
+
+	// Закэшированная копия заказа содержит прежний статус. Остальные экземпляры
+	// узнают об изменении через уведомление БД.
+	c.loader.Invalidate(ctx, event.OrderUID)
+	log.Printf("Событие статуса %q заказа %s применено.", event.NewStatus, event.OrderUID)
+	metrics.KafkaMessagesProcessed.WithLabelValues("status_applied").Inc()
+	return nil
+}
+
+// runParkedRetrier периодически применяет отложенные события статуса.
+func (c *Consumer) runParkedRetrier(ctx context.Context) {
+	ticker := time.NewTicker(c.status.retryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.retryParkedEvents(ctx)
+		}
+	}
+}
+
+// retryParkedEvents выбирает отложенные события, срок повтора которых наступил,
+// и применяет их в порядке occurred_at. Примененное или устаревшее событие
+// удаляется; событие, заказ которого так и не появился за maxAttempts попыток,
+// отправляется в DLQ. Событие, применить которое помешала ошибка БД, остается
+// отложенным до следующей попытки.
+func (c *Consumer) retryParkedEvents(ctx context.Context) {
+	ctx, span := c.tracer.Start(ctx, "Consumer.retryParkedEvents")
+	defer span.End()
+
+	parked, err := c.storage.ClaimParkedStatusEvents(ctx, parkedClaimLimit, c.status.retryInterval)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Ошибка выборки отложенных событий статуса: %v", err)
+		}
+		return
+	}
+
+	for _, p := range parked {
+		applied, err := c.storage.ApplyStatusEvent(ctx, p.Event)
+		switch {
+		case ctx.Err() != nil:
+			return // Событие будет выбрано снова после перезапуска
+		case errors.Is(err, database.ErrStatusEventTargetNotFound) && p.Attempts < c.status.maxAttempts:
+			continue
+		case errors.Is(err, database.ErrStatusEventTargetNotFound):
+			log.Printf("Заказ %s не появился за %d попыток, событие статуса отправлено в DLQ.", p.Event.OrderUID, p.Attempts)
+			c.sendToDLQ(ctx, parkedMessage(c.status.topic, p.Event), "status_target_not_found", err)
+			metrics.KafkaMessagesProcessed.WithLabelValues("dlq_status").Inc()
+		case errors.Is(err, model.ErrIllegalTransition):
+			log.Printf("Отложенное событие статуса заказа %s противоречит жизненному циклу, отправка в DLQ: %v", p.Event.OrderUID, err)
+			c.sendToDLQ(ctx, parkedMessage(c.status.topic, p.Event), "status_illegal_transition", err)
+			metrics.KafkaMessagesProcessed.WithLabelValues("dlq_status").Inc()
+		case database.IsPermanent(err):
+			log.Printf("Постоянная ошибка применения отложенного события статуса заказа %s, отправка в DLQ: %v", p.Event.OrderUID, err)
+			c.sendToDLQ(ctx, parkedMessage(c.status.topic, p.Event), "status_db_error", err)
+			metrics.KafkaMessagesProcessed.WithLabelValues("dlq_db_error").Inc()
+		case err != nil:
+			log.Printf("Ошибка применения отложенного события статуса заказа %s: %v", p.Event.OrderUID, err)
+			continue
+		case applied:
+			c.loader.Invalidate(ctx, p.Event.OrderUID)
+			log.Printf("Отложенное событие статуса %q заказа %s применено.", p.Event.NewStatus, p.Event.OrderUID)
+			metrics.KafkaMessagesProcessed.WithLabelValues("status_unparked").Inc()
+		default:
+			metrics.KafkaMessagesProcessed.WithLabelValues("status_stale").Inc()
+		}
+
+		if err := c.storage.DeleteParkedStatusEvent(ctx, p.ID); err != nil {
+			// Событие будет выбрано снова; повторное применение не изменит статус
+			log.Printf("Ошибка удаления отложенного события статуса %d: %v", p.ID, err)
+		}
+	}
+}
+
+// parkedMessage восстанавливает Kafka-сообщение отложенного события для DLQ.
+func parkedMessage(topic string, event model.StatusEvent) kafka.Message {
+	value, _ := json.Marshal(event) // StatusEvent всегда сериализуется
+	return kafka.Message{Topic: topic, Key: []byte(event.OrderUID), Value: value}
+}
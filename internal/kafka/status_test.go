@@ -0,0 +1,156 @@
+package kafka
+
+import (
+	"L0_project/internal/database"
+	"L0_project/internal/model"
+	"L0_project/internal/retry"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+// statusMessage - сообщение с событием статуса заказа helperTestOrder
+func statusMessage(status string) kafka.Message {
+	value := fmt.Sprintf(`{"order_uid":%q,"new_status":%q,"occurred_at":"2024-01-01T12:00:00Z"}`, helperTestOrder.OrderUID, status)
+	return kafka.Message{Key: []byte(helperTestOrder.OrderUID), Value: []byte(value)}
+}
+
+func TestConsumer_ProcessStatusMessage_Applied(t *testing.T) {
+	ctrl, consumer, mockCache, mockStorage := setupConsumerAndMocks(t)
+	defer ctrl.Finish()
+
+	mockStorage.EXPECT().ApplyStatusEvent(gomock.Any(), model.StatusEvent{
+		OrderUID:   helperTestOrder.OrderUID,
+		NewStatus:  "paid",
+		OccurredAt: parseTime("2024-01-01T12:00:00Z"),
+	}).Return(true, nil)
+	// Закэшированная копия с прежним статусом удаляется
+	mockCache.EXPECT().Delete(gomock.Any(), helperTestOrder.OrderUID).Return(true)
+
+	assert.NoError(t, consumer.processStatusMessage(context.Background(), statusMessage("paid")))
+}
+
+func TestConsumer_ProcessStatusMessage_StaleKeepsCache(t *testing.T) {
+	ctrl, consumer, mockCache, mockStorage := setupConsumerAndMocks(t)
+	defer ctrl.Finish()
+
+	// Статус уже менялся позже события
+	mockStorage.EXPECT().ApplyStatusEvent(gomock.Any(), gomock.Any()).Return(false, nil)
+	mockCache.EXPECT().Delete(gomock.Any(), gomock.Any()).Times(0)
+
+	assert.NoError(t, consumer.processStatusMessage(context.Background(), statusMessage("paid")))
+}
+
+func TestConsumer_ProcessStatusMessage_UnknownOrderParked(t *testing.T) {
+	ctrl, consumer, mockCache, mockStorage := setupConsumerAndMocks(t)
+	defer ctrl.Finish()
+	consumer.status.retryInterval = time.Minute
+
+	notFound := fmt.Errorf("%w: заказ %s", database.ErrStatusEventTargetNotFound, helperTestOrder.OrderUID)
+	mockStorage.EXPECT().ApplyStatusEvent(gomock.Any(), gomock.Any()).Return(false, notFound)
+	mockStorage.EXPECT().ParkStatusEvent(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, event model.StatusEvent, retryAt time.Time) error {
+			assert.Equal(t, model.StatusValue("paid"), event.NewStatus)
+			assert.WithinDuration(t, time.Now().Add(time.Minute), retryAt, 5*time.Second)
+			return nil
+		})
+	mockCache.EXPECT().Delete(gomock.Any(), gomock.Any()).Times(0)
+
+	assert.NoError(t, consumer.processStatusMessage(context.Background(), statusMessage("paid")))
+}
+
+func TestConsumer_ProcessStatusMessage_NotRetried(t *testing.T) {
+	testCases := []struct {
+		name  string
+		msg   kafka.Message
+		calls int
+		err   error
+	}{
+		{name: "битый JSON", msg: kafka.Message{Value: []byte("not json")}},
+		{name: "неизвестный статус", msg: statusMessage("lost")},
+		{name: "запрещенный переход", msg: statusMessage("created"), calls: 1, err: fmt.Errorf("%w: paid -> created", model.ErrIllegalTransition)},
+		{name: "постоянная ошибка БД", msg: statusMessage("paid"), calls: 1, err: &pq.Error{Code: "23514"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl, consumer, mockCache, mockStorage := setupConsumerAndMocks(t)
+			defer ctrl.Finish()
+
+			// Сообщение уходит в DLQ после первой же попытки
+			mockStorage.EXPECT().ApplyStatusEvent(gomock.Any(), gomock.Any()).Return(false, tc.err).Times(tc.calls)
+			mockCache.EXPECT().Delete(gomock.Any(), gomock.Any()).Times(0)
+
+			assert.NoError(t, consumer.processStatusMessage(context.Background(), tc.msg))
+		})
+	}
+}
+
+func TestConsumer_ProcessStatusMessage_TransientErrorRetried(t *testing.T) {
+	ctrl, consumer, mockCache, mockStorage := setupConsumerAndMocks(t)
+	defer ctrl.Finish()
+
+	transientErr := &pq.Error{Code: "40P01"} // deadlock_detected
+	gomock.InOrder(
+		mockStorage.EXPECT().ApplyStatusEvent(gomock.Any(), gomock.Any()).Return(false, transientErr).Times(consumer.retry.MaxRetries+1),
+		mockStorage.EXPECT().ApplyStatusEvent(gomock.Any(), gomock.Any()).Return(true, nil),
+	)
+	mockCache.EXPECT().Delete(gomock.Any(), helperTestOrder.OrderUID).Return(true)
+
+	assert.NoError(t, consumer.processStatusMessage(context.Background(), statusMessage("paid")))
+}
+
+func TestConsumer_ProcessStatusMessage_InterruptedNotCommitted(t *testing.T) {
+	ctrl, consumer, _, mockStorage := setupConsumerAndMocks(t)
+	defer ctrl.Finish()
+	consumer.retry = retry.Policy{MaxRetries: 3, BaseDelay: time.Hour, MaxDelay: time.Hour, IsTransient: database.IsTransient, IsPermanent: database.IsPermanent}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	mockStorage.EXPECT().ApplyStatusEvent(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(context.Context, model.StatusEvent) (bool, error) {
+			cancel()
+			return false, &pq.Error{Code: "57P01"} // admin_shutdown
+		})
+
+	assert.ErrorIs(t, consumer.processStatusMessage(ctx, statusMessage("paid")), context.Canceled)
+}
+
+func TestConsumer_RetryParkedEvents(t *testing.T) {
+	ctrl, consumer, mockCache, mockStorage := setupConsumerAndMocks(t)
+	defer ctrl.Finish()
+	consumer.status = statusOptions{topic: "order_status_events", retryInterval: time.Minute, maxAttempts: 3}
+
+	event := func(uid string) model.StatusEvent {
+		return model.StatusEvent{OrderUID: uid, NewStatus: "paid", OccurredAt: parseTime("2024-01-01T12:00:00Z")}
+	}
+	notFound := fmt.Errorf("%w: заказ", database.ErrStatusEventTargetNotFound)
+	parked := []model.ParkedStatusEvent{
+		{ID: 1, Event: event("applied"), Attempts: 1},
+		{ID: 2, Event: event("still-missing"), Attempts: 1},
+		{ID: 3, Event: event("gave-up"), Attempts: 3},
+		{ID: 4, Event: event("stale"), Attempts: 2},
+		{ID: 5, Event: event("db-down"), Attempts: 1},
+	}
+
+	mockStorage.EXPECT().ClaimParkedStatusEvents(gomock.Any(), parkedClaimLimit, time.Minute).Return(parked, nil)
+	mockStorage.EXPECT().ApplyStatusEvent(gomock.Any(), event("applied")).Return(true, nil)
+	mockStorage.EXPECT().ApplyStatusEvent(gomock.Any(), event("still-missing")).Return(false, notFound)
+	mockStorage.EXPECT().ApplyStatusEvent(gomock.Any(), event("gave-up")).Return(false, notFound)
+	mockStorage.EXPECT().ApplyStatusEvent(gomock.Any(), event("stale")).Return(false, nil)
+	mockStorage.EXPECT().ApplyStatusEvent(gomock.Any(), event("db-down")).Return(false, errors.New("connection reset"))
+	mockCache.EXPECT().Delete(gomock.Any(), "applied").Return(true)
+
+	// Остаются отложенными только события, которые еще имеет смысл повторить
+	mockStorage.EXPECT().DeleteParkedStatusEvent(gomock.Any(), int64(1)).Return(nil)
+	mockStorage.EXPECT().DeleteParkedStatusEvent(gomock.Any(), int64(3)).Return(nil)
+	mockStorage.EXPECT().DeleteParkedStatusEvent(gomock.Any(), int64(4)).Return(nil)
+
+	consumer.retryParkedEvents(context.Background())
+}
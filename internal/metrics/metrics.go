@@ -48,7 +48,7 @@ var (
 			Name: "kafka_messages_processed_total",
 			Help: "Количество обработанных сообщений Kafka",
 		},
-		[]string{"status"}, // Метки: "success", "dlq_validation", "dlq_db_error", "dlq_failed_write"
+		[]string{"status"}, // Метки: "success", "duplicate", "dlq_validation", "dlq_conflict", "dlq_db_error", "dlq_failed_write"
 	)
 
 	// DBErrors - Счетчик ошибок базы данных
@@ -57,7 +57,34 @@ var (
 			Name: "db_errors_total",
 			Help: "Количество ошибок при работе с БД",
 		},
-		[]string{"operation"}, // Метки: "save_order", "get_order", "get_all", "get_items"
+		[]string{"operation"}, // Метки: "save_order", "get_order", "stream_recent_orders", "get_items", "transition_status", "get_status_history"
+	)
+
+	// OrderStatusTransitions - Счетчик переходов статусов заказов
+	OrderStatusTransitions = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "order_status_transitions_total",
+			Help: "Количество переходов статусов заказов",
+		},
+		[]string{"from", "to"},
+	)
+
+	// ValidationRuleViolations - Счетчик нарушений бизнес-правил заказов
+	ValidationRuleViolations = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "validation_rule_violations_total",
+			Help: "Количество нарушений бизнес-правил в принятых заказах",
+		},
+		[]string{"code", "severity"}, // Метки: код правила и "reject" / "warn"
+	)
+
+	// ValidationRulesReloads - Счетчик перезагрузок файла правил валидации
+	ValidationRulesReloads = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "validation_rules_reloads_total",
+			Help: "Количество перезагрузок файла правил валидации",
+		},
+		[]string{"status"}, // Метки: "success", "error"
 	)
 
 	// CacheSize - Датчик (Gauge) текущего размера кэша
@@ -68,6 +95,14 @@ var (
 		},
 	)
 
+	// CacheSizeBytes - Датчик (Gauge) оценочной суммарной стоимости записей кэша
+	CacheSizeBytes = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "cache_size_bytes",
+			Help: "Оценочный размер кэша в байтах (при ограничении CACHE_MAX_BYTES)",
+		},
+	)
+
 	// CacheEvictions - Счетчик вытеснений из кэша (LRU)
 	CacheEvictions = promauto.NewCounter(
 		prometheus.CounterOpts{
@@ -75,6 +110,88 @@ var (
 			Help: "Количество вытесненных из кэша элементов",
 		},
 	)
+
+	// CacheExpirations - Счетчик записей кэша, удаленных по истечении TTL
+	CacheExpirations = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "cache_expirations_total",
+			Help: "Количество удаленных из кэша элементов с истекшим сроком жизни",
+		},
+	)
+
+	// CacheNegativeHits - Счетчик запросов, обслуженных отрицательным кэшем (ключ заведомо отсутствует)
+	CacheNegativeHits = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "cache_negative_hits_total",
+			Help: "Количество запросов несуществующих ключей, обслуженных отрицательным кэшем без обращения к источнику",
+		},
+	)
+
+	// CacheRemoteHits - Счетчик промахов локального кэша, найденных в общем кэше (Redis)
+	CacheRemoteHits = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "cache_remote_hits_total",
+			Help: "Количество промахов локального кэша, обслуженных общим кэшем",
+		},
+	)
+
+	// CacheRemoteErrors - Счетчик ошибок общего кэша (Redis)
+	CacheRemoteErrors = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cache_remote_errors_total",
+			Help: "Количество ошибок при работе с общим кэшем",
+		},
+		[]string{"operation"}, // Метки: "get", "set", "delete", "purge", "encode", "decode"
+	)
+
+	// CacheInvalidations - Счетчик полученных уведомлений об изменении заказов (LISTEN/NOTIFY)
+	CacheInvalidations = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cache_invalidations_received_total",
+			Help: "Количество полученных уведомлений об изменении заказов от других экземпляров",
+		},
+		[]string{"status"}, // Метки: "applied", "own", "invalid"
+	)
+
+	// CacheInvalidationReconnects - Счетчик переподключений подписки на уведомления об изменении заказов
+	CacheInvalidationReconnects = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "cache_invalidation_reconnects_total",
+			Help: "Количество переподключений подписки на уведомления об изменении заказов",
+		},
+	)
+
+	// CacheWarmUpDuration - Датчик длительности последнего прогрева кэша
+	CacheWarmUpDuration = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "cache_warmup_duration_seconds",
+			Help: "Длительность последнего прогрева кэша в секундах",
+		},
+	)
+
+	// CacheWarmUpOrders - Датчик количества заказов, загруженных последним прогревом кэша
+	CacheWarmUpOrders = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "cache_warmup_loaded_orders",
+			Help: "Количество заказов, загруженных в кэш последним прогревом",
+		},
+	)
+
+	// CacheStaleHits - Счетчик попаданий в кэш, вернувших значение старше мягкого срока жизни
+	CacheStaleHits = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "cache_stale_hits_total",
+			Help: "Количество попаданий в кэш, отданных как устаревшие с фоновым обновлением",
+		},
+	)
+
+	// CacheCoalescedRequests - Счетчик промахов кэша, присоединившихся к уже идущей загрузке
+	CacheCoalescedRequests = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "cache_coalesced_requests_total",
+			Help: "Количество промахов кэша, обслуженных чужой загрузкой без отдельного запроса к источнику",
+		},
+	)
 )
 
 // Init используется для регистрации метрик.
@@ -11,7 +11,7 @@ type Order struct {
 	Payment  Payment  `json:"payment" db:"payment" validate:""`
 
 	Items             []Item    `json:"items" db:"items" validate:"required,min=1,dive"`
-	Locale            string    `json:"locale" db:"locale" validate:"required,len=2"`
+	Locale            string    `json:"locale" db:"locale" validate:"required,locale"`
 	InternalSignature string    `json:"internal_signature" db:"internal_signature"`
 	CustomerID        string    `json:"customer_id" db:"customer_id" validate:"required"`
 	DeliveryService   string    `json:"delivery_service" db:"delivery_service" validate:"required"`
@@ -19,13 +19,18 @@ type Order struct {
 	SmID              int       `json:"sm_id" db:"sm_id" validate:"gte=0"`
 	DateCreated       time.Time `json:"date_created" db:"date_created" validate:"required"`
 	OofShard          string    `json:"oof_shard" db:"oof_shard"`
+
+	// Статус ведется сервисом (POST /api/order/{uid}/status) и при сохранении
+	// заказа из входящего сообщения не меняется.
+	Status        OrderStatus `json:"status,omitempty" db:"status"`
+	StatusVersion int         `json:"status_version,omitempty" db:"status_version"`
 }
 
 type Delivery struct {
 	ID      int    `json:"-" db:"id"`
 	Name    string `json:"name" db:"name" validate:"required"`
-	Phone   string `json:"phone" db:"phone" validate:"required"`
-	Zip     string `json:"zip" db:"zip" validate:"required"`
+	Phone   string `json:"phone" db:"phone" validate:"required,phone"`
+	Zip     string `json:"zip" db:"zip" validate:"required,zip=Phone"`
 	City    string `json:"city" db:"city" validate:"required"`
 	Address string `json:"address" db:"address" validate:"required"`
 	Region  string `json:"region" db:"region" validate:"required"`
@@ -36,10 +41,10 @@ type Payment struct {
 	ID           int    `json:"-" db:"id"`
 	Transaction  string `json:"transaction" db:"transaction" validate:"required"`
 	RequestID    string `json:"request_id" db:"request_id"`
-	Currency     string `json:"currency" db:"currency" validate:"required"`
+	Currency     string `json:"currency" db:"currency" validate:"required,currency"`
 	Provider     string `json:"provider" db:"provider" validate:"required"`
 	Amount       int    `json:"amount" db:"amount" validate:"gte=0"`
-	PaymentDt    int64  `json:"payment_dt" db:"payment_dt" validate:"required"`
+	PaymentDt    int64  `json:"payment_dt" db:"payment_dt" validate:"required,unix_ts"`
 	Bank         string `json:"bank" db:"bank" validate:"required"`
 	DeliveryCost int    `json:"delivery_cost" db:"delivery_cost" validate:"gte=0"`
 	GoodsTotal   int    `json:"goods_total" db:"goods_total" validate:"gte=0"`
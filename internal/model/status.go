@@ -0,0 +1,118 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// OrderStatus - статус заказа в его жизненном цикле.
+type OrderStatus string
+
+const (
+	StatusCreated    OrderStatus = "created"
+	StatusPaid       OrderStatus = "paid"
+	StatusAssembling OrderStatus = "assembling"
+	StatusShipped    OrderStatus = "shipped"
+	StatusDelivered  OrderStatus = "delivered"
+	StatusCancelled  OrderStatus = "cancelled"
+	StatusReturned   OrderStatus = "returned"
+)
+
+// InitialStatusVersion - версия статуса нового заказа. Каждый переход увеличивает ее на 1.
+const InitialStatusVersion = 1
+
+// orderTransitions - допустимые переходы между статусами. Отменить заказ можно
+// до отгрузки, вернуть - после нее; cancelled и returned - конечные статусы.
+var orderTransitions = map[OrderStatus][]OrderStatus{
+	StatusCreated:    {StatusPaid, StatusCancelled},
+	StatusPaid:       {StatusAssembling, StatusCancelled},
+	StatusAssembling: {StatusShipped, StatusCancelled},
+	StatusShipped:    {StatusDelivered, StatusReturned},
+	StatusDelivered:  {StatusReturned},
+	StatusCancelled:  {},
+	StatusReturned:   {},
+}
+
+var (
+	// ErrUnknownStatus возвращается для значения, не являющегося статусом заказа.
+	ErrUnknownStatus = errors.New("неизвестный статус заказа")
+	// ErrIllegalTransition возвращается для перехода, запрещенного жизненным циклом заказа.
+	ErrIllegalTransition = errors.New("недопустимый переход статуса заказа")
+)
+
+// Valid сообщает, является ли значение известным статусом заказа.
+func (s OrderStatus) Valid() bool {
+	_, ok := orderTransitions[s]
+	return ok
+}
+
+// Final сообщает, что из статуса нет переходов.
+func (s OrderStatus) Final() bool {
+	return s.Valid() && len(orderTransitions[s]) == 0
+}
+
+// CanTransitionTo сообщает, допустим ли переход из s в next.
+func (s OrderStatus) CanTransitionTo(next OrderStatus) bool {
+	for _, allowed := range orderTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// CanReach сообщает, достижим ли статус next из s через один или несколько
+// допустимых переходов.
+func (s OrderStatus) CanReach(next OrderStatus) bool {
+	visited := map[OrderStatus]bool{s: true}
+	queue := []OrderStatus{s}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, allowed := range orderTransitions[current] {
+			if allowed == next {
+				return true
+			}
+			if !visited[allowed] {
+				visited[allowed] = true
+				queue = append(queue, allowed)
+			}
+		}
+	}
+	return false
+}
+
+// ValidateTransition проверяет переход статуса заказа. Для неизвестного статуса
+// возвращает ошибку, оборачивающую ErrUnknownStatus, для запрещенного перехода -
+// ErrIllegalTransition.
+func ValidateTransition(from, to OrderStatus) error {
+	if !to.Valid() {
+		return fmt.Errorf("%w: %q", ErrUnknownStatus, to)
+	}
+	if !from.Valid() {
+		return fmt.Errorf("%w: %q", ErrUnknownStatus, from)
+	}
+	if !from.CanTransitionTo(to) {
+		return fmt.Errorf("%w: %s -> %s", ErrIllegalTransition, from, to)
+	}
+	return nil
+}
+
+// StatusChange - запись истории статусов заказа.
+type StatusChange struct {
+	OrderUID  string      `json:"order_uid" db:"order_uid"`
+	From      OrderStatus `json:"from" db:"from_status"`
+	To        OrderStatus `json:"to" db:"to_status"`
+	Version   int         `json:"version" db:"version"` // Версия статуса после перехода
+	Reason    string      `json:"reason,omitempty" db:"reason"`
+	ChangedAt time.Time   `json:"changed_at" db:"changed_at"`
+}
+
+// StatusHistory - текущий статус заказа и история его переходов.
+type StatusHistory struct {
+	OrderUID string         `json:"order_uid"`
+	Status   OrderStatus    `json:"status"`
+	Version  int            `json:"version"`
+	Changes  []StatusChange `json:"changes"` // От старых к новым
+}
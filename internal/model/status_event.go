@@ -0,0 +1,97 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ErrInvalidStatusEvent возвращается StatusEvent.Validate для некорректного события.
+var ErrInvalidStatusEvent = errors.New("некорректное событие статуса")
+
+// StatusValue - новый статус в событии: название статуса заказа или числовой
+// код статуса товара. В JSON принимается как строка, так и число.
+type StatusValue string
+
+// UnmarshalJSON принимает статус строкой ("paid") или числом (202).
+func (v *StatusValue) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		*v = StatusValue(s)
+		return nil
+	}
+
+	var n json.Number
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&n); err != nil {
+		return fmt.Errorf("статус должен быть строкой или числом: %w", err)
+	}
+	*v = StatusValue(n.String())
+	return nil
+}
+
+// StatusEvent - событие изменения статуса заказа или одного из его товаров.
+// События могут приходить не в том порядке, в котором произошли: порядок
+// определяется по OccurredAt.
+type StatusEvent struct {
+	OrderUID   string      `json:"order_uid"`
+	Rid        string      `json:"rid,omitempty"` // Товар заказа; пусто - событие о статусе заказа
+	NewStatus  StatusValue `json:"new_status"`
+	OccurredAt time.Time   `json:"occurred_at"`
+}
+
+// ItemEvent сообщает, что событие относится к товару, а не к заказу.
+func (e StatusEvent) ItemEvent() bool {
+	return e.Rid != ""
+}
+
+// OrderStatus возвращает новый статус заказа из события о заказе.
+func (e StatusEvent) OrderStatus() OrderStatus {
+	return OrderStatus(e.NewStatus)
+}
+
+// ItemStatus возвращает новый код статуса товара из события о товаре.
+func (e StatusEvent) ItemStatus() (int, error) {
+	status, err := strconv.Atoi(string(e.NewStatus))
+	if err != nil || status < 0 {
+		return 0, fmt.Errorf("%w: статус товара должен быть неотрицательным числом: %q", ErrInvalidStatusEvent, e.NewStatus)
+	}
+	return status, nil
+}
+
+// Validate проверяет обязательные поля события и значение статуса.
+func (e StatusEvent) Validate() error {
+	switch {
+	case e.OrderUID == "":
+		return fmt.Errorf("%w: не указан order_uid", ErrInvalidStatusEvent)
+	case e.OccurredAt.IsZero():
+		return fmt.Errorf("%w: не указан occurred_at", ErrInvalidStatusEvent)
+	case e.NewStatus == "":
+		return fmt.Errorf("%w: не указан new_status", ErrInvalidStatusEvent)
+	}
+
+	if e.ItemEvent() {
+		_, err := e.ItemStatus()
+		return err
+	}
+	if !e.OrderStatus().Valid() {
+		return fmt.Errorf("%w: %w: %q", ErrInvalidStatusEvent, ErrUnknownStatus, e.NewStatus)
+	}
+	return nil
+}
+
+// ParkedStatusEvent - отложенное событие статуса, заказ или товар которого
+// еще не сохранен. Такие события периодически применяются повторно.
+type ParkedStatusEvent struct {
+	ID       int64
+	Event    StatusEvent
+	Attempts int // Сколько раз событие уже пытались применить повторно
+	ParkedAt time.Time
+}
@@ -0,0 +1,49 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatusEvent_Unmarshal(t *testing.T) {
+	var orderEvent StatusEvent
+	require.NoError(t, json.Unmarshal([]byte(`{"order_uid":"uid-1","new_status":"paid","occurred_at":"2024-01-01T12:00:00Z"}`), &orderEvent))
+	assert.False(t, orderEvent.ItemEvent())
+	assert.Equal(t, StatusPaid, orderEvent.OrderStatus())
+	assert.NoError(t, orderEvent.Validate())
+
+	// Статус товара может прийти числом
+	var itemEvent StatusEvent
+	require.NoError(t, json.Unmarshal([]byte(`{"order_uid":"uid-1","rid":"rid-1","new_status":202,"occurred_at":"2024-01-01T12:00:00Z"}`), &itemEvent))
+	assert.True(t, itemEvent.ItemEvent())
+	status, err := itemEvent.ItemStatus()
+	require.NoError(t, err)
+	assert.Equal(t, 202, status)
+	assert.NoError(t, itemEvent.Validate())
+
+	assert.Error(t, json.Unmarshal([]byte(`{"new_status":{}}`), &StatusEvent{}))
+}
+
+func TestStatusEvent_Validate(t *testing.T) {
+	occurredAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name  string
+		event StatusEvent
+	}{
+		{name: "нет order_uid", event: StatusEvent{NewStatus: "paid", OccurredAt: occurredAt}},
+		{name: "нет occurred_at", event: StatusEvent{OrderUID: "uid-1", NewStatus: "paid"}},
+		{name: "нет статуса", event: StatusEvent{OrderUID: "uid-1", OccurredAt: occurredAt}},
+		{name: "неизвестный статус заказа", event: StatusEvent{OrderUID: "uid-1", NewStatus: "lost", OccurredAt: occurredAt}},
+		{name: "нечисловой статус товара", event: StatusEvent{OrderUID: "uid-1", Rid: "rid-1", NewStatus: "paid", OccurredAt: occurredAt}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.ErrorIs(t, tc.event.Validate(), ErrInvalidStatusEvent)
+		})
+	}
+}
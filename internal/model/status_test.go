@@ -0,0 +1,58 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateTransition(t *testing.T) {
+	testCases := []struct {
+		from, to OrderStatus
+		wantErr  error
+	}{
+		{from: StatusCreated, to: StatusPaid},
+		{from: StatusCreated, to: StatusCancelled},
+		{from: StatusPaid, to: StatusAssembling},
+		{from: StatusAssembling, to: StatusShipped},
+		{from: StatusShipped, to: StatusDelivered},
+		{from: StatusShipped, to: StatusReturned},
+		{from: StatusDelivered, to: StatusReturned},
+		{from: StatusCreated, to: StatusShipped, wantErr: ErrIllegalTransition},
+		{from: StatusShipped, to: StatusCancelled, wantErr: ErrIllegalTransition},
+		{from: StatusPaid, to: StatusPaid, wantErr: ErrIllegalTransition},
+		{from: StatusCancelled, to: StatusPaid, wantErr: ErrIllegalTransition},
+		{from: StatusReturned, to: StatusDelivered, wantErr: ErrIllegalTransition},
+		{from: StatusCreated, to: "lost", wantErr: ErrUnknownStatus},
+		{from: "", to: StatusPaid, wantErr: ErrUnknownStatus},
+	}
+
+	for _, tc := range testCases {
+		t.Run(string(tc.from)+"->"+string(tc.to), func(t *testing.T) {
+			err := ValidateTransition(tc.from, tc.to)
+			if tc.wantErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestOrderStatus_Final(t *testing.T) {
+	assert.True(t, StatusCancelled.Final())
+	assert.True(t, StatusReturned.Final())
+	assert.False(t, StatusDelivered.Final())
+	assert.False(t, OrderStatus("lost").Final())
+}
+
+func TestOrderStatus_CanReach(t *testing.T) {
+	assert.True(t, StatusCreated.CanReach(StatusPaid))
+	// Промежуточные события могли еще не прийти
+	assert.True(t, StatusCreated.CanReach(StatusDelivered))
+	assert.True(t, StatusPaid.CanReach(StatusReturned))
+	// Назад по жизненному циклу и из финальных статусов пути нет
+	assert.False(t, StatusShipped.CanReach(StatusPaid))
+	assert.False(t, StatusCancelled.CanReach(StatusPaid))
+	assert.False(t, StatusShipped.CanReach(StatusCancelled))
+}
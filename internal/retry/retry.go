@@ -0,0 +1,105 @@
+// Package retry содержит политику повторов операций с БД с экспоненциальным backoff.
+package retry
+
+import (
+	"context"
+	"log"
+	"math/rand/v2"
+	"time"
+)
+
+// Policy задает повторы операций с БД. Временные ошибки (по IsTransient)
+// повторяются без ограничения, пока не будет отменен ctx; постоянные (по
+// IsPermanent) не повторяются; остальные повторяются, пока общее число попыток
+// не достигнет MaxRetries. Классификацию ошибок задает вызывающий код: nil
+// означает, что ошибок такого класса нет.
+type Policy struct {
+	MaxRetries  int              // Лимит попыток для нераспознанных ошибок
+	BaseDelay   time.Duration    // Начальная задержка экспоненциального backoff
+	MaxDelay    time.Duration    // Верхняя граница задержки
+	IsTransient func(error) bool // Временная ошибка: повтор имеет смысл
+	IsPermanent func(error) bool // Постоянная ошибка: повтор не поможет
+}
+
+// Do выполняет op, повторяя ее по правилам политики. Возвращает nil после
+// первой успешной попытки, последнюю ошибку op, если повтор не поможет или
+// попытки исчерпаны, либо ctx.Err(), если ожидание прервано.
+func (p Policy) Do(ctx context.Context, op func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		kind := p.classify(err)
+		if kind == errorPermanent || (kind == errorUnknown && attempt >= p.MaxRetries) {
+			return err
+		}
+
+		delay := Delay(attempt, p.BaseDelay, p.MaxDelay)
+		log.Printf("Ошибка БД (%s, попытка %d, повтор через %v): %v", kind, attempt, delay, err)
+		if err := Sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// errorKind - класс ошибки с точки зрения повторных попыток (для лога).
+type errorKind string
+
+const (
+	errorUnknown   errorKind = "unknown"
+	errorTransient errorKind = "transient"
+	errorPermanent errorKind = "permanent"
+)
+
+// classify определяет класс ошибки по проверкам политики.
+func (p Policy) classify(err error) errorKind {
+	switch {
+	case p.IsPermanent != nil && p.IsPermanent(err):
+		return errorPermanent
+	case p.IsTransient != nil && p.IsTransient(err):
+		return errorTransient
+	default:
+		return errorUnknown
+	}
+}
+
+// Delay вычисляет задержку перед повторной попыткой с номером attempt (с 1).
+// Задержка растет экспоненциально от base до max, половина ее случайна (jitter),
+// чтобы несколько консюмеров не повторяли запросы к БД синхронно.
+func Delay(attempt int, base, max time.Duration) time.Duration {
+	if base <= 0 {
+		return 0
+	}
+	if max < base {
+		max = base
+	}
+
+	delay := base
+	for i := 1; i < attempt && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+
+	half := delay / 2
+	return half + rand.N(half+1)
+}
+
+// Sleep ждет d или отмены ctx. Возвращает ctx.Err(), если ожидание прервано.
+func Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
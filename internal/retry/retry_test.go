@@ -0,0 +1,99 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDelay_Bounds(t *testing.T) {
+	base, max := 100*time.Millisecond, time.Second
+
+	for attempt := 1; attempt <= 10; attempt++ {
+		expected := base << (attempt - 1)
+		if expected > max {
+			expected = max
+		}
+		delay := Delay(attempt, base, max)
+		assert.GreaterOrEqual(t, delay, expected/2, "attempt %d", attempt)
+		assert.LessOrEqual(t, delay, expected, "attempt %d", attempt)
+	}
+}
+
+var (
+	errTransient = errors.New("временная ошибка")
+	errPermanent = errors.New("постоянная ошибка")
+)
+
+func TestPolicy_Do(t *testing.T) {
+	policy := Policy{
+		MaxRetries:  3,
+		BaseDelay:   time.Millisecond,
+		MaxDelay:    5 * time.Millisecond,
+		IsTransient: func(err error) bool { return errors.Is(err, errTransient) },
+		IsPermanent: func(err error) bool { return errors.Is(err, errPermanent) },
+	}
+	testCases := []struct {
+		name      string
+		err       error
+		failures  int // Сколько попыток завершаются ошибкой err
+		wantCalls int
+		wantErr   bool
+	}{
+		{name: "успех с первой попытки", wantCalls: 1},
+		{name: "временная ошибка повторяется сверх лимита", err: errTransient, failures: 5, wantCalls: 6},
+		{name: "нераспознанная ошибка ограничена лимитом", err: errors.New("boom"), failures: 10, wantCalls: 3, wantErr: true},
+		{name: "постоянная ошибка не повторяется", err: errPermanent, failures: 10, wantCalls: 1, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			calls := 0
+			err := policy.Do(context.Background(), func() error {
+				calls++
+				if calls <= tc.failures {
+					return tc.err
+				}
+				return nil
+			})
+			assert.Equal(t, tc.wantCalls, calls)
+			if tc.wantErr {
+				assert.ErrorIs(t, err, tc.err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestPolicy_Do_WithoutClassifiers(t *testing.T) {
+	// Без проверок все ошибки нераспознанные и ограничены лимитом
+	policy := Policy{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+
+	calls := 0
+	err := policy.Do(context.Background(), func() error {
+		calls++
+		return errTransient
+	})
+	assert.ErrorIs(t, err, errTransient)
+	assert.Equal(t, 2, calls)
+}
+
+func TestPolicy_Do_InterruptedByContext(t *testing.T) {
+	policy := Policy{
+		MaxRetries:  3,
+		BaseDelay:   time.Hour,
+		MaxDelay:    time.Hour,
+		IsTransient: func(error) bool { return true },
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	start := time.Now()
+	err := policy.Do(ctx, func() error { return errTransient })
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, time.Since(start), time.Second)
+}
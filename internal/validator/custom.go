@@ -0,0 +1,139 @@
+package validator
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Теги собственных правил валидации.
+const (
+	TagCurrency = "currency" // Код валюты ISO 4217 (USD)
+	TagLocale   = "locale"   // Код языка ISO 639-1 (en)
+	TagPhone    = "phone"    // Номер телефона в формате E.164 (+79991234567)
+	TagZip      = "zip"      // Почтовый индекс в формате страны; параметр - поле с телефоном
+	TagUnixTime = "unix_ts"  // Время UNIX в секундах: не раньше 2000 года и не в будущем
+)
+
+// Границы правдоподобного времени UNIX.
+var (
+	minUnixTime = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC).Unix()
+	// maxClockSkew - допустимое опережение часов отправителя
+	maxClockSkew = 5 * time.Minute
+)
+
+// currencyCodes - действующие коды валют ISO 4217.
+var currencyCodes = setOf(`
+	AED AFN ALL AMD ANG AOA ARS AUD AWG AZN BAM BBD BDT BGN BHD BIF BMD BND BOB BOV
+	BRL BSD BTN BWP BYN BZD CAD CDF CHE CHF CHW CLF CLP CNY COP COU CRC CUC CUP CVE
+	CZK DJF DKK DOP DZD EGP ERN ETB EUR FJD FKP GBP GEL GHS GIP GMD GNF GTQ GYD HKD
+	HNL HTG HUF IDR ILS INR IQD IRR ISK JMD JOD JPY KES KGS KHR KMF KPW KRW KWD KYD
+	KZT LAK LBP LKR LRD LSL LYD MAD MDL MGA MKD MMK MNT MOP MRU MUR MVR MWK MXN MXV
+	MYR MZN NAD NGN NIO NOK NPR NZD OMR PAB PEN PGK PHP PKR PLN PYG QAR RON RSD RUB
+	RWF SAR SBD SCR SDG SEK SGD SHP SLE SLL SOS SRD SSP STN SVC SYP SZL THB TJS TMT
+	TND TOP TRY TTD TWD TZS UAH UGX USD USN UYI UYU UYW UZS VED VES VND VUV WST XAF
+	XAG XAU XBA XBB XBC XBD XCD XCG XDR XOF XPD XPF XPT XSU XTS XUA XXX YER ZAR ZMW
+	ZWG ZWL`)
+
+// languageCodes - двухбуквенные коды языков ISO 639-1.
+var languageCodes = setOf(`
+	aa ab ae af ak am an ar as av ay az ba be bg bi bm bn bo br bs ca ce ch co cr cs
+	cu cv cy da de dv dz ee el en eo es et eu fa ff fi fj fo fr fy ga gd gl gn gu gv
+	ha he hi ho hr ht hu hy hz ia id ie ig ii ik io is it iu ja jv ka kg ki kj kk kl
+	km kn ko kr ks ku kv kw ky la lb lg li ln lo lt lu lv mg mh mi mk ml mn mr ms mt
+	my na nb nd ne ng nl nn no nr nv ny oc oj om or os pa pi pl ps pt qu rm rn ro ru
+	rw sa sc sd se sg si sk sl sm sn so sq sr ss st su sv sw ta te tg th ti tk tl tn
+	to tr ts tt tw ty ug uk ur uz ve vi vo wa wo xh yi yo za zh zu`)
+
+// e164 - номер E.164: "+", код страны и не больше 15 цифр всего.
+var e164 = regexp.MustCompile(`^\+[1-9]\d{1,14}$`)
+
+// zipFormats - форматы почтовых индексов по телефонному коду страны.
+// Страна определяется по номеру телефона доставки: отдельного поля страны
+// в заказе нет.
+var zipFormats = map[string]*regexp.Regexp{
+	"1":   regexp.MustCompile(`^(\d{5}(-\d{4})?|[A-Za-z]\d[A-Za-z] ?\d[A-Za-z]\d)$`), // США и Канада
+	"7":   regexp.MustCompile(`^\d{6}$`),                                             // Россия и Казахстан
+	"33":  regexp.MustCompile(`^\d{5}$`),                                             // Франция
+	"34":  regexp.MustCompile(`^\d{5}$`),                                             // Испания
+	"39":  regexp.MustCompile(`^\d{5}$`),                                             // Италия
+	"44":  regexp.MustCompile(`^[A-Za-z]{1,2}\d[A-Za-z\d]? ?\d[A-Za-z]{2}$`),         // Великобритания
+	"48":  regexp.MustCompile(`^\d{2}-\d{3}$`),                                       // Польша
+	"49":  regexp.MustCompile(`^\d{5}$`),                                             // Германия
+	"81":  regexp.MustCompile(`^\d{3}-?\d{4}$`),                                      // Япония
+	"86":  regexp.MustCompile(`^\d{6}$`),                                             // Китай
+	"90":  regexp.MustCompile(`^\d{5}$`),                                             // Турция
+	"91":  regexp.MustCompile(`^\d{6}$`),                                             // Индия
+	"374": regexp.MustCompile(`^\d{4}$`),                                             // Армения
+	"375": regexp.MustCompile(`^\d{6}$`),                                             // Беларусь
+	"380": regexp.MustCompile(`^\d{5}$`),                                             // Украина
+	"972": regexp.MustCompile(`^\d{5}(\d{2})?$`),                                     // Израиль
+	"995": regexp.MustCompile(`^\d{4}$`),                                             // Грузия
+	"996": regexp.MustCompile(`^\d{6}$`),                                             // Киргизия
+	"998": regexp.MustCompile(`^\d{6}$`),                                             // Узбекистан
+}
+
+// genericZip - индекс страны без известного формата (или без телефона).
+var genericZip = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9 -]{1,9}$`)
+
+// customValidations - собственные правила валидации по тегам.
+var customValidations = map[string]validator.Func{
+	TagCurrency: func(fl validator.FieldLevel) bool { return currencyCodes[fl.Field().String()] },
+	TagLocale:   func(fl validator.FieldLevel) bool { return languageCodes[fl.Field().String()] },
+	TagPhone:    func(fl validator.FieldLevel) bool { return e164.MatchString(fl.Field().String()) },
+	TagZip:      validateZip,
+	TagUnixTime: func(fl validator.FieldLevel) bool { return ValidUnixTime(fl.Field().Int(), time.Now()) },
+}
+
+// registerCustom регистрирует собственные правила валидации.
+func registerCustom(v *validator.Validate) {
+	for tag, fn := range customValidations {
+		if err := v.RegisterValidation(tag, fn); err != nil {
+			panic(fmt.Sprintf("не удалось зарегистрировать правило %s: %v", tag, err))
+		}
+	}
+}
+
+// validateZip проверяет индекс по формату страны из номера телефона в поле,
+// указанном параметром тега (zip=Phone).
+func validateZip(fl validator.FieldLevel) bool {
+	phone := fl.Parent().FieldByName(fl.Param())
+	if phone.Kind() != reflect.String {
+		return genericZip.MatchString(fl.Field().String())
+	}
+	return ValidZip(fl.Field().String(), phone.String())
+}
+
+// ValidZip проверяет почтовый индекс по формату страны номера телефона
+// (самый длинный подходящий код страны). Если страна неизвестна, проверяется
+// только общий вид индекса.
+func ValidZip(zip, phone string) bool {
+	if e164.MatchString(phone) {
+		digits := phone[1:]
+		for n := min(3, len(digits)); n >= 1; n-- {
+			if format, ok := zipFormats[digits[:n]]; ok {
+				return format.MatchString(zip)
+			}
+		}
+	}
+	return genericZip.MatchString(zip)
+}
+
+// ValidUnixTime проверяет, что время UNIX (в секундах) не раньше 2000 года
+// и не позже now (с допуском на расхождение часов).
+func ValidUnixTime(ts int64, now time.Time) bool {
+	return ts >= minUnixTime && ts <= now.Add(maxClockSkew).Unix()
+}
+
+// setOf разбирает список слов, разделенных пробелами, в множество.
+func setOf(words string) map[string]bool {
+	set := make(map[string]bool)
+	for _, word := range strings.Fields(words) {
+		set[word] = true
+	}
+	return set
+}
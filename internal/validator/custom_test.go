@@ -0,0 +1,117 @@
+package validator
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testContact struct {
+	Currency  string `json:"currency" validate:"currency"`
+	Locale    string `json:"locale" validate:"locale"`
+	Phone     string `json:"phone" validate:"phone"`
+	Zip       string `json:"zip" validate:"zip=Phone"`
+	PaymentDt int64  `json:"payment_dt" validate:"unix_ts"`
+}
+
+// validContact возвращает структуру, проходящую все собственные правила
+func validContact() testContact {
+	return testContact{Currency: "USD", Locale: "en", Phone: "+79991234567", Zip: "101000", PaymentDt: 1637907727}
+}
+
+func TestCustomValidators(t *testing.T) {
+	testCases := []struct {
+		name     string
+		modify   func(c *testContact)
+		wantPath string // Пусто, если структура валидна
+		wantRule string
+	}{
+		{name: "валидные значения", modify: func(*testContact) {}},
+		{name: "валюта ISO 4217", modify: func(c *testContact) { c.Currency = "EUR" }},
+		{name: "неизвестная валюта", modify: func(c *testContact) { c.Currency = "ABC" }, wantPath: "currency", wantRule: TagCurrency},
+		{name: "выведенная из обращения валюта", modify: func(c *testContact) { c.Currency = "BYR" }, wantPath: "currency", wantRule: TagCurrency},
+		{name: "валюта в нижнем регистре", modify: func(c *testContact) { c.Currency = "usd" }, wantPath: "currency", wantRule: TagCurrency},
+		{name: "язык ISO 639-1", modify: func(c *testContact) { c.Locale = "ru" }},
+		{name: "неизвестный язык", modify: func(c *testContact) { c.Locale = "xx" }, wantPath: "locale", wantRule: TagLocale},
+		{name: "код языка ISO 639-2", modify: func(c *testContact) { c.Locale = "eng" }, wantPath: "locale", wantRule: TagLocale},
+		{name: "телефон без плюса", modify: func(c *testContact) { c.Phone = "79991234567"; c.Zip = "AB-12" }, wantPath: "phone", wantRule: TagPhone},
+		{name: "телефон с пробелами", modify: func(c *testContact) { c.Phone = "+7 999 123 45 67" }, wantPath: "phone", wantRule: TagPhone},
+		{name: "телефон длиннее 15 цифр", modify: func(c *testContact) { c.Phone = "+7999123456789012" }, wantPath: "phone", wantRule: TagPhone},
+		{name: "телефон с кодом 0", modify: func(c *testContact) { c.Phone = "+0999123456" }, wantPath: "phone", wantRule: TagPhone},
+		{name: "индекс не в формате страны", modify: func(c *testContact) { c.Zip = "10100" }, wantPath: "zip", wantRule: TagZip},
+		{name: "время до 2000 года", modify: func(c *testContact) { c.PaymentDt = 946684799 }, wantPath: "payment_dt", wantRule: TagUnixTime},
+		{name: "время в будущем", modify: func(c *testContact) { c.PaymentDt = time.Now().Add(time.Hour).Unix() }, wantPath: "payment_dt", wantRule: TagUnixTime},
+		{name: "время в миллисекундах", modify: func(c *testContact) { c.PaymentDt = 1637907727000 }, wantPath: "payment_dt", wantRule: TagUnixTime},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			contact := validContact()
+			tc.modify(&contact)
+
+			err := ValidateStruct(&contact)
+			if tc.wantPath == "" {
+				assert.NoError(t, err)
+				return
+			}
+			var validationErr *ValidationError
+			require.True(t, errors.As(err, &validationErr), "ожидалась ошибка валидации, получено %v", err)
+			require.Len(t, validationErr.Fields, 1)
+			assert.Equal(t, tc.wantPath, validationErr.Fields[0].Path)
+			assert.Equal(t, tc.wantRule, validationErr.Fields[0].Rule)
+			assert.NotContains(t, validationErr.Fields[0].Message, "не прошло проверку", "у правила должно быть свое сообщение")
+		})
+	}
+}
+
+func TestValidZip(t *testing.T) {
+	testCases := []struct {
+		zip, phone string
+		want       bool
+	}{
+		{zip: "101000", phone: "+79991234567", want: true}, // Россия
+		{zip: "1010", phone: "+79991234567", want: false},  // Россия: 6 цифр
+		{zip: "10001", phone: "+12125551234", want: true},  // США
+		{zip: "10001-1234", phone: "+12125551234", want: true},
+		{zip: "K1A 0B1", phone: "+16135550123", want: true},   // Канада
+		{zip: "SW1A 1AA", phone: "+442071234567", want: true}, // Великобритания
+		{zip: "12345", phone: "+442071234567", want: false},
+		{zip: "2639809", phone: "+9720000000", want: true},  // Израиль, 7 цифр
+		{zip: "0105", phone: "+995322123456", want: true},   // Грузия: код 995, а не 99
+		{zip: "00-950", phone: "+48221234567", want: true},  // Польша
+		{zip: "1234 AB", phone: "+31201234567", want: true}, // Нидерланды: формат неизвестен, общий вид индекса
+		{zip: "!!", phone: "+31201234567", want: false},
+		{zip: "101000", phone: "не телефон", want: true}, // Страна неизвестна
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.zip+" "+tc.phone, func(t *testing.T) {
+			assert.Equal(t, tc.want, ValidZip(tc.zip, tc.phone))
+		})
+	}
+}
+
+func TestValidUnixTime(t *testing.T) {
+	now := time.Date(2024, time.June, 1, 12, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name string
+		ts   int64
+		want bool
+	}{
+		{name: "начало 2000 года", ts: 946684800, want: true},
+		{name: "конец 1999 года", ts: 946684799, want: false},
+		{name: "текущее время", ts: now.Unix(), want: true},
+		{name: "расхождение часов", ts: now.Add(maxClockSkew).Unix(), want: true},
+		{name: "будущее", ts: now.Add(maxClockSkew + time.Second).Unix(), want: false},
+		{name: "ноль", ts: 0, want: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, ValidUnixTime(tc.ts, now))
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package validator
+
+import (
+	_ "embed"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// ErrorSchema - JSON Schema списка ошибок валидации (тело ошибки API и
+// заголовок X-Error-Details сообщений DLQ).
+//
+//go:embed validation_errors.schema.json
+var ErrorSchema []byte
+
+// FieldError - ошибка валидации одного поля.
+type FieldError struct {
+	Path    string `json:"path"`            // JSON-путь к полю, например items[2].price
+	Rule    string `json:"rule"`            // Нарушенное правило (тег валидации): required, gt, uuid4...
+	Param   string `json:"param,omitempty"` // Параметр правила: 0 для gt=0
+	Value   any    `json:"value"`           // Фактическое значение (null для составных полей)
+	Message string `json:"message"`         // Сообщение на выбранном языке
+
+	kind reflect.Kind // Тип поля: от него зависит текст сообщения min, max, len
+}
+
+// ValidationError - ошибки валидации всех полей структуры.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+// Error перечисляет ошибки полей в виде "путь: сообщение".
+func (e *ValidationError) Error() string {
+	parts := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		parts[i] = field.Path + ": " + field.Message
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Localize возвращает копию ошибок с сообщениями на языке lang.
+func (e *ValidationError) Localize(lang Lang) *ValidationError {
+	fields := make([]FieldError, len(e.Fields))
+	for i, field := range e.Fields {
+		field.Message = message(lang, field.Rule, field.Param, field.kind)
+		fields[i] = field
+	}
+	return &ValidationError{Fields: fields}
+}
+
+// newValidationError переводит ошибки go-playground/validator в список FieldError.
+func newValidationError(fieldErrs validator.ValidationErrors, lang Lang) *ValidationError {
+	fields := make([]FieldError, len(fieldErrs))
+	for i, fieldErr := range fieldErrs {
+		fields[i] = newFieldError(fieldPath(fieldErr.Namespace()), fieldErr, lang)
+	}
+	return &ValidationError{Fields: fields}
+}
+
+// newFieldError описывает ошибку поля по пути path.
+func newFieldError(path string, fieldErr validator.FieldError, lang Lang) FieldError {
+	return FieldError{
+		Path:    path,
+		Rule:    fieldErr.Tag(),
+		Param:   fieldErr.Param(),
+		Value:   scalarValue(fieldErr.Value()),
+		Message: message(lang, fieldErr.Tag(), fieldErr.Param(), fieldErr.Kind()),
+		kind:    fieldErr.Kind(),
+	}
+}
+
+// fieldPath убирает имя корневой структуры: Order.items[2].price -> items[2].price.
+func fieldPath(namespace string) string {
+	if _, path, found := strings.Cut(namespace, "."); found {
+		return path
+	}
+	return namespace
+}
+
+// scalarValue возвращает значение простого поля; вложенные структуры и списки
+// в ошибку не копируются.
+func scalarValue(value any) any {
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return value
+	case reflect.Struct:
+		if stringer, ok := value.(fmt.Stringer); ok {
+			return stringer.String() // Например, time.Time
+		}
+	}
+	return nil
+}
@@ -0,0 +1,338 @@
+package validator
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"gopkg.in/yaml.v3"
+)
+
+// FieldRule - правила поля из файла правил в синтаксисе тегов validate.
+type FieldRule struct {
+	Rules    string `yaml:"rules"`    // Например, "oneof=alpha sber" или "gt=0,lte=1000000"
+	Override bool   `yaml:"override"` // true - заменить правила тега, false - дополнить их
+}
+
+// rulesFile - содержимое файла правил (YAML или JSON):
+//
+//	fields:
+//	  payment.bank:
+//	    rules: oneof=alpha sber tinkoff vtb
+//	  items[].price:
+//	    rules: gt=0,lte=1000000
+//	    override: true
+type rulesFile struct {
+	Fields map[string]FieldRule `yaml:"fields"` // JSON-путь поля; элементы списка - через []
+}
+
+// pathStep - шаг пути к полю: поле структуры и признак обхода элементов списка.
+type pathStep struct {
+	name  string
+	index int  // Номер поля в структуре
+	each  bool // Поле - список, правило применяется к каждому элементу
+}
+
+// fileRule - проверенное правило из файла.
+type fileRule struct {
+	path  string
+	rule  FieldRule
+	steps []pathStep
+}
+
+// fileRuleSet - правила из файла для структур типа root.
+type fileRuleSet struct {
+	root     reflect.Type
+	rules    []fileRule // По возрастанию пути
+	source   string
+	loadedAt time.Time
+}
+
+// fileRules - действующие правила из файла (nil - файл не загружен).
+var fileRules atomic.Pointer[fileRuleSet]
+
+// indexPattern - номер элемента списка в пути ошибки: items[2].price.
+var indexPattern = regexp.MustCompile(`\[\d+\]`)
+
+// LoadRulesFile загружает правила из файла и применяет их к структурам того
+// же типа, что root, вместе с тегами. Некорректный файл (неизвестное поле,
+// неизвестное правило) не загружается: действующими остаются прежние правила.
+// Возвращает количество загруженных правил.
+func LoadRulesFile(path string, root any) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка чтения файла правил: %w", err)
+	}
+	set, err := parseRules(data, reflect.TypeOf(root))
+	if err != nil {
+		return 0, fmt.Errorf("файл правил %s: %w", path, err)
+	}
+	set.source = path
+	set.loadedAt = time.Now()
+	fileRules.Store(set)
+	return len(set.rules), nil
+}
+
+// parseRules разбирает файл правил и проверяет каждое правило на типе root.
+func parseRules(data []byte, root reflect.Type) (*fileRuleSet, error) {
+	var file rulesFile
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true) // Опечатка в ключе не должна тихо отключать правило
+	if err := decoder.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("ошибка разбора: %w", err)
+	}
+
+	root = indirectType(root)
+	if root.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("правила задаются только для структур, а не %s", root)
+	}
+	set := &fileRuleSet{root: root}
+	for path, rule := range file.Fields {
+		rule.Rules = strings.TrimSpace(rule.Rules)
+		if rule.Rules == "" && !rule.Override {
+			return nil, fmt.Errorf("поле %s: пустые правила (чтобы снять правила тега, укажите override: true)", path)
+		}
+		steps, fieldType, err := resolvePath(root, path)
+		if err != nil {
+			return nil, fmt.Errorf("поле %s: %w", path, err)
+		}
+		if err := checkExpression(fieldType, rule.Rules); err != nil {
+			return nil, fmt.Errorf("поле %s: %w", path, err)
+		}
+		set.rules = append(set.rules, fileRule{path: path, rule: rule, steps: steps})
+	}
+	slices.SortFunc(set.rules, func(a, b fileRule) int { return strings.Compare(a.path, b.path) })
+	return set, nil
+}
+
+// resolvePath находит поле по JSON-пути (items[].price) и возвращает шаги пути
+// и тип поля (для items[] - тип элемента).
+func resolvePath(root reflect.Type, path string) ([]pathStep, reflect.Type, error) {
+	var steps []pathStep
+	t := root
+	for _, segment := range strings.Split(path, ".") {
+		name, each := strings.CutSuffix(segment, "[]")
+		t = indirectType(t)
+		if t.Kind() != reflect.Struct {
+			return nil, nil, fmt.Errorf("%s не является объектом", strings.Join(stepNames(steps), "."))
+		}
+		index := -1
+		for i := 0; i < t.NumField(); i++ {
+			if f := t.Field(i); f.IsExported() && jsonFieldName(f) == name {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			return nil, nil, fmt.Errorf("нет поля %q", name)
+		}
+		field := t.Field(index)
+		t = field.Type
+		if each {
+			if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+				return nil, nil, fmt.Errorf("%s не является списком", name)
+			}
+			t = t.Elem()
+		}
+		steps = append(steps, pathStep{name: name, index: index, each: each})
+	}
+	return steps, t, nil
+}
+
+// checkExpression проверяет, что выражение разбирается валидатором: на
+// неизвестном правиле go-playground/validator паникует.
+func checkExpression(t reflect.Type, expr string) (err error) {
+	if expr == "" {
+		return nil
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("некорректное выражение %q: %v", expr, p)
+		}
+	}()
+	_ = getInstance().Var(reflect.Zero(t).Interface(), expr)
+	return nil
+}
+
+// apply проверяет значение v правилами файла: ошибки тегов полей с override
+// отбрасываются, ошибки правил файла добавляются к fields.
+func (s *fileRuleSet) apply(v reflect.Value, fields []FieldError, lang Lang) []FieldError {
+	overridden := make(map[string]bool)
+	for _, rule := range s.rules {
+		if rule.rule.Override {
+			overridden[rule.path] = true
+		}
+	}
+	if len(overridden) > 0 {
+		fields = slices.DeleteFunc(fields, func(field FieldError) bool {
+			return overridden[indexPattern.ReplaceAllString(field.Path, "[]")]
+		})
+	}
+
+	for _, rule := range s.rules {
+		if rule.rule.Rules == "" {
+			continue
+		}
+		for _, target := range collectTargets(v, rule.steps, "") {
+			err := getInstance().Var(target.value.Interface(), rule.rule.Rules)
+			var fieldErrs validator.ValidationErrors
+			if !errors.As(err, &fieldErrs) {
+				continue
+			}
+			for _, fieldErr := range fieldErrs {
+				fields = append(fields, newFieldError(target.path+fieldErr.Namespace(), fieldErr, lang))
+			}
+		}
+	}
+	return fields
+}
+
+// target - значение поля, найденное по пути правила.
+type target struct {
+	path  string // Путь с номерами элементов: items[2].price
+	value reflect.Value
+}
+
+// collectTargets находит значения по шагам пути (для списков - каждый элемент).
+func collectTargets(v reflect.Value, steps []pathStep, path string) []target {
+	if len(steps) == 0 {
+		return []target{{path: path, value: v}}
+	}
+	v = reflect.Indirect(v)
+	if !v.IsValid() {
+		return nil // nil-указатель: проверять нечего
+	}
+
+	step := steps[0]
+	field := v.Field(step.index)
+	if path != "" {
+		path += "."
+	}
+	path += step.name
+	if !step.each {
+		return collectTargets(field, steps[1:], path)
+	}
+	var targets []target
+	for i := 0; i < field.Len(); i++ {
+		targets = append(targets, collectTargets(field.Index(i), steps[1:], fmt.Sprintf("%s[%d]", path, i))...)
+	}
+	return targets
+}
+
+// EffectiveField - итоговые правила поля.
+type EffectiveField struct {
+	Path      string `json:"path"`               // JSON-путь; элементы списка - через []
+	Tag       string `json:"tag,omitempty"`      // Правила из тега validate
+	File      string `json:"file,omitempty"`     // Правила из файла
+	Override  bool   `json:"override,omitempty"` // Правила файла заменяют тег
+	Effective string `json:"effective"`          // Правила, которые проверяются
+}
+
+// RuleState - бизнес-правило и его состояние.
+type RuleState struct {
+	Code     string   `json:"code"`
+	Severity Severity `json:"severity"`
+	Enabled  bool     `json:"enabled"`
+}
+
+// RuleSet - действующие правила валидации.
+type RuleSet struct {
+	Source        string           `json:"source,omitempty"`    // Загруженный файл правил
+	LoadedAt      *time.Time       `json:"loaded_at,omitempty"` // Когда файл загружен
+	Fields        []EffectiveField `json:"fields"`
+	BusinessRules []RuleState      `json:"business_rules"`
+}
+
+// EffectiveRules возвращает правила, которыми проверяются структуры типа
+// root: теги, объединенные с правилами из файла, и бизнес-правила.
+func EffectiveRules(root any) RuleSet {
+	rootType := indirectType(reflect.TypeOf(root))
+	fields := make(map[string]*EffectiveField)
+	collectTagRules(rootType, "", fields)
+
+	var ruleSet RuleSet
+	if set := fileRules.Load(); set != nil && set.root == rootType {
+		ruleSet.Source = set.source
+		loadedAt := set.loadedAt
+		ruleSet.LoadedAt = &loadedAt
+		for _, rule := range set.rules {
+			field, ok := fields[rule.path]
+			if !ok {
+				field = &EffectiveField{Path: rule.path}
+				fields[rule.path] = field
+			}
+			field.File = rule.rule.Rules
+			field.Override = rule.rule.Override
+		}
+	}
+
+	for _, field := range fields {
+		switch {
+		case field.Override || field.Tag == "":
+			field.Effective = field.File
+		case field.File == "":
+			field.Effective = field.Tag
+		default:
+			field.Effective = field.Tag + "," + field.File
+		}
+		ruleSet.Fields = append(ruleSet.Fields, *field)
+	}
+	slices.SortFunc(ruleSet.Fields, func(a, b EffectiveField) int { return strings.Compare(a.Path, b.Path) })
+
+	for _, rule := range Rules() {
+		ruleSet.BusinessRules = append(ruleSet.BusinessRules, RuleState{Code: rule.Code, Severity: rule.Severity, Enabled: RuleEnabled(rule.Code)})
+	}
+	return ruleSet
+}
+
+// collectTagRules собирает теги validate полей структуры t и вложенных структур.
+func collectTagRules(t reflect.Type, prefix string, fields map[string]*EffectiveField) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := jsonFieldName(f)
+		if !f.IsExported() || name == "" {
+			continue
+		}
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+		if tag := f.Tag.Get("validate"); tag != "" {
+			fields[path] = &EffectiveField{Path: path, Tag: tag}
+		}
+
+		ft := indirectType(f.Type)
+		if ft.Kind() == reflect.Slice || ft.Kind() == reflect.Array {
+			ft, path = indirectType(ft.Elem()), path+"[]"
+		}
+		if ft.Kind() == reflect.Struct && ft != reflect.TypeOf(time.Time{}) {
+			collectTagRules(ft, path, fields)
+		}
+	}
+}
+
+// indirectType возвращает тип значения, на которое указывает t.
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+// stepNames возвращает имена полей пути.
+func stepNames(steps []pathStep) []string {
+	names := make([]string, len(steps))
+	for i, step := range steps {
+		names[i] = step.name
+	}
+	return names
+}
@@ -0,0 +1,175 @@
+package validator
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testLine struct {
+	Price int `json:"price" validate:"gt=0"`
+	Sale  int `json:"sale" validate:"lte=50"`
+}
+
+type testCart struct {
+	Bank  string     `json:"bank" validate:"required"`
+	Lines []testLine `json:"lines" validate:"required,dive"`
+	Note  string     `json:"note"`
+}
+
+// writeRules записывает файл правил во временный каталог
+func writeRules(t *testing.T, dir, content string) string {
+	path := filepath.Join(dir, "rules.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+// loadTestRules загружает правила для testCart и сбрасывает их после теста
+func loadTestRules(t *testing.T, content string) error {
+	t.Cleanup(func() { fileRules.Store(nil) })
+	_, err := LoadRulesFile(writeRules(t, t.TempDir(), content), testCart{})
+	return err
+}
+
+// fieldPaths возвращает пути и правила ошибок валидации
+func fieldPaths(t *testing.T, err error) []string {
+	if err == nil {
+		return nil
+	}
+	var validationErr *ValidationError
+	require.True(t, errors.As(err, &validationErr), "ожидалась ошибка валидации, получено %v", err)
+	var paths []string
+	for _, field := range validationErr.Fields {
+		paths = append(paths, field.Path+":"+field.Rule)
+	}
+	return paths
+}
+
+func TestLoadRulesFile_MergedWithTags(t *testing.T) {
+	require.NoError(t, loadTestRules(t, `
+fields:
+  bank:
+    rules: oneof=alpha sber
+  lines[].price:
+    rules: lte=1000
+  lines[].sale:
+    rules: lte=90
+    override: true
+  note:
+    rules: max=5
+`))
+
+	testCases := []struct {
+		name string
+		cart testCart
+		want []string
+	}{
+		{name: "валидная корзина", cart: testCart{Bank: "alpha", Lines: []testLine{{Price: 10, Sale: 80}}}},
+		{name: "правило файла дополняет тег", cart: testCart{Bank: "vtb", Lines: []testLine{{Price: 10}}}, want: []string{"bank:oneof"}},
+		{name: "тег продолжает действовать", cart: testCart{Lines: []testLine{{Price: 10}}}, want: []string{"bank:required", "bank:oneof"}},
+		{
+			name: "правило элемента списка",
+			cart: testCart{Bank: "sber", Lines: []testLine{{Price: 10}, {Price: 5000}, {Price: 0}}},
+			want: []string{"lines[2].price:gt", "lines[1].price:lte"},
+		},
+		{name: "override заменяет тег", cart: testCart{Bank: "sber", Lines: []testLine{{Price: 10, Sale: 95}}}, want: []string{"lines[0].sale:lte"}},
+		{name: "поле без тега", cart: testCart{Bank: "sber", Lines: []testLine{{Price: 10}}, Note: "слишком длинно"}, want: []string{"note:max"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, fieldPaths(t, ValidateStruct(&tc.cart)))
+		})
+	}
+}
+
+func TestLoadRulesFile_JSON(t *testing.T) {
+	require.NoError(t, loadTestRules(t, `{"fields": {"bank": {"rules": "oneof=alpha"}}}`))
+	assert.Equal(t, []string{"bank:oneof"}, fieldPaths(t, ValidateStruct(&testCart{Bank: "sber", Lines: []testLine{{Price: 1}}})))
+}
+
+func TestLoadRulesFile_InvalidKeepsPrevious(t *testing.T) {
+	require.NoError(t, loadTestRules(t, "fields:\n  bank:\n    rules: oneof=alpha\n"))
+
+	testCases := []struct {
+		name    string
+		content string
+	}{
+		{name: "неизвестное поле", content: "fields:\n  missing:\n    rules: required\n"},
+		{name: "не список", content: "fields:\n  bank[].x:\n    rules: required\n"},
+		{name: "неизвестное правило", content: "fields:\n  bank:\n    rules: no_such_rule\n"},
+		{name: "опечатка в ключе", content: "fields:\n  bank:\n    rule: required\n"},
+		{name: "пустые правила без override", content: "fields:\n  bank:\n    rules: \"\"\n"},
+		{name: "битый YAML", content: "fields: [\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := LoadRulesFile(writeRules(t, t.TempDir(), tc.content), testCart{})
+			assert.Error(t, err)
+			// Прежние правила остаются в силе
+			assert.Equal(t, []string{"bank:oneof"}, fieldPaths(t, ValidateStruct(&testCart{Bank: "sber", Lines: []testLine{{Price: 1}}})))
+		})
+	}
+}
+
+func TestLoadRulesFile_OtherTypesUnaffected(t *testing.T) {
+	require.NoError(t, loadTestRules(t, "fields:\n  bank:\n    rules: oneof=alpha\n"))
+	assert.NoError(t, ValidateStruct(&testItem{Price: 1}))
+}
+
+func TestEffectiveRules(t *testing.T) {
+	require.NoError(t, loadTestRules(t, `
+fields:
+  bank:
+    rules: oneof=alpha sber
+  lines[].sale:
+    rules: lte=90
+    override: true
+  note:
+    rules: max=5
+`))
+
+	ruleSet := EffectiveRules(testCart{})
+	assert.NotEmpty(t, ruleSet.Source)
+	assert.NotNil(t, ruleSet.LoadedAt)
+	assert.Equal(t, []EffectiveField{
+		{Path: "bank", Tag: "required", File: "oneof=alpha sber", Effective: "required,oneof=alpha sber"},
+		{Path: "lines", Tag: "required,dive", Effective: "required,dive"},
+		{Path: "lines[].price", Tag: "gt=0", Effective: "gt=0"},
+		{Path: "lines[].sale", Tag: "lte=50", File: "lte=90", Override: true, Effective: "lte=90"},
+		{Path: "note", File: "max=5", Effective: "max=5"},
+	}, ruleSet.Fields)
+	assert.NotEmpty(t, ruleSet.BusinessRules)
+}
+
+func TestWatchRulesFile_ReloadsOnChange(t *testing.T) {
+	t.Cleanup(func() { fileRules.Store(nil) })
+	path := writeRules(t, t.TempDir(), "fields:\n  bank:\n    rules: oneof=alpha\n")
+	_, err := LoadRulesFile(path, testCart{})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go WatchRulesFile(ctx, path, testCart{}, 10*time.Millisecond)
+
+	cart := testCart{Bank: "sber", Lines: []testLine{{Price: 1}}}
+	require.Error(t, ValidateStruct(&cart))
+
+	require.NoError(t, os.WriteFile(path, []byte("fields:\n  bank:\n    rules: oneof=alpha sber\n"), 0o644))
+
+	// Время изменения сдвигается явно (на некоторых ФС его точность - секунда) и
+	// повторно: наблюдатель мог запомнить состояние файла уже после записи
+	later := time.Now()
+	assert.Eventually(t, func() bool {
+		later = later.Add(time.Second)
+		require.NoError(t, os.Chtimes(path, later, later))
+		return ValidateStruct(&cart) == nil
+	}, 2*time.Second, 20*time.Millisecond)
+}
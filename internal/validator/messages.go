@@ -0,0 +1,133 @@
+package validator
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// Lang - язык сообщений об ошибках валидации.
+type Lang string
+
+const (
+	LangRU Lang = "ru"
+	LangEN Lang = "en"
+)
+
+// defaultLang - язык сообщений в логах и заголовках DLQ.
+var defaultLang atomic.Value
+
+// DefaultLang возвращает язык сообщений по умолчанию (ru, если не задан).
+func DefaultLang() Lang {
+	if lang, ok := defaultLang.Load().(Lang); ok {
+		return lang
+	}
+	return LangRU
+}
+
+// SetDefaultLang задает язык сообщений по умолчанию.
+func SetDefaultLang(lang Lang) {
+	defaultLang.Store(lang)
+}
+
+// ParseLang распознает язык по коду (ru, en-US...). Для неподдерживаемого
+// языка возвращает false.
+func ParseLang(code string) (Lang, bool) {
+	base, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(code)), "-")
+	switch Lang(base) {
+	case LangRU:
+		return LangRU, true
+	case LangEN:
+		return LangEN, true
+	}
+	return "", false
+}
+
+// FromAcceptLanguage выбирает первый поддерживаемый язык из заголовка
+// Accept-Language (в порядке перечисления, языки с q=0 пропускаются).
+// Если подходящего нет, возвращает DefaultLang.
+func FromAcceptLanguage(header string) Lang {
+	for _, part := range strings.Split(header, ",") {
+		code, params, _ := strings.Cut(part, ";")
+		if q, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			if weight, err := strconv.ParseFloat(q, 64); err == nil && weight == 0 {
+				continue
+			}
+		}
+		if lang, ok := ParseLang(code); ok {
+			return lang
+		}
+	}
+	return DefaultLang()
+}
+
+// Text - шаблон сообщения на поддерживаемых языках. Подстановки ({param},
+// {value}...) заменяются значениями из ошибки.
+type Text struct {
+	RU, EN string
+}
+
+// In возвращает шаблон на языке lang.
+func (t Text) In(lang Lang) string {
+	if lang == LangEN {
+		return t.EN
+	}
+	return t.RU
+}
+
+// messages - сообщения по правилам. Для min, max и len текст зависит от
+// типа поля: ключ "правило.string" - для строк, "правило.items" - для списков.
+var messages = map[string]Text{
+	"required": {"обязательное поле", "is required"},
+	"uuid4":    {"должно быть UUID версии 4", "must be a valid UUID v4"},
+	"email":    {"должно быть корректным email", "must be a valid email address"},
+	"gt":       {"должно быть больше {param}", "must be greater than {param}"},
+	"gte":      {"должно быть не меньше {param}", "must be greater than or equal to {param}"},
+	"lt":       {"должно быть меньше {param}", "must be less than {param}"},
+	"lte":      {"должно быть не больше {param}", "must be less than or equal to {param}"},
+	"oneof":    {"должно быть одним из: {param}", "must be one of: {param}"},
+
+	TagCurrency: {"должно быть кодом валюты ISO 4217 (например, USD)", "must be an ISO 4217 currency code (e.g. USD)"},
+	TagLocale:   {"должно быть кодом языка ISO 639-1 (например, en)", "must be an ISO 639-1 language code (e.g. en)"},
+	TagPhone:    {"должно быть номером телефона в формате E.164 (например, +79991234567)", "must be a phone number in E.164 format (e.g. +79991234567)"},
+	TagZip:      {"должно быть почтовым индексом в формате страны номера телефона", "must be a postal code in the format of the phone number's country"},
+	TagUnixTime: {"должно быть временем UNIX в секундах не раньше 2000 года и не в будущем", "must be a UNIX timestamp in seconds, not before 2000 and not in the future"},
+
+	"len":        {"должно быть равно {param}", "must be equal to {param}"},
+	"len.string": {"длина должна быть {param} символов", "must be exactly {param} characters long"},
+	"len.items":  {"должно содержать {param} элементов", "must contain exactly {param} items"},
+	"min":        {"должно быть не меньше {param}", "must be at least {param}"},
+	"min.string": {"длина должна быть не меньше {param} символов", "must be at least {param} characters long"},
+	"min.items":  {"должно содержать не меньше {param} элементов", "must contain at least {param} items"},
+	"max":        {"должно быть не больше {param}", "must be at most {param}"},
+	"max.string": {"длина должна быть не больше {param} символов", "must be at most {param} characters long"},
+	"max.items":  {"должно содержать не больше {param} элементов", "must contain at most {param} items"},
+}
+
+// fallbackText - сообщение для правила без собственного шаблона.
+var fallbackText = Text{"не прошло проверку {rule}", "failed the {rule} check"}
+
+// message возвращает сообщение о нарушении правила rule на языке lang.
+func message(lang Lang, rule, param string, kind reflect.Kind) string {
+	tmpl, ok := messages[rule+kindSuffix(kind)]
+	if !ok {
+		tmpl, ok = messages[rule]
+	}
+	if !ok {
+		tmpl = fallbackText
+	}
+
+	return strings.NewReplacer("{param}", param, "{rule}", rule).Replace(tmpl.In(lang))
+}
+
+// kindSuffix возвращает суффикс ключа сообщения для типа поля.
+func kindSuffix(kind reflect.Kind) string {
+	switch kind {
+	case reflect.String:
+		return ".string"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return ".items"
+	}
+	return ""
+}
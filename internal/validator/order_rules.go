@@ -0,0 +1,103 @@
+package validator
+
+import (
+	"L0_project/internal/model"
+	"fmt"
+)
+
+// Коды встроенных бизнес-правил заказа.
+const (
+	RuleGoodsTotal      = "goods_total_mismatch"       // goods_total = сумма total_price товаров
+	RuleAmount          = "amount_mismatch"            // amount = goods_total + delivery_cost + custom_fee
+	RuleItemTotalPrice  = "item_total_price_mismatch"  // total_price = price со скидкой sale
+	RuleItemTrackNumber = "item_track_number_mismatch" // трек-номер товара совпадает с трек-номером заказа
+	RuleTransaction     = "transaction_mismatch"       // transaction платежа совпадает с order_uid
+)
+
+// itemTotalTolerance - допустимое расхождение total_price товара из-за
+// округления цены со скидкой.
+const itemTotalTolerance = 1
+
+func init() {
+	// Расхождения в суммах - ошибка в данных заказа, такой заказ отклоняется.
+	// Остальные правила пока только фиксируют расхождения: отправители
+	// заполняют эти поля по-разному.
+	RegisterRule(BusinessRule{
+		Code:     RuleGoodsTotal,
+		Severity: SeverityReject,
+		Message:  Text{"сумма товаров {value} не равна сумме total_price товаров {expected}", "goods total {value} does not equal the sum of item total prices {expected}"},
+		Check:    checkGoodsTotal,
+	})
+	RegisterRule(BusinessRule{
+		Code:     RuleAmount,
+		Severity: SeverityReject,
+		Message:  Text{"сумма платежа {value} не равна goods_total + delivery_cost + custom_fee = {expected}", "payment amount {value} does not equal goods_total + delivery_cost + custom_fee = {expected}"},
+		Check:    checkAmount,
+	})
+	RegisterRule(BusinessRule{
+		Code:     RuleItemTotalPrice,
+		Severity: SeverityWarn,
+		Message:  Text{"итоговая цена {value} не соответствует цене со скидкой {expected}", "total price {value} does not match the discounted price {expected}"},
+		Check:    checkItemTotalPrice,
+	})
+	RegisterRule(BusinessRule{
+		Code:     RuleItemTrackNumber,
+		Severity: SeverityWarn,
+		Message:  Text{"трек-номер товара {value} отличается от трек-номера заказа {expected}", "item track number {value} differs from the order track number {expected}"},
+		Check:    checkItemTrackNumber,
+	})
+	RegisterRule(BusinessRule{
+		Code:     RuleTransaction,
+		Severity: SeverityWarn,
+		Message:  Text{"транзакция {value} не совпадает с UID заказа {expected}", "transaction {value} does not match the order UID {expected}"},
+		Check:    checkTransaction,
+	})
+}
+
+func checkGoodsTotal(order *model.Order) []Violation {
+	sum := 0
+	for _, item := range order.Items {
+		sum += item.TotalPrice
+	}
+	if order.Payment.GoodsTotal != sum {
+		return []Violation{{Path: "payment.goods_total", Value: order.Payment.GoodsTotal, Expected: sum}}
+	}
+	return nil
+}
+
+func checkAmount(order *model.Order) []Violation {
+	payment := order.Payment
+	expected := payment.GoodsTotal + payment.DeliveryCost + payment.CustomFee
+	if payment.Amount != expected {
+		return []Violation{{Path: "payment.amount", Value: payment.Amount, Expected: expected}}
+	}
+	return nil
+}
+
+func checkItemTotalPrice(order *model.Order) []Violation {
+	var violations []Violation
+	for i, item := range order.Items {
+		expected := item.Price * (100 - item.Sale) / 100
+		if diff := item.TotalPrice - expected; diff < -itemTotalTolerance || diff > itemTotalTolerance {
+			violations = append(violations, Violation{Path: fmt.Sprintf("items[%d].total_price", i), Value: item.TotalPrice, Expected: expected})
+		}
+	}
+	return violations
+}
+
+func checkItemTrackNumber(order *model.Order) []Violation {
+	var violations []Violation
+	for i, item := range order.Items {
+		if item.TrackNumber != order.TrackNumber {
+			violations = append(violations, Violation{Path: fmt.Sprintf("items[%d].track_number", i), Value: item.TrackNumber, Expected: order.TrackNumber})
+		}
+	}
+	return violations
+}
+
+func checkTransaction(order *model.Order) []Violation {
+	if order.Payment.Transaction != order.OrderUID {
+		return []Violation{{Path: "payment.transaction", Value: order.Payment.Transaction, Expected: order.OrderUID}}
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package validator
+
+import (
+	"L0_project/internal/model"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Severity - последствия нарушения бизнес-правила.
+type Severity string
+
+const (
+	// SeverityReject - заказ отклоняется (в Kafka - уходит в DLQ).
+	SeverityReject Severity = "reject"
+	// SeverityWarn - заказ принимается, нарушение только фиксируется.
+	SeverityWarn Severity = "warn"
+)
+
+// Violation - нарушение бизнес-правила заказа.
+type Violation struct {
+	Code     string   `json:"code"`     // Код правила
+	Severity Severity `json:"severity"` // reject или warn
+	Path     string   `json:"path"`     // JSON-путь к полю с расхождением
+	Value    any      `json:"value"`    // Фактическое значение
+	Expected any      `json:"expected"` // Значение, которое ожидает правило
+	Message  string   `json:"message"`  // Сообщение на выбранном языке
+
+	text Text // Шаблон сообщения правила
+}
+
+// BusinessRule - проверка заказа целиком, которую не выразить тегами полей.
+type BusinessRule struct {
+	Code     string   // Уникальный код правила, по нему правило отключается
+	Severity Severity // Последствия нарушения
+	// Message - шаблон сообщения; {path}, {value} и {expected} заменяются
+	// значениями нарушения
+	Message Text
+	// Check возвращает нарушения с заполненными Path, Value и Expected
+	Check func(order *model.Order) []Violation
+}
+
+// RuleViolationError - нарушения бизнес-правил, из-за которых заказ отклонен.
+type RuleViolationError struct {
+	Violations []Violation
+}
+
+// Error перечисляет нарушения в виде "путь: сообщение (код)".
+func (e *RuleViolationError) Error() string {
+	parts := make([]string, len(e.Violations))
+	for i, violation := range e.Violations {
+		parts[i] = fmt.Sprintf("%s: %s (%s)", violation.Path, violation.Message, violation.Code)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Localize возвращает копию нарушений с сообщениями на языке lang.
+func (e *RuleViolationError) Localize(lang Lang) *RuleViolationError {
+	return &RuleViolationError{Violations: LocalizeViolations(e.Violations, lang)}
+}
+
+// LocalizeViolations возвращает копию нарушений с сообщениями на языке lang.
+func LocalizeViolations(violations []Violation, lang Lang) []Violation {
+	if violations == nil {
+		return nil
+	}
+	localized := make([]Violation, len(violations))
+	for i, violation := range violations {
+		violation.Message = violationMessage(violation.text, violation, lang)
+		localized[i] = violation
+	}
+	return localized
+}
+
+var (
+	rulesMu  sync.RWMutex
+	rules    []BusinessRule      // Правила в порядке регистрации
+	disabled = map[string]bool{} // Отключенные в конфигурации правила
+)
+
+// RegisterRule добавляет бизнес-правило. Правило с уже занятым кодом заменяет прежнее.
+func RegisterRule(rule BusinessRule) {
+	rulesMu.Lock()
+	defer rulesMu.Unlock()
+
+	if i := slices.IndexFunc(rules, func(r BusinessRule) bool { return r.Code == rule.Code }); i >= 0 {
+		rules[i] = rule
+		return
+	}
+	rules = append(rules, rule)
+}
+
+// Rules возвращает зарегистрированные правила.
+func Rules() []BusinessRule {
+	rulesMu.RLock()
+	defer rulesMu.RUnlock()
+	return slices.Clone(rules)
+}
+
+// RuleEnabled сообщает, проверяется ли правило с кодом code.
+func RuleEnabled(code string) bool {
+	rulesMu.RLock()
+	defer rulesMu.RUnlock()
+	return !disabled[code]
+}
+
+// DisableRules отключает правила с перечисленными кодами (остальные
+// включаются). Неизвестный код - ошибка: скорее всего, опечатка в конфигурации.
+func DisableRules(codes ...string) error {
+	rulesMu.Lock()
+	defer rulesMu.Unlock()
+
+	next := make(map[string]bool, len(codes))
+	for _, code := range codes {
+		code = strings.TrimSpace(code)
+		if code == "" {
+			continue
+		}
+		if !slices.ContainsFunc(rules, func(r BusinessRule) bool { return r.Code == code }) {
+			return fmt.Errorf("неизвестное бизнес-правило: %q", code)
+		}
+		next[code] = true
+	}
+	disabled = next
+	return nil
+}
+
+// ValidateRules проверяет заказ включенными бизнес-правилами. Нарушения
+// правил с SeverityReject возвращаются как *RuleViolationError, нарушения
+// правил с SeverityWarn - в warnings. Сообщения - на языке по умолчанию.
+func ValidateRules(order *model.Order) (warnings []Violation, err error) {
+	rulesMu.RLock()
+	active := make([]BusinessRule, 0, len(rules))
+	for _, rule := range rules {
+		if !disabled[rule.Code] {
+			active = append(active, rule)
+		}
+	}
+	rulesMu.RUnlock()
+
+	lang := DefaultLang()
+	var rejects []Violation
+	for _, rule := range active {
+		for _, violation := range rule.Check(order) {
+			violation.Code = rule.Code
+			violation.Severity = rule.Severity
+			violation.text = rule.Message
+			violation.Message = violationMessage(rule.Message, violation, lang)
+			if rule.Severity == SeverityReject {
+				rejects = append(rejects, violation)
+			} else {
+				warnings = append(warnings, violation)
+			}
+		}
+	}
+	if len(rejects) > 0 {
+		return warnings, &RuleViolationError{Violations: rejects}
+	}
+	return warnings, nil
+}
+
+// violationMessage подставляет значения нарушения в шаблон.
+func violationMessage(tmpl Text, violation Violation, lang Lang) string {
+	return strings.NewReplacer(
+		"{path}", violation.Path,
+		"{value}", fmt.Sprint(violation.Value),
+		"{expected}", fmt.Sprint(violation.Expected),
+	).Replace(tmpl.In(lang))
+}
@@ -0,0 +1,137 @@
+package validator
+
+import (
+	"L0_project/internal/model"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// consistentOrder возвращает заказ, согласованный по всем бизнес-правилам
+func consistentOrder() *model.Order {
+	return &model.Order{
+		OrderUID:    "b563feb7-b2b8-4b6f-807c-9b63a11e81b9",
+		TrackNumber: "WBILMTESTTRACK",
+		Payment: model.Payment{
+			Transaction:  "b563feb7-b2b8-4b6f-807c-9b63a11e81b9",
+			Amount:       1817,
+			DeliveryCost: 1500,
+			GoodsTotal:   317,
+		},
+		Items: []model.Item{
+			{TrackNumber: "WBILMTESTTRACK", Price: 453, Sale: 30, TotalPrice: 317},
+		},
+	}
+}
+
+func TestValidateRules(t *testing.T) {
+	testCases := []struct {
+		name         string
+		modify       func(order *model.Order)
+		wantRejects  []string // Пути отклоняющих нарушений
+		wantWarnings []string // Пути предупреждений
+	}{
+		{name: "согласованный заказ", modify: func(*model.Order) {}},
+		{
+			name:        "goods_total не равен сумме товаров",
+			modify:      func(o *model.Order) { o.Payment.GoodsTotal = 300; o.Payment.Amount = 1800 },
+			wantRejects: []string{"payment.goods_total"},
+		},
+		{
+			name:        "amount не равен сумме составляющих",
+			modify:      func(o *model.Order) { o.Payment.CustomFee = 10 },
+			wantRejects: []string{"payment.amount"},
+		},
+		{
+			name:         "total_price в пределах округления",
+			modify:       func(o *model.Order) { o.Items[0].TotalPrice = 318; o.Payment.GoodsTotal = 318; o.Payment.Amount = 1818 },
+			wantWarnings: nil,
+		},
+		{
+			name:         "total_price не соответствует скидке",
+			modify:       func(o *model.Order) { o.Items[0].TotalPrice = 453; o.Payment.GoodsTotal = 453; o.Payment.Amount = 1953 },
+			wantWarnings: []string{"items[0].total_price"},
+		},
+		{
+			name: "трек-номер товара отличается",
+			modify: func(o *model.Order) {
+				o.Items = append(o.Items, model.Item{TrackNumber: "OTHER", Price: 100, TotalPrice: 100})
+				o.Payment.GoodsTotal, o.Payment.Amount = 417, 1917
+			},
+			wantWarnings: []string{"items[1].track_number"},
+		},
+		{
+			name:         "транзакция не совпадает с UID",
+			modify:       func(o *model.Order) { o.Payment.Transaction = "other" },
+			wantWarnings: []string{"payment.transaction"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			order := consistentOrder()
+			tc.modify(order)
+
+			warnings, err := ValidateRules(order)
+
+			var rejects []string
+			var rulesErr *RuleViolationError
+			if errors.As(err, &rulesErr) {
+				for _, violation := range rulesErr.Violations {
+					assert.Equal(t, SeverityReject, violation.Severity)
+					rejects = append(rejects, violation.Path)
+				}
+			} else {
+				require.NoError(t, err)
+			}
+			var warningPaths []string
+			for _, warning := range warnings {
+				assert.Equal(t, SeverityWarn, warning.Severity)
+				warningPaths = append(warningPaths, warning.Path)
+			}
+			assert.Equal(t, tc.wantRejects, rejects)
+			assert.Equal(t, tc.wantWarnings, warningPaths)
+		})
+	}
+}
+
+func TestValidateRules_ViolationDetails(t *testing.T) {
+	order := consistentOrder()
+	order.Payment.Amount = 2000
+
+	_, err := ValidateRules(order)
+	var rulesErr *RuleViolationError
+	require.True(t, errors.As(err, &rulesErr))
+	require.Len(t, rulesErr.Violations, 1)
+
+	violation := rulesErr.Violations[0]
+	assert.Equal(t, RuleAmount, violation.Code)
+	assert.Equal(t, 2000, violation.Value)
+	assert.Equal(t, 1817, violation.Expected)
+	assert.Equal(t, "сумма платежа 2000 не равна goods_total + delivery_cost + custom_fee = 1817", violation.Message)
+	assert.Equal(t, "payment amount 2000 does not equal goods_total + delivery_cost + custom_fee = 1817",
+		rulesErr.Localize(LangEN).Violations[0].Message)
+	assert.Contains(t, err.Error(), "payment.amount")
+}
+
+func TestDisableRules(t *testing.T) {
+	t.Cleanup(func() { require.NoError(t, DisableRules()) })
+
+	order := consistentOrder()
+	order.Payment.Amount = 2000
+	order.Payment.Transaction = "other"
+
+	require.NoError(t, DisableRules(RuleAmount, " "+RuleTransaction))
+	assert.False(t, RuleEnabled(RuleAmount))
+	assert.True(t, RuleEnabled(RuleGoodsTotal))
+
+	warnings, err := ValidateRules(order)
+	assert.NoError(t, err)
+	assert.Empty(t, warnings)
+
+	// Опечатка в коде правила не должна тихо оставлять правило включенным
+	assert.Error(t, DisableRules("amount_mismatchh"))
+	assert.False(t, RuleEnabled(RuleAmount), "прежняя настройка сохраняется")
+}
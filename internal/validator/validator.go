@@ -1,8 +1,12 @@
 package validator
 
 import (
-	"github.com/go-playground/validator/v10"
+	"errors"
+	"reflect"
+	"strings"
 	"sync"
+
+	"github.com/go-playground/validator/v10"
 )
 
 var (
@@ -14,11 +18,49 @@ var (
 func getInstance() *validator.Validate {
 	once.Do(func() {
 		validate = validator.New()
+		// Поля в ошибках называются так же, как в JSON
+		validate.RegisterTagNameFunc(jsonFieldName)
+		registerCustom(validate)
 	})
 	return validate
 }
 
-// ValidateStruct выполняет валидацию по тегам структуры.
+// ValidateStruct выполняет валидацию по тегам структуры и правилам из файла
+// правил (LoadRulesFile). Ошибки полей возвращаются как *ValidationError
+// с сообщениями на языке по умолчанию.
 func ValidateStruct(s interface{}) error {
-	return getInstance().Struct(s)
+	lang := DefaultLang()
+	err := getInstance().Struct(s)
+	var (
+		fieldErrs validator.ValidationErrors
+		fields    []FieldError
+	)
+	switch {
+	case errors.As(err, &fieldErrs):
+		fields = newValidationError(fieldErrs, lang).Fields
+	case err != nil:
+		return err
+	}
+
+	if set := fileRules.Load(); set != nil {
+		if v := reflect.Indirect(reflect.ValueOf(s)); v.Type() == set.root {
+			fields = set.apply(v, fields, lang)
+		}
+	}
+	if len(fields) > 0 {
+		return &ValidationError{Fields: fields}
+	}
+	return nil
+}
+
+// jsonFieldName возвращает имя поля из тега json (пусто для json:"-").
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	}
+	return name
 }
@@ -0,0 +1,94 @@
+package validator
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testItem struct {
+	Price int `json:"price" validate:"gt=0"`
+}
+
+type testOrder struct {
+	UID    string     `json:"order_uid" validate:"required,uuid4"`
+	Locale string     `json:"locale" validate:"required,len=2"`
+	Items  []testItem `json:"items" validate:"required,min=1,dive"`
+	Tags   []string   `json:"tags" validate:"max=1"`
+}
+
+func TestValidateStruct_FieldErrors(t *testing.T) {
+	order := testOrder{
+		UID:    "not-a-uuid",
+		Locale: "eng",
+		Items:  []testItem{{Price: 10}, {Price: 5}, {Price: -1}},
+		Tags:   []string{"a", "b"},
+	}
+
+	err := ValidateStruct(&order)
+	var validationErr *ValidationError
+	require.True(t, errors.As(err, &validationErr))
+
+	assert.Equal(t, []FieldError{
+		{Path: "order_uid", Rule: "uuid4", Value: "not-a-uuid", Message: "должно быть UUID версии 4"},
+		{Path: "locale", Rule: "len", Param: "2", Value: "eng", Message: "длина должна быть 2 символов"},
+		{Path: "items[2].price", Rule: "gt", Param: "0", Value: -1, Message: "должно быть больше 0"},
+		{Path: "tags", Rule: "max", Param: "1", Message: "должно содержать не больше 1 элементов"},
+	}, withoutKind(validationErr.Fields))
+	assert.Contains(t, err.Error(), "items[2].price: должно быть больше 0")
+}
+
+func TestValidationError_Localize(t *testing.T) {
+	err := ValidateStruct(&testOrder{UID: "b563feb7-b2b8-4b6f-807c-9b63a11e81b9", Locale: "ru"})
+	var validationErr *ValidationError
+	require.True(t, errors.As(err, &validationErr))
+
+	english := validationErr.Localize(LangEN)
+	require.Len(t, english.Fields, 1)
+	assert.Equal(t, "items", english.Fields[0].Path)
+	assert.Equal(t, "is required", english.Fields[0].Message)
+	// Исходная ошибка не меняется
+	assert.Equal(t, "обязательное поле", validationErr.Fields[0].Message)
+
+	data, err := json.Marshal(english.Fields)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[{"path":"items","rule":"required","value":null,"message":"is required"}]`, string(data))
+}
+
+func TestValidateStruct_Valid(t *testing.T) {
+	assert.NoError(t, ValidateStruct(&testOrder{UID: "b563feb7-b2b8-4b6f-807c-9b63a11e81b9", Locale: "ru", Items: []testItem{{Price: 1}}}))
+}
+
+func TestFromAcceptLanguage(t *testing.T) {
+	testCases := []struct {
+		header string
+		want   Lang
+	}{
+		{header: "", want: LangRU},
+		{header: "en-US,en;q=0.9", want: LangEN},
+		{header: "de-DE, ru;q=0.8, en;q=0.5", want: LangRU},
+		{header: "en;q=0, ru", want: LangRU},
+		{header: "fr", want: LangRU},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.header, func(t *testing.T) {
+			assert.Equal(t, tc.want, FromAcceptLanguage(tc.header))
+		})
+	}
+}
+
+func TestErrorSchema_IsJSON(t *testing.T) {
+	assert.True(t, json.Valid(ErrorSchema))
+}
+
+// withoutKind обнуляет служебное поле для сравнения в тестах.
+func withoutKind(fields []FieldError) []FieldError {
+	for i := range fields {
+		fields[i].kind = 0
+	}
+	return fields
+}
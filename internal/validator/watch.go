@@ -0,0 +1,72 @@
+package validator
+
+import (
+	"L0_project/internal/metrics"
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// WatchRulesFile перечитывает файл правил по сигналу SIGHUP и при изменении
+// файла (проверяется раз в pollInterval, 0 - только по сигналу) до отмены ctx.
+// Если новый файл некорректен, ошибка пишется в лог, а действующими остаются
+// прежние правила.
+func WatchRulesFile(ctx context.Context, path string, root any, pollInterval time.Duration) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	var poll <-chan time.Time
+	if pollInterval > 0 {
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+		poll = ticker.C
+	}
+
+	last, _ := statFile(path)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+			log.Printf("Получен SIGHUP: перечитываем правила валидации из %s", path)
+		case <-poll:
+			stamp, err := statFile(path)
+			if err != nil || stamp == last {
+				continue // Файл не изменился (или заменяется прямо сейчас)
+			}
+		}
+		last, _ = statFile(path)
+		reloadRulesFile(path, root)
+	}
+}
+
+// fileStamp - признаки изменения файла.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// statFile возвращает признаки изменения файла.
+func statFile(path string) (fileStamp, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}, err
+	}
+	return fileStamp{modTime: info.ModTime(), size: info.Size()}, nil
+}
+
+// reloadRulesFile загружает файл правил и записывает результат в лог и метрики.
+func reloadRulesFile(path string, root any) {
+	count, err := LoadRulesFile(path, root)
+	if err != nil {
+		metrics.ValidationRulesReloads.WithLabelValues("error").Inc()
+		log.Printf("Правила валидации не обновлены, действуют прежние: %v", err)
+		return
+	}
+	metrics.ValidationRulesReloads.WithLabelValues("success").Inc()
+	log.Printf("Правила валидации обновлены из %s: %d полей.", path, count)
+}